@@ -11,7 +11,6 @@ import (
 	"github.com/tdelov/imagemeta/imagetype"
 	"github.com/tdelov/imagemeta/isobmff"
 	"github.com/tdelov/imagemeta/tiff"
-	"github.com/rs/zerolog"
 )
 
 var (
@@ -204,7 +203,7 @@ func BenchmarkHeif(b *testing.B) {
 					rr := readerPool.Get().(*bufio.Reader)
 					rr.Reset(r)
 
-					ir := exif2.NewIfdReader(zerolog.Logger{})
+					ir := exif2.NewIfdReader(exif2.Logger)
 
 					it, err := imagetype.ScanBuf(rr)
 					if err != nil {
@@ -226,7 +225,7 @@ func BenchmarkHeif(b *testing.B) {
 					if _, err = r.Seek(0, 0); err != nil {
 						b.Fatal(err)
 					}
-					ir := exif2.NewIfdReader(zerolog.Logger{})
+					ir := exif2.NewIfdReader(exif2.Logger)
 
 					br := isobmff.NewReader(r)
 					br.ExifReader = ir.DecodeIfd
@@ -246,3 +245,32 @@ func BenchmarkHeif(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkDecodeBytes(b *testing.B) {
+	for _, fileName := range []string{"JPEG.jpg", "NEF.exif", "CR2.exif"} {
+		buf, err := os.ReadFile("testImages/" + fileName)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run("Decode/"+fileName, func(b *testing.B) {
+			r := bytes.NewReader(buf)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err = r.Seek(0, 0); err != nil {
+					b.Fatal(err)
+				}
+				if _, err = Decode(r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("DecodeBytes/"+fileName, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err = DecodeBytes(buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // image decoders of the hash command
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/tdelov/imagemeta"
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagehash"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/xmp"
+	"github.com/nfnt/resize"
+)
+
+// runDump prints the metadata of the images.
+func runDump(c *cli, args []string) error {
+	var verbose bool
+	fs := c.flagSet("dump", &verbose)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.setVerbose(verbose)
+	return c.each(fs.Args(), func(name string, r io.ReadSeeker) error {
+		m, err := imagemeta.DecodeAll(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "==> %s <==\n", name)
+		fmt.Fprintf(c.stdout, "Dimensions: \t%dx%d\n", m.Dimensions.Width, m.Dimensions.Height)
+		fmt.Fprint(c.stdout, m.Exif.String())
+		if !reflect.ValueOf(m.XMP).IsZero() {
+			fmt.Fprintf(c.stdout, "XMP\nCreatorTool: \t%s\nLens: \t\t%s\nSerialNumber: \t%s\nLabel: \t\t%s\n", m.XMP.Basic.CreatorTool, m.XMP.Aux.Lens, m.XMP.Aux.SerialNumber, m.XMP.Basic.Label)
+		}
+		if len(m.ICCProfile) > 0 {
+			fmt.Fprintf(c.stdout, "ICC Profile: \t%d bytes\n", len(m.ICCProfile))
+		}
+		for _, w := range m.Warnings {
+			fmt.Fprintf(c.stdout, "Warning: \t%v\n", w)
+		}
+		fmt.Fprintln(c.stdout)
+		return nil
+	})
+}
+
+// jsonOutput is the JSON output of an image.
+type jsonOutput struct {
+	File       string                   `json:"file"`
+	ImageType  string                   `json:"imageType"`
+	Dimensions meta.Dimensions          `json:"dimensions"`
+	Exif       *exif2.Exif              `json:"exif,omitempty"`
+	XMP        *xmp.XMP                 `json:"xmp,omitempty"`
+	Previews   []imagemeta.PreviewImage `json:"previews,omitempty"`
+	Warnings   []string                 `json:"warnings,omitempty"`
+	Error      string                   `json:"error,omitempty"`
+}
+
+// runJSON prints the metadata of the images as a JSON array.
+func runJSON(c *cli, args []string) error {
+	var verbose, compact, exiftool bool
+	fs := c.flagSet("json", &verbose)
+	fs.BoolVar(&compact, "compact", false, "print compact JSON")
+	fs.BoolVar(&exiftool, "exiftool", false, `print the group-qualified tags of "exiftool -j -G"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.setVerbose(verbose)
+	var outputs interface{}
+	var err error
+	if exiftool {
+		outputs, err = c.exiftoolOutputs(fs.Args())
+	} else {
+		outputs, err = c.jsonOutputs(fs.Args())
+	}
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.stdout)
+	if !compact {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(outputs)
+}
+
+// exiftoolOutputs returns the exiftool tags of the images with the "SourceFile" tag.
+func (c *cli) exiftoolOutputs(args []string) ([]imagemeta.ExiftoolTags, error) {
+	outputs := []imagemeta.ExiftoolTags{}
+	err := c.each(args, func(name string, r io.ReadSeeker) error {
+		m, err := imagemeta.DecodeAll(r)
+		if err != nil {
+			return err
+		}
+		tags := imagemeta.Exiftool(m)
+		tags["SourceFile"] = name
+		outputs = append(outputs, tags)
+		return nil
+	})
+	return outputs, err
+}
+
+// jsonOutputs returns the JSON outputs of the images.
+func (c *cli) jsonOutputs(args []string) ([]jsonOutput, error) {
+	outputs := []jsonOutput{}
+	err := c.each(args, func(name string, r io.ReadSeeker) error {
+		out := jsonOutput{File: name}
+		m, err := imagemeta.DecodeAll(r)
+		out.ImageType = m.ImageType.String()
+		if err != nil {
+			// The error is part of the output of the image
+			c.failed = true
+			out.Error = err.Error()
+			outputs = append(outputs, out)
+			return nil
+		}
+		out.Dimensions, out.Exif = m.Dimensions, &m.Exif
+		if !reflect.ValueOf(m.XMP).IsZero() {
+			out.XMP = &m.XMP
+		}
+		for _, w := range m.Warnings {
+			out.Warnings = append(out.Warnings, w.Error())
+		}
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		// Images without preview images are not an error
+		out.Previews, _ = imagemeta.Previews(r)
+		outputs = append(outputs, out)
+		return nil
+	})
+	return outputs, err
+}
+
+// runType prints the image type of the images.
+func runType(c *cli, args []string) error {
+	var verbose bool
+	fs := c.flagSet("type", &verbose)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.setVerbose(verbose)
+	return c.each(fs.Args(), func(name string, r io.ReadSeeker) error {
+		// Unknown image types are printed as "application/octet-stream"
+		it, _ := imagetype.Scan(r)
+		fmt.Fprintf(c.stdout, "%s\t%s\n", it, name)
+		return nil
+	})
+}
+
+// runPreview writes the embedded JPEG preview images of the images to files. The
+// preview images of images with the same basename in different directories are not
+// written over each other, they are reported as an error.
+func runPreview(c *cli, args []string) error {
+	var verbose, all, smallest bool
+	var dir string
+	fs := c.flagSet("preview", &verbose)
+	fs.StringVar(&dir, "o", ".", "output directory of the preview images")
+	fs.BoolVar(&all, "all", false, "extract all JPEG preview images instead of the largest")
+	fs.BoolVar(&smallest, "smallest", false, "extract the smallest JPEG preview image instead of the largest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.setVerbose(verbose)
+	// written maps the filenames of the written preview images to their images
+	written := make(map[string]string)
+	return c.each(fs.Args(), func(name string, r io.ReadSeeker) error {
+		previews, err := jpegPreviews(r)
+		if err != nil {
+			return err
+		}
+		if !all {
+			p, err := imagemeta.LargestPreview(previews)
+			if smallest {
+				p, err = imagemeta.SmallestPreview(previews)
+			}
+			if err != nil {
+				return err
+			}
+			previews = []imagemeta.PreviewImage{p}
+		}
+		base := "stdin"
+		if name != stdinName {
+			base = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+		}
+		for _, p := range previews {
+			filename := filepath.Join(dir, fmt.Sprintf("%s_%s.jpg", base, p.Source))
+			if prev, ok := written[filename]; ok {
+				return fmt.Errorf("preview image %s was already written for %s", filename, prev)
+			}
+			if err = writePreview(filename, r, p); err != nil {
+				return err
+			}
+			written[filename] = name
+			fmt.Fprintf(c.stdout, "%s\t%dx%d\t%s\n", name, p.Dimensions.Width, p.Dimensions.Height, filename)
+		}
+		return nil
+	})
+}
+
+// jpegPreviews returns the JPEG preview images of r.
+func jpegPreviews(r io.ReadSeeker) ([]imagemeta.PreviewImage, error) {
+	previews, err := imagemeta.Previews(r)
+	if err != nil {
+		return nil, err
+	}
+	var jpegs []imagemeta.PreviewImage
+	for _, p := range previews {
+		if p.ImageType == imagetype.ImageJPEG {
+			jpegs = append(jpegs, p)
+		}
+	}
+	return jpegs, nil
+}
+
+func writePreview(filename string, r io.ReadSeeker, p imagemeta.PreviewImage) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err = imagemeta.WritePreview(f, r, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runHash prints the PHash64, PHash256 and BlurHash of the images. The largest
+// JPEG preview image is hashed for images that are not JPEG, PNG or GIF images.
+func runHash(c *cli, args []string) error {
+	var verbose bool
+	fs := c.flagSet("hash", &verbose)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c.setVerbose(verbose)
+	return c.each(fs.Args(), func(name string, r io.ReadSeeker) error {
+		img, err := decodeImage(r)
+		if err != nil {
+			return err
+		}
+		p64, err := imagehash.NewPHash64(resize.Resize(64, 64, img, resize.Bilinear))
+		if err != nil {
+			return err
+		}
+		p256, err := imagehash.NewPHash256(resize.Resize(256, 256, img, resize.Bilinear))
+		if err != nil {
+			return err
+		}
+		blurHash, err := imagehash.EncodeBlurHashFast(resize.Resize(64, 64, img, resize.Bilinear))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s\t%s\t%s\t%s\n", p64, p256, blurHash, name)
+		return nil
+	})
+}
+
+// decodeImage decodes a JPEG, PNG or GIF image or the largest JPEG preview image of r.
+func decodeImage(r io.ReadSeeker) (image.Image, error) {
+	it, err := imagetype.Scan(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch it {
+	case imagetype.ImageJPEG, imagetype.ImagePNG, imagetype.ImageGIF:
+		img, _, err := image.Decode(r)
+		return img, err
+	}
+	previews, err := jpegPreviews(r)
+	if err != nil {
+		return nil, err
+	}
+	p, err := imagemeta.LargestPreview(previews)
+	if err != nil {
+		return nil, errors.New("error no JPEG preview image to hash")
+	}
+	if _, err = r.Seek(p.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return jpeg.Decode(io.LimitReader(r, p.Length))
+}
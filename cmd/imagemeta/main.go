@@ -0,0 +1,217 @@
+// Command imagemeta prints the metadata, image type, preview images and image hashes of images.
+//
+// Usage:
+//
+//	imagemeta <command> [flags] [file|glob|directory|-]...
+//
+// The commands are:
+//
+//	dump     print the metadata of the images
+//	json     print the metadata of the images as JSON
+//	type     print the image type of the images
+//	preview  extract the embedded preview images of the images
+//	hash     print the PHash64, PHash256 and BlurHash of the images
+//
+// Images are read from stdin when there are no files or the file is "-". Directories are
+// walked recursively and globs are expanded.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tdelov/imagemeta"
+)
+
+// stdinName is the name of the input that is read from stdin
+const stdinName = "-"
+
+// command is a subcommand of the command-line tool.
+type command struct {
+	name  string
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = []command{
+	{"dump", "print the metadata of the images", runDump},
+	{"json", "print the metadata of the images as JSON", runJSON},
+	{"type", "print the image type of the images", runType},
+	{"preview", "extract the embedded preview images of the images", runPreview},
+	{"hash", "print the PHash64, PHash256 and BlurHash of the images", runHash},
+}
+
+// cli is the state of an invocation of the command-line tool.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	// failed is true when an input could not be processed
+	failed bool
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
+
+// run runs the command of args and returns the exit code.
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		if err := cmd.run(c, args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			fmt.Fprintf(c.stderr, "imagemeta %s: %v\n", cmd.name, err)
+			return 2
+		}
+		if c.failed {
+			return 1
+		}
+		return 0
+	}
+	if args[0] != "help" && args[0] != "-h" && args[0] != "-help" {
+		fmt.Fprintf(c.stderr, "imagemeta: unknown command %q\n", args[0])
+	}
+	c.usage()
+	return 2
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.stderr, "Usage: imagemeta <command> [flags] [file|glob|directory|-]...\n\nThe commands are:\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "\t%-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(c.stderr, "\nImages are read from stdin when there are no files or the file is %q.\n", stdinName)
+}
+
+// flagSet returns a new flag.FlagSet of the command name with the common flags.
+func (c *cli) flagSet(name string, verbose *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.BoolVar(verbose, "v", false, "log decoding warnings to stderr")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: imagemeta %s [flags] [file|glob|directory|-]...\n", name)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// setVerbose logs the warnings of the imagemeta packages to stderr when verbose is true.
+func (c *cli) setVerbose(verbose bool) {
+	if verbose {
+		imagemeta.SetLogger(slog.NewTextHandler(c.stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		return
+	}
+	imagemeta.SetLogger(nil)
+}
+
+// errorf reports the error of an input and continues with the next input.
+func (c *cli) errorf(name string, err error) {
+	c.failed = true
+	fmt.Fprintf(c.stderr, "%s: %v\n", name, err)
+}
+
+// inputs returns the names of the files of args. Globs are expanded and directories
+// are walked recursively. Returns stdinName when there are no args.
+func inputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return []string{stdinName}, nil
+	}
+	var names []string
+	for _, arg := range args {
+		if arg == stdinName {
+			names = append(names, stdinName)
+			continue
+		}
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, err
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %q", arg)
+			}
+		}
+		for _, match := range matches {
+			// Inputs that can not be opened are reported by open
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				names = append(names, match)
+				continue
+			}
+			var files []string
+			err = filepath.WalkDir(match, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.Type().IsRegular() {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			sort.Strings(files)
+			names = append(names, files...)
+		}
+	}
+	return names, nil
+}
+
+// open returns an io.ReadSeeker of the input name. Stdin is read into memory.
+func (c *cli) open(name string) (io.ReadSeeker, func() error, error) {
+	if name == stdinName {
+		buf, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		return bytes.NewReader(buf), func() error { return nil }, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// each runs fn for each input of args. Errors of an input are reported and the
+// remaining inputs are processed.
+func (c *cli) each(args []string, fn func(name string, r io.ReadSeeker) error) error {
+	names, err := inputs(args)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		r, closeFn, err := c.open(name)
+		if err != nil {
+			c.errorf(name, err)
+			continue
+		}
+		err = fn(name, r)
+		if closeErr := closeFn(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			c.errorf(name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runCLI(stdin []byte, args ...string) (code int, stdout, stderr string) {
+	var out, errOut bytes.Buffer
+	c := &cli{stdin: bytes.NewReader(stdin), stdout: &out, stderr: &errOut}
+	code = c.run(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestCLI(t *testing.T) {
+	jpegImage, err := os.ReadFile("../../testImages/JPEG.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		stdin  []byte
+		args   []string
+		code   int
+		stdout []string
+	}{
+		{"no command", nil, nil, 2, nil},
+		{"unknown command", nil, []string{"unknown"}, 2, nil},
+		{"type", nil, []string{"type", "../../testImages/JPEG.jpg", "../../testImages/ARW.exif"}, 0, []string{"image/jpeg\t../../testImages/JPEG.jpg", "image/tiff\t../../testImages/ARW.exif"}},
+		{"type glob", nil, []string{"type", "../../testImages/CR2.*"}, 0, []string{"image/x-canon-cr2\t../../testImages/CR2.exif"}},
+		{"type stdin", jpegImage, []string{"type"}, 0, []string{"image/jpeg\t-"}},
+		{"type missing", nil, []string{"type", "../../testImages/Missing.jpg"}, 1, nil},
+		{"dump", nil, []string{"dump", "../../testImages/CR2.exif"}, 0, []string{"==> ../../testImages/CR2.exif <==", "Canon"}},
+		{"json", jpegImage, []string{"json", "-"}, 0, []string{`"imageType": "image/jpeg"`, `"Source": "IFD1"`}},
+		{"json exiftool", nil, []string{"json", "-exiftool", "../../testImages/CR2.exif"}, 0, []string{`"EXIF:Make": "Canon"`, `"SourceFile": "../../testImages/CR2.exif"`}},
+		{"preview", nil, []string{"preview", "-o", dir, "../../testImages/NEF.exif"}, 0, []string{"6000x4000\t" + filepath.Join(dir, "NEF_SubIFD0.jpg")}},
+		{"preview no preview", nil, []string{"preview", "-o", dir, "../../testImages/NoExif.jpg"}, 1, nil},
+		{"hash", jpegImage, []string{"hash"}, 0, []string{"p:93b3071c583cf4d6\t"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(test.stdin, test.args...)
+			if code != test.code {
+				t.Errorf("Incorrect exit code wanted %d got %d: %s", test.code, code, stderr)
+			}
+			for _, s := range test.stdout {
+				if !strings.Contains(stdout, s) {
+					t.Errorf("Incorrect output wanted %q in %q", s, stdout)
+				}
+			}
+		})
+	}
+
+	// The extracted preview image is a JPEG image
+	buf, err := os.ReadFile(filepath.Join(dir, "NEF_SubIFD0.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf, []byte{0xff, 0xd8}) {
+		t.Errorf("Incorrect preview image wanted JPEG SOI got %x", buf[:2])
+	}
+}
+
+func TestCLIPreviewCollision(t *testing.T) {
+	jpegImage, err := os.ReadFile("../../testImages/JPEG.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Images with the same basename in different directories
+	src, out := t.TempDir(), t.TempDir()
+	for _, d := range []string{"a", "b"} {
+		if err = os.MkdirAll(filepath.Join(src, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(src, d, "JPEG.jpg"), jpegImage, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	code, stdout, stderr := runCLI(nil, "preview", "-o", out, src)
+	if code != 1 || !strings.Contains(stderr, "already written for "+filepath.Join(src, "a", "JPEG.jpg")) {
+		t.Errorf("Incorrect preview collision wanted exit code 1 and an error got %d: %q", code, stderr)
+	}
+	if strings.Count(stdout, filepath.Join(out, "JPEG_IFD1.jpg")) != 1 {
+		t.Errorf("Incorrect preview images wanted one %s got %q", "JPEG_IFD1.jpg", stdout)
+	}
+}
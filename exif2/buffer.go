@@ -1,14 +1,22 @@
 package exif2
 
 import (
+	"io"
+	"log/slog"
 	"sync"
 
-	"github.com/rs/zerolog"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/internal/logging"
+	"github.com/tdelov/imagemeta/meta"
 )
 
 const (
 	tagMaxCount  = 84
 	bufferLength = 1024
+
+	// readAtMaxLength is the maximum length of a single read with an io.ReaderAt,
+	// the same as the size of the bufio.Reader used by the streaming decoders.
+	readAtMaxLength = 4 * bufferLength
 )
 
 // buffer for data and tags
@@ -34,6 +42,17 @@ func (b *buffer) nextTag() Tag {
 	return b.tag[b.pos+1]
 }
 
+// hasTagBetween returns true when a tag in the tagBuffer has its value
+// at an offset from start up to end.
+func (b *buffer) hasTagBetween(start, end uint32) bool {
+	for i := b.pos; i < b.len; i++ {
+		if b.tag[i].ValueOffset >= start && b.tag[i].ValueOffset < end {
+			return true
+		}
+	}
+	return false
+}
+
 // nextTag increments the position by 1
 func (b *buffer) advanceBuffer() Tag {
 	if b.pos < b.len {
@@ -51,17 +70,63 @@ func (b *buffer) validTag() bool {
 // readTagValue discards until tag.ValueOffset and reads length of tag
 func (ir *ifdReader) readTagValue() (buf []byte, err error) {
 	t := ir.buffer.currentTag()
-	if err := ir.discard(int(t.ValueOffset) - int(ir.po)); err != nil {
-		return nil, err
+	if err = ir.discard(int(t.ValueOffset) - int(ir.po)); err == nil {
+		buf, err = ir.fastRead(int(t.Size()))
 	}
-	return ir.fastRead(int(t.Size()))
+	if err != nil {
+		err = ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
+		ir.warn(err)
+	}
+	return buf, err
+}
+
+// readTagValueLength reads the value of the current tag. Returns meta.ErrBufLength
+// when the value is shorter than n bytes.
+func (ir *ifdReader) readTagValueLength(n int) ([]byte, error) {
+	buf, err := ir.readTagValue()
+	if err == nil && len(buf) < n {
+		t := ir.buffer.currentTag()
+		err = ir.decodeError(meta.ErrBufLength, t.Ifd, t.ID, t.ValueOffset)
+		ir.warn(err)
+	}
+	return buf, err
+}
+
+// readTagReader discards until tag.ValueOffset and runs fn with a reader
+// limited to the length of the tag value. The unread remainder of the
+// tag value is discarded.
+func (ir *ifdReader) readTagReader(t Tag, fn func(r io.Reader) error) (err error) {
+	if err = ir.discard(int(t.ValueOffset) - int(ir.po)); err != nil {
+		return ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
+	}
+	size := t.Size()
+	if ir.exifLength != 0 && int(ir.po)+int(size) > int(ir.exifLength) {
+		return ir.decodeError(imagetype.ErrDataLength, t.Ifd, t.ID, t.ValueOffset)
+	}
+	if ir.readerAt != nil {
+		err = fn(io.NewSectionReader(ir.readerAt, ir.readerAtOffset+int64(ir.po), int64(size)))
+		ir.po += size
+		return ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
+	}
+	lr := &io.LimitedReader{R: ir.reader, N: int64(size)}
+	err = fn(lr)
+	ir.po += size - uint32(lr.N)
+	if lr.N > 0 {
+		if discardErr := ir.discard(int(lr.N)); err == nil {
+			err = discardErr
+		}
+	}
+	return ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
 }
 
 // seekToTag seeks with the underlying reader to given tag value
 func (ir *ifdReader) seekToTag(t Tag) (err error) {
 	discard := int(t.ValueOffset) - int(ir.po)
-	if err = ir.discard(discard); err != nil && ir.logLevelError() {
-		t.logTag(ir.logError(err)).Uint32("ifdReaderPosition", ir.po).Uint32("discard", uint32(discard)).Send()
+	if err = ir.discard(discard); err != nil {
+		err = ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
+		if ir.logLevelError() {
+			t.logTag(ir.logError(err)).Uint32("ifdReaderPosition", ir.po).Uint32("discard", uint32(discard)).Send()
+		}
 	}
 	return
 }
@@ -77,13 +142,22 @@ func (b *buffer) resetPosition() {
 
 // addTagBuffer adds the given tag to the tagBuffer
 func (ir *ifdReader) addTagBuffer(t Tag) {
-	if t.ValueOffset < ir.po {
-		if ir.logLevelWarn() {
-			t.logTag(ir.logWarn()).Uint32("readerOffset", ir.po).Msg("Incompatible reverse exif tag")
+	b := ir.buffer
+	if ir.readerAt != nil {
+		// Tags are read in the order they are added with random access
+		if b.len < tagMaxCount {
+			b.tag[b.len] = t
+			b.len++
+			return
 		}
+		ir.warn(ir.decodeError(ErrTagMaxCount, t.Ifd, t.ID, t.ValueOffset))
+		return
+	}
+	if t.ValueOffset < ir.po {
+		// The streaming decoder does not read backward
+		ir.warn(ir.decodeError(ErrReverseTagOffset, t.Ifd, t.ID, t.ValueOffset))
 		return
 	}
-	b := ir.buffer
 	if b.len < tagMaxCount {
 		for i := b.len; i > 0; i-- {
 			if t.ValueOffset > b.tag[i-1].ValueOffset {
@@ -107,9 +181,7 @@ func (ir *ifdReader) addTagBuffer(t Tag) {
 			return
 		}
 	}
-	if ir.logLevelWarn() {
-		ir.logWarn().Int32("tagMaxCount", tagMaxCount).Msg("error tagMaxCount is too short")
-	}
+	ir.warn(ir.decodeError(ErrTagMaxCount, t.Ifd, t.ID, t.ValueOffset))
 }
 
 // discard, discards n amount from ir.Reader
@@ -117,6 +189,11 @@ func (ir *ifdReader) discard(n int) (err error) {
 	if n == 0 {
 		return nil
 	}
+	if ir.readerAt != nil {
+		// Move the reader offset in either direction
+		ir.po = uint32(int(ir.po) + n)
+		return nil
+	}
 	if int(ir.exifLength) < n+int(ir.po) {
 		n = int(ir.exifLength) - int(ir.po)
 	}
@@ -144,9 +221,11 @@ func (b *buffer) clear() {
 	b.pos = 0
 }
 
-// MarshalZerologArray is a zerolog interface for logging
-func (b *buffer) MarshalZerologArray(a *zerolog.Array) {
+// LogValue is a slog.LogValuer interface for logging
+func (b *buffer) LogValue() slog.Value {
+	tags := make([]slog.Value, 0, b.len-b.pos)
 	for i := b.pos; i < b.len; i++ {
-		a.Object(b.tag[i])
+		tags = append(tags, b.tag[i].LogValue())
 	}
+	return logging.Array(tags...)
 }
@@ -0,0 +1,245 @@
+package exif2
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/ifds/gpsifd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+const (
+	// exifDateFormat is the time format of Exif dates
+	exifDateFormat = "2006:01:02 15:04:05"
+
+	// gpsDateFormat is the time format of the GPSDateStamp
+	gpsDateFormat = "2006:01:02"
+)
+
+var (
+	// exifVersion is the ExifVersion written by the Encoder
+	exifVersion = []byte("0232")
+
+	// gpsVersion is the GPSVersionID written by the Encoder
+	gpsVersion = []byte{2, 3, 0, 0}
+)
+
+// Encode encodes the Exif into an Exif APP1 payload with the given byte order.
+func Encode(e Exif, byteOrder utils.ByteOrder) ([]byte, error) {
+	enc := NewEncoder(byteOrder)
+	enc.SetExif(e)
+	return enc.EncodeAPP1()
+}
+
+// SetExif sets the entries of the Encoder from the non-zero fields of the Exif.
+// MakerNotes and the fields that reference image data of the file (StripOffsets,
+// ThumbnailOffset) are not encoded.
+func (enc *Encoder) SetExif(e Exif) {
+	// IFD0
+	enc.setString(ifds.IFD0, ifds.ProcessingSoftware, e.ProcessingSoftware)
+	enc.setString(ifds.IFD0, ifds.DocumentName, e.DocumentName)
+	enc.setString(ifds.IFD0, ifds.ImageDescription, e.ImageDescription)
+	enc.setString(ifds.IFD0, ifds.Make, e.Make)
+	enc.setString(ifds.IFD0, ifds.Model, e.Model)
+	enc.setShort(ifds.IFD0, ifds.Orientation, uint16(e.Orientation))
+	if e.XResolution != 0 {
+		enc.ifd0 = setEntry(enc.ifd0, NewRationalEntry(ifds.XResolution, [2]uint32{e.XResolution, 1}))
+	}
+	if e.YResolution != 0 {
+		enc.ifd0 = setEntry(enc.ifd0, NewRationalEntry(ifds.YResolution, [2]uint32{e.YResolution, 1}))
+	}
+	enc.setShort(ifds.IFD0, ifds.ResolutionUnit, e.ResolutionUnit)
+	enc.setString(ifds.IFD0, ifds.Software, e.Software)
+	enc.setDate(ifds.IFD0, ifds.DateTime, e.Time.modifyDate)
+	enc.setString(ifds.IFD0, ifds.Artist, e.Artist)
+	if len(e.ApplicationNotes) > 0 {
+		enc.ifd0 = setEntry(enc.ifd0, NewByteEntry(ifds.ApplicationNotes, e.ApplicationNotes))
+	}
+	enc.setShort(ifds.IFD0, ifds.Rating, e.Rating)
+	enc.setString(ifds.IFD0, ifds.Copyright, e.Copyright)
+
+	// ExifIFD
+	enc.exif = setEntry(enc.exif, NewUndefinedEntry(exififd.ExifVersion, exifVersion))
+	if e.ExposureTime > 0 {
+		enc.exif = setEntry(enc.exif, NewRationalEntry(exififd.ExposureTime, exposureTimeRational(float64(e.ExposureTime))))
+	}
+	if e.FNumber > 0 {
+		enc.exif = setEntry(enc.exif, NewRationalEntry(exififd.FNumber, rationalU(float64(e.FNumber))))
+	}
+	enc.setShort(ifds.ExifIFD, exififd.ExposureProgram, uint16(e.ExposureProgram))
+	if e.ISOSpeed > 0 {
+		iso := e.ISOSpeed
+		if iso > math.MaxUint16 {
+			iso = math.MaxUint16
+		}
+		enc.setShort(ifds.ExifIFD, exififd.ISOSpeedRatings, uint16(iso))
+	}
+	enc.setShort(ifds.ExifIFD, ifds.SelfTimerMode, e.SelfTimerMode)
+	enc.setDate(ifds.ExifIFD, exififd.DateTimeOriginal, e.Time.dateTimeOriginal)
+	enc.setDate(ifds.ExifIFD, exififd.DateTimeDigitized, e.Time.createDate)
+	enc.setOffsetTime(exififd.OffsetTime, e.Time.offsetTime)
+	enc.setOffsetTime(exififd.OffsetTimeOriginal, e.Time.offsetTimeOriginal)
+	enc.setOffsetTime(exififd.OffsetTimeDigitized, e.Time.offsetTimeDigitized)
+	if e.ExposureBias != 0 {
+		enc.exif = setEntry(enc.exif, NewSRationalEntry(exififd.ExposureBiasValue, [2]int32{int32(e.ExposureBias >> 8), int32(uint16(e.ExposureBias) << 8 >> 8)}))
+	}
+	if e.SubjectDistance > 0 {
+		enc.exif = setEntry(enc.exif, NewRationalEntry(exififd.SubjectDistance, rationalU(float64(e.SubjectDistance))))
+	}
+	enc.setShort(ifds.ExifIFD, exififd.MeteringMode, uint16(e.MeteringMode))
+	enc.setShort(ifds.ExifIFD, exififd.Flash, uint16(e.Flash))
+	if e.FocalLength > 0 {
+		enc.exif = setEntry(enc.exif, NewRationalEntry(exififd.FocalLength, rationalU(float64(e.FocalLength))))
+	}
+	if e.ImageNumber != 0 {
+		enc.exif = setEntry(enc.exif, NewLongEntry(ifds.ImageNumber, e.ImageNumber))
+	}
+	if len(e.SubjectArea) > 0 {
+		enc.exif = setEntry(enc.exif, NewShortEntry(exififd.SubjectArea, e.SubjectArea...))
+	}
+	enc.setSubSecTime(exififd.SubSecTime, e.Time.subSecTime)
+	enc.setSubSecTime(exififd.SubSecTimeOriginal, e.Time.subSecTimeOriginal)
+	enc.setSubSecTime(exififd.SubSecTimeDigitized, e.Time.subSecTimeDigitized)
+	enc.setShort(ifds.ExifIFD, exififd.ColorSpace, uint16(e.ColorSpace))
+	if e.ImageWidth != 0 {
+		enc.exif = setEntry(enc.exif, NewLongEntry(exififd.PixelXDimension, uint32(e.ImageWidth)))
+	}
+	if e.ImageHeight != 0 {
+		enc.exif = setEntry(enc.exif, NewLongEntry(exififd.PixelYDimension, uint32(e.ImageHeight)))
+	}
+	enc.setShort(ifds.ExifIFD, exififd.ExposureMode, uint16(e.ExposureMode))
+	if e.FocalLengthIn35mmFormat > 0 {
+		enc.setShort(ifds.ExifIFD, exififd.FocalLengthIn35mmFilm, uint16(math.Round(float64(e.FocalLengthIn35mmFormat))))
+	}
+	enc.setString(ifds.ExifIFD, exififd.ImageUniqueID, e.ImageUniqueID)
+	enc.setString(ifds.ExifIFD, exififd.CameraOwnerName, e.OwnerName)
+	enc.setString(ifds.ExifIFD, exififd.BodySerialNumber, e.CameraSerial)
+	if e.LensInfo != (LensInfo{}) {
+		l := e.LensInfo
+		enc.exif = setEntry(enc.exif, NewRationalEntry(exififd.LensSpecification, [2]uint32{l[0], l[1]}, [2]uint32{l[2], l[3]}, [2]uint32{l[4], l[5]}, [2]uint32{l[6], l[7]}))
+	}
+	enc.setString(ifds.ExifIFD, exififd.LensMake, e.LensMake)
+	enc.setString(ifds.ExifIFD, exififd.LensModel, e.LensModel)
+	enc.setString(ifds.ExifIFD, exififd.LensSerialNumber, e.LensSerial)
+
+	// GPSIFD
+	enc.setGPS(e.GPS)
+}
+
+// setGPS sets the entries of the GPSIFD from the GPSInfo.
+func (enc *Encoder) setGPS(g GPSInfo) {
+	if g == (GPSInfo{}) {
+		return
+	}
+	enc.gps = setEntry(enc.gps, NewByteEntry(gpsifd.GPSVersionID, gpsVersion))
+	if g.latitude != 0 || g.longitude != 0 {
+		enc.gps = setEntry(enc.gps, NewASCIIEntry(gpsifd.GPSLatitudeRef, gpsRef(g.latitudeRef, "N", "S")))
+		enc.gps = setEntry(enc.gps, NewRationalEntry(gpsifd.GPSLatitude, gpsCoord(g.latitude)...))
+		enc.gps = setEntry(enc.gps, NewASCIIEntry(gpsifd.GPSLongitudeRef, gpsRef(g.longitudeRef, "E", "W")))
+		enc.gps = setEntry(enc.gps, NewRationalEntry(gpsifd.GPSLongitude, gpsCoord(g.longitude)...))
+	}
+	if g.altitude != 0 {
+		var ref byte
+		if g.altitudeRef {
+			ref = 1
+		}
+		enc.gps = setEntry(enc.gps, NewByteEntry(gpsifd.GPSAltitudeRef, []byte{ref}))
+		enc.gps = setEntry(enc.gps, NewRationalEntry(gpsifd.GPSAltitude, rationalU(float64(g.altitude))))
+	}
+	if !g.date.IsZero() {
+		enc.gps = setEntry(enc.gps, NewRationalEntry(gpsifd.GPSTimeStamp, [2]uint32{g.time / hoursToSeconds, 1}, [2]uint32{g.time % hoursToSeconds / minutesToSeconds, 1}, [2]uint32{g.time % minutesToSeconds, 1}))
+		enc.gps = setEntry(enc.gps, NewASCIIEntry(gpsifd.GPSDateStamp, g.date.Format(gpsDateFormat)))
+	}
+}
+
+// setString sets an ASCII entry when the string is not empty.
+func (enc *Encoder) setString(ifdType ifds.IfdType, id tag.ID, s string) {
+	if s != "" {
+		_ = enc.Set(ifdType, NewASCIIEntry(id, s))
+	}
+}
+
+// setShort sets a SHORT entry when the value is not zero.
+func (enc *Encoder) setShort(ifdType ifds.IfdType, id tag.ID, v uint16) {
+	if v != 0 {
+		_ = enc.Set(ifdType, NewShortEntry(id, v))
+	}
+}
+
+// setDate sets an Exif date entry when the time is not zero.
+func (enc *Encoder) setDate(ifdType ifds.IfdType, id tag.ID, t time.Time) {
+	if !t.IsZero() {
+		_ = enc.Set(ifdType, NewASCIIEntry(id, t.Format(exifDateFormat)))
+	}
+}
+
+// setOffsetTime sets an OffsetTime entry of the ExifIFD.
+func (enc *Encoder) setOffsetTime(id tag.ID, l *time.Location) {
+	if l != nil {
+		_, offset := time.Date(2000, 1, 1, 0, 0, 0, 0, l).Zone()
+		enc.exif = setEntry(enc.exif, NewASCIIEntry(id, offsetTimeString(offset)))
+	}
+}
+
+// offsetTimeString returns the time zone offset in seconds formatted as "+hh:mm".
+func offsetTimeString(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, offset/hoursToSeconds, offset%hoursToSeconds/minutesToSeconds)
+}
+
+// setSubSecTime sets a SubSecTime entry of the ExifIFD in milliseconds.
+func (enc *Encoder) setSubSecTime(id tag.ID, ms uint16) {
+	if ms != 0 {
+		enc.exif = setEntry(enc.exif, NewASCIIEntry(id, fmt.Sprintf("%03d", ms)))
+	}
+}
+
+// gpsRef returns ref when negative is true and pos otherwise.
+func gpsRef(negative bool, pos string, ref string) string {
+	if negative {
+		return ref
+	}
+	return pos
+}
+
+// gpsCoord returns the degrees, minutes and seconds of a GPS coordinate.
+// Seconds have a precision of 1/10000.
+func gpsCoord(coord float64) [][2]uint32 {
+	coord = math.Abs(coord)
+	deg := math.Floor(coord)
+	min := math.Floor((coord - deg) * 60)
+	sec := math.Round((coord-deg-min/60)*3600*10000) / 10000
+	return [][2]uint32{{uint32(deg), 1}, {uint32(min), 1}, rationalU(sec)}
+}
+
+// exposureTimeRational returns an exposure time as a fraction of a second
+// when it is shorter than a second.
+func exposureTimeRational(t float64) [2]uint32 {
+	if t < 1 {
+		if d := math.Round(1 / t); math.Abs(1/d-t) < t/100 {
+			return [2]uint32{1, uint32(d)}
+		}
+	}
+	return rationalU(t)
+}
+
+// rationalU returns f as an unsigned rational with a precision of 1/10000.
+func rationalU(f float64) [2]uint32 {
+	if f*10000 > math.MaxUint32 {
+		return [2]uint32{uint32(math.Min(math.Round(f), math.MaxUint32)), 1}
+	}
+	n, d := uint32(math.Round(f*10000)), uint32(10000)
+	a, b := n, d
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return [2]uint32{n / a, d / a}
+}
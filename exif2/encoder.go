@@ -0,0 +1,421 @@
+package exif2
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// Encoder errors
+var (
+	ErrEncodeIfd    = errors.New("error Ifd not supported by Encoder")
+	ErrEncodeLength = errors.New("error encoded Exif exceeds maximum length")
+	ErrEncodeTiff   = errors.New("error invalid Tiff block")
+)
+
+const (
+	// tiffHeaderLength is the length of the Tiff header
+	tiffHeaderLength = 8
+
+	// ifdEntryLength is the length of an IFD entry
+	ifdEntryLength = 12
+
+	// app1MaxLength is the maximum length of an Exif APP1 payload. The
+	// segment length of 2 bytes includes itself.
+	app1MaxLength = math.MaxUint16 - 2
+)
+
+// exifPrefix is the prefix of the Exif APP1 payload in a JPEG
+var exifPrefix = []byte("Exif\x00\x00")
+
+// Entry is an Exif tag with a value that is encoded by an Encoder.
+// An Entry is created with one of the New...Entry functions.
+type Entry struct {
+	ID   tag.ID
+	Type tag.Type
+
+	buf    []byte   // BYTE, ASCII and UNDEFINED values
+	values []uint64 // numeric values, Rationals are numerator and denominator pairs
+}
+
+// NewASCIIEntry returns a new ASCII Entry. The NUL terminator is added.
+func NewASCIIEntry(id tag.ID, s string) Entry {
+	buf := make([]byte, len(s)+1)
+	copy(buf, s)
+	return Entry{ID: id, Type: tag.TypeASCII, buf: buf}
+}
+
+// NewByteEntry returns a new BYTE Entry.
+func NewByteEntry(id tag.ID, buf []byte) Entry {
+	return Entry{ID: id, Type: tag.TypeByte, buf: buf}
+}
+
+// NewUndefinedEntry returns a new UNDEFINED Entry.
+func NewUndefinedEntry(id tag.ID, buf []byte) Entry {
+	return Entry{ID: id, Type: tag.TypeUndefined, buf: buf}
+}
+
+// NewShortEntry returns a new SHORT Entry.
+func NewShortEntry(id tag.ID, v ...uint16) Entry {
+	e := Entry{ID: id, Type: tag.TypeShort, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = uint64(v[i])
+	}
+	return e
+}
+
+// NewSShortEntry returns a new SSHORT Entry.
+func NewSShortEntry(id tag.ID, v ...int16) Entry {
+	e := Entry{ID: id, Type: tag.TypeSignedShort, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = uint64(uint16(v[i]))
+	}
+	return e
+}
+
+// NewLongEntry returns a new LONG Entry.
+func NewLongEntry(id tag.ID, v ...uint32) Entry {
+	e := Entry{ID: id, Type: tag.TypeLong, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = uint64(v[i])
+	}
+	return e
+}
+
+// NewSLongEntry returns a new SLONG Entry.
+func NewSLongEntry(id tag.ID, v ...int32) Entry {
+	e := Entry{ID: id, Type: tag.TypeSignedLong, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = uint64(uint32(v[i]))
+	}
+	return e
+}
+
+// NewRationalEntry returns a new RATIONAL Entry from numerator and denominator pairs.
+func NewRationalEntry(id tag.ID, v ...[2]uint32) Entry {
+	e := Entry{ID: id, Type: tag.TypeRational, values: make([]uint64, 0, 2*len(v))}
+	for i := range v {
+		e.values = append(e.values, uint64(v[i][0]), uint64(v[i][1]))
+	}
+	return e
+}
+
+// NewSRationalEntry returns a new SRATIONAL Entry from numerator and denominator pairs.
+func NewSRationalEntry(id tag.ID, v ...[2]int32) Entry {
+	e := Entry{ID: id, Type: tag.TypeSignedRational, values: make([]uint64, 0, 2*len(v))}
+	for i := range v {
+		e.values = append(e.values, uint64(uint32(v[i][0])), uint64(uint32(v[i][1])))
+	}
+	return e
+}
+
+// NewFloatEntry returns a new FLOAT Entry.
+func NewFloatEntry(id tag.ID, v ...float32) Entry {
+	e := Entry{ID: id, Type: tag.TypeFloat, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = uint64(math.Float32bits(v[i]))
+	}
+	return e
+}
+
+// NewDoubleEntry returns a new DOUBLE Entry.
+func NewDoubleEntry(id tag.ID, v ...float64) Entry {
+	e := Entry{ID: id, Type: tag.TypeDouble, values: make([]uint64, len(v))}
+	for i := range v {
+		e.values[i] = math.Float64bits(v[i])
+	}
+	return e
+}
+
+// newRawEntry returns a new Entry with the value buf that is already encoded.
+func newRawEntry(id tag.ID, t tag.Type, buf []byte) Entry {
+	return Entry{ID: id, Type: t, buf: buf}
+}
+
+// Count returns the number of values of the Entry.
+func (e Entry) Count() uint32 {
+	if e.buf != nil {
+		return uint32(len(e.buf)) / uint32(e.Type.Size())
+	}
+	switch e.Type {
+	case tag.TypeRational, tag.TypeSignedRational:
+		return uint32(len(e.values) / 2)
+	}
+	return uint32(len(e.values))
+}
+
+// Size returns the size of the Entry's value
+func (e Entry) Size() uint32 {
+	return uint32(e.Type.Size()) * e.Count()
+}
+
+// appendValue appends the value of the Entry to buf with the byte order.
+func (e Entry) appendValue(buf []byte, bo utils.ByteOrder) []byte {
+	if e.buf != nil {
+		return append(buf, e.buf...)
+	}
+	var b [8]byte
+	for _, v := range e.values {
+		switch e.Type {
+		case tag.TypeShort, tag.TypeSignedShort:
+			bo.PutUint16(b[:2], uint16(v))
+			buf = append(buf, b[:2]...)
+		case tag.TypeDouble:
+			bo.PutUint64(b[:8], v)
+			buf = append(buf, b[:8]...)
+		default:
+			bo.PutUint32(b[:4], uint32(v))
+			buf = append(buf, b[:4]...)
+		}
+	}
+	return buf
+}
+
+// Encoder encodes Exif tags of IFD0, ExifIFD, GPSIFD, the Interoperability IFD and IFD1
+// into a Tiff block or an Exif APP1 payload.
+//
+// The offsets of the ExifIFD, GPSIFD and Interoperability IFD and of the thumbnail in IFD1
+// are set by the Encoder.
+type Encoder struct {
+	byteOrder utils.ByteOrder
+
+	ifd0, exif, gps, iop, ifd1 []Entry
+	thumbnail                  []byte
+}
+
+// NewEncoder returns a new Encoder that encodes with the given byte order.
+// Defaults to utils.BigEndian when the byte order is unknown.
+func NewEncoder(byteOrder utils.ByteOrder) *Encoder {
+	if byteOrder != utils.LittleEndian {
+		byteOrder = utils.BigEndian
+	}
+	return &Encoder{byteOrder: byteOrder}
+}
+
+// entries returns the entries of the IFD of the given type.
+func (enc *Encoder) entries(ifdType ifds.IfdType) (*[]Entry, error) {
+	switch ifdType {
+	case ifds.IFD0:
+		return &enc.ifd0, nil
+	case ifds.ExifIFD:
+		return &enc.exif, nil
+	case ifds.GPSIFD:
+		return &enc.gps, nil
+	case ifds.IopIFD:
+		return &enc.iop, nil
+	}
+	return nil, ErrEncodeIfd
+}
+
+// Set sets the Entry of the IFD, an existing Entry with the same tag ID is replaced.
+// Supports IFD0, ExifIFD, GPSIFD and IopIFD, use SetIfd1 for IFD1.
+func (enc *Encoder) Set(ifdType ifds.IfdType, e Entry) error {
+	entries, err := enc.entries(ifdType)
+	if err != nil {
+		return err
+	}
+	*entries = setEntry(*entries, e)
+	return nil
+}
+
+// Delete deletes the Entry with the tag ID from the IFD.
+func (enc *Encoder) Delete(ifdType ifds.IfdType, id tag.ID) error {
+	entries, err := enc.entries(ifdType)
+	if err != nil {
+		return err
+	}
+	*entries = deleteEntry(*entries, id)
+	return nil
+}
+
+// SetIfd1 sets the Entry of IFD1, the IFD of the thumbnail image.
+func (enc *Encoder) SetIfd1(e Entry) {
+	enc.ifd1 = setEntry(enc.ifd1, e)
+}
+
+// SetThumbnail sets the JPEG thumbnail image that is referenced from IFD1.
+func (enc *Encoder) SetThumbnail(buf []byte) {
+	enc.thumbnail = buf
+}
+
+func setEntry(entries []Entry, e Entry) []Entry {
+	for i := range entries {
+		if entries[i].ID == e.ID {
+			entries[i] = e
+			return entries
+		}
+	}
+	return append(entries, e)
+}
+
+func deleteEntry(entries []Entry, id tag.ID) []Entry {
+	for i := range entries {
+		if entries[i].ID == id {
+			return append(entries[:i], entries[i+1:]...)
+		}
+	}
+	return entries
+}
+
+// encodeIfd is an IFD with its offset during encoding
+type encodeIfd struct {
+	entries []Entry
+	offset  uint32
+	next    *encodeIfd
+}
+
+// newEncodeIfd returns the entries with the pointer tags removed and sorted by tag ID.
+func newEncodeIfd(entries []Entry, pointers ...tag.ID) *encodeIfd {
+	ifd := &encodeIfd{entries: make([]Entry, 0, len(entries)+len(pointers))}
+	for _, e := range entries {
+		if !containsID(pointers, e.ID) {
+			ifd.entries = append(ifd.entries, e)
+		}
+	}
+	return ifd
+}
+
+func containsID(ids []tag.ID, id tag.ID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// length returns the length of the IFD and its values.
+func (ifd *encodeIfd) length() uint32 {
+	n := 2 + ifdEntryLength*uint32(len(ifd.entries)) + 4
+	for _, e := range ifd.entries {
+		if size := e.Size(); size > 4 {
+			n += size + size&1
+		}
+	}
+	return n
+}
+
+// setPointer sets the value of the LONG pointer tag to offset.
+func (ifd *encodeIfd) setPointer(id tag.ID, offset uint32) {
+	for i := range ifd.entries {
+		if ifd.entries[i].ID == id {
+			ifd.entries[i].values[0] = uint64(offset)
+		}
+	}
+}
+
+// appendIfd appends the IFD and its values to buf, buf starts at the Tiff header.
+func (ifd *encodeIfd) appendIfd(buf []byte, bo utils.ByteOrder) []byte {
+	var b [4]byte
+	bo.PutUint16(b[:2], uint16(len(ifd.entries)))
+	buf = append(buf, b[:2]...)
+
+	valueOffset := ifd.offset + 2 + ifdEntryLength*uint32(len(ifd.entries)) + 4
+	values := make([]byte, 0, ifd.length())
+	for _, e := range ifd.entries {
+		bo.PutUint16(b[:2], uint16(e.ID))
+		buf = append(buf, b[:2]...)
+		bo.PutUint16(b[:2], uint16(e.Type))
+		buf = append(buf, b[:2]...)
+		bo.PutUint32(b[:4], e.Count())
+		buf = append(buf, b[:4]...)
+		if e.Size() <= 4 {
+			// Values of 4 bytes or less are left-justified in the value offset
+			n := len(buf)
+			buf = append(e.appendValue(buf, bo), 0, 0, 0, 0)[:n+4]
+			continue
+		}
+		bo.PutUint32(b[:4], valueOffset+uint32(len(values)))
+		buf = append(buf, b[:4]...)
+		values = e.appendValue(values, bo)
+		if len(values)&1 == 1 {
+			// Values start on a word boundary
+			values = append(values, 0)
+		}
+	}
+	var next uint32
+	if ifd.next != nil {
+		next = ifd.next.offset
+	}
+	bo.PutUint32(b[:4], next)
+	buf = append(buf, b[:4]...)
+	return append(buf, values...)
+}
+
+// EncodeTiff encodes the IFDs into a Tiff block that starts with the Tiff header.
+func (enc *Encoder) EncodeTiff() ([]byte, error) {
+	ifd0 := newEncodeIfd(enc.ifd0, ifds.ExifTag, ifds.GPSTag)
+	exif := newEncodeIfd(enc.exif, exififd.InteroperabilityTag)
+	iop := newEncodeIfd(enc.iop)
+	gps := newEncodeIfd(enc.gps)
+	ifd1 := newEncodeIfd(enc.ifd1, ifds.JPEGInterchangeFormat, ifds.JPEGInterchangeFormatLength)
+
+	if len(iop.entries) > 0 {
+		exif.entries = append(exif.entries, NewLongEntry(exififd.InteroperabilityTag, 0))
+	}
+	if len(exif.entries) > 0 {
+		ifd0.entries = append(ifd0.entries, NewLongEntry(ifds.ExifTag, 0))
+	}
+	if len(gps.entries) > 0 {
+		ifd0.entries = append(ifd0.entries, NewLongEntry(ifds.GPSTag, 0))
+	}
+	if len(enc.thumbnail) > 0 {
+		ifd1.entries = append(ifd1.entries, NewLongEntry(ifds.JPEGInterchangeFormat, 0), NewLongEntry(ifds.JPEGInterchangeFormatLength, uint32(len(enc.thumbnail))))
+	}
+
+	// Layout of the IFDs after the Tiff header
+	layout := []*encodeIfd{ifd0}
+	for _, ifd := range []*encodeIfd{exif, iop, gps, ifd1} {
+		if len(ifd.entries) > 0 {
+			layout = append(layout, ifd)
+		}
+	}
+	if len(ifd1.entries) > 0 {
+		ifd0.next = ifd1
+	}
+	length := uint64(tiffHeaderLength)
+	for _, ifd := range layout {
+		sort.Slice(ifd.entries, func(i, j int) bool { return ifd.entries[i].ID < ifd.entries[j].ID })
+		ifd.offset = uint32(length)
+		length += uint64(ifd.length())
+	}
+	length += uint64(len(enc.thumbnail))
+	if length > math.MaxUint32 {
+		return nil, ErrEncodeLength
+	}
+	ifd0.setPointer(ifds.ExifTag, exif.offset)
+	ifd0.setPointer(ifds.GPSTag, gps.offset)
+	exif.setPointer(exififd.InteroperabilityTag, iop.offset)
+	ifd1.setPointer(ifds.JPEGInterchangeFormat, ifd1.offset+ifd1.length())
+
+	buf := make([]byte, tiffHeaderLength, length)
+	if enc.byteOrder == utils.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	enc.byteOrder.PutUint16(buf[2:4], 0x002a)
+	enc.byteOrder.PutUint32(buf[4:8], ifd0.offset)
+	for _, ifd := range layout {
+		buf = ifd.appendIfd(buf, enc.byteOrder)
+	}
+	return append(buf, enc.thumbnail...), nil
+}
+
+// EncodeAPP1 encodes the IFDs into an Exif APP1 payload, the Exif prefix
+// followed by the Tiff block, without the JPEG marker and segment length.
+func (enc *Encoder) EncodeAPP1() ([]byte, error) {
+	tiff, err := enc.EncodeTiff()
+	if err != nil {
+		return nil, err
+	}
+	if len(exifPrefix)+len(tiff) > app1MaxLength {
+		return nil, ErrEncodeLength
+	}
+	return append(append(make([]byte, 0, len(exifPrefix)+len(tiff)), exifPrefix...), tiff...), nil
+}
@@ -0,0 +1,222 @@
+package exif2
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// decodeTiff decodes a Tiff block encoded by an Encoder.
+func decodeTiff(t *testing.T, buf []byte) Exif {
+	t.Helper()
+	bo := utils.BinaryOrder(buf)
+	h := meta.NewExifHeader(bo, bo.Uint32(buf[4:8]), 0, uint32(len(buf)), imagetype.ImageTiff)
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err := ir.DecodeTiff(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	return ir.Exif
+}
+
+func TestEncode(t *testing.T) {
+	loc := time.FixedZone("", -5*60*60)
+	original := time.Date(2023, 6, 15, 10, 30, 45, 250*int(time.Millisecond), loc)
+
+	var e Exif
+	e.Make = "Canon"
+	e.Model = "Canon EOS 6D"
+	e.Copyright = "Jane Doe"
+	e.Orientation = meta.OrientationRotate180
+	e.ExposureTime = 1.0 / 250
+	e.FNumber = 2.8
+	e.FocalLength = 50
+	e.ISOSpeed = 400
+	e.ExposureBias = meta.NewExposureBias(-2, 3)
+	e.LensModel = "EF50mm f/1.8 STM"
+	e.SetDateTimeOriginal(original)
+	e.GPS.SetLatitude(-33.8688)
+	e.GPS.SetLongitude(151.2093)
+	e.GPS.SetAltitude(58)
+	e.GPS.SetDate(original)
+
+	for _, bo := range []utils.ByteOrder{utils.LittleEndian, utils.BigEndian} {
+		buf, err := Encode(e, bo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(buf, exifPrefix) {
+			t.Fatalf("Incorrect APP1 prefix got %q", buf[:6])
+		}
+		d := decodeTiff(t, buf[len(exifPrefix):])
+		if d.Make != e.Make || d.Model != e.Model || d.Copyright != e.Copyright || d.LensModel != e.LensModel {
+			t.Errorf("%s: Incorrect strings wanted %q %q %q %q got %q %q %q %q", bo, e.Make, e.Model, e.Copyright, e.LensModel, d.Make, d.Model, d.Copyright, d.LensModel)
+		}
+		if d.Orientation != e.Orientation || d.ISOSpeed != e.ISOSpeed || d.ExposureBias != e.ExposureBias {
+			t.Errorf("%s: Incorrect values wanted %v %d %s got %v %d %s", bo, e.Orientation, e.ISOSpeed, e.ExposureBias, d.Orientation, d.ISOSpeed, d.ExposureBias)
+		}
+		if d.ExposureTime != e.ExposureTime || d.FNumber != e.FNumber || d.FocalLength != e.FocalLength {
+			t.Errorf("%s: Incorrect rationals wanted %s %v %s got %s %v %s", bo, e.ExposureTime, e.FNumber, e.FocalLength, d.ExposureTime, d.FNumber, d.FocalLength)
+		}
+		if !d.DateTimeOriginal().Equal(original) {
+			t.Errorf("%s: Incorrect DateTimeOriginal wanted %s got %s", bo, original, d.DateTimeOriginal())
+		}
+		if lat, lng := d.GPS.Latitude(), d.GPS.Longitude(); lat < -33.8689 || lat > -33.8687 || lng < 151.2092 || lng > 151.2094 {
+			t.Errorf("%s: Incorrect GPS coordinates got %f %f", bo, lat, lng)
+		}
+		if d.GPS.Altitude() != 58 || !d.GPS.Date().Equal(original.Truncate(time.Second)) {
+			t.Errorf("%s: Incorrect GPS altitude and date got %f %s", bo, d.GPS.Altitude(), d.GPS.Date())
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	enc := NewEncoder(utils.LittleEndian)
+	if err := enc.Set(ifds.IFD0, NewASCIIEntry(ifds.Artist, "John")); err != nil {
+		t.Fatal(err)
+	}
+	// Replaces the Artist
+	_ = enc.Set(ifds.IFD0, NewASCIIEntry(ifds.Artist, "Jane"))
+	_ = enc.Set(ifds.IFD0, NewASCIIEntry(ifds.Make, "Nikon"))
+	_ = enc.Set(ifds.ExifIFD, NewLongEntry(exififd.ISOSpeedRatings, 800))
+	if err := enc.Delete(ifds.IFD0, ifds.Make); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Set(ifds.MkNoteCanonIFD, NewShortEntry(1, 1)); err != ErrEncodeIfd {
+		t.Errorf("Incorrect error wanted %s got %v", ErrEncodeIfd, err)
+	}
+	thumbnail := []byte{0xff, 0xd8, 0xff, 0xd9}
+	enc.SetIfd1(NewShortEntry(ifds.Compression, 6))
+	enc.SetThumbnail(thumbnail)
+
+	buf, err := enc.EncodeTiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(buf, thumbnail) {
+		t.Errorf("Incorrect thumbnail at end of Tiff got %x", buf[len(buf)-4:])
+	}
+	d := decodeTiff(t, buf)
+	if d.Artist != "Jane" || d.Make != "" || d.ISOSpeed != 800 {
+		t.Errorf("Incorrect Exif wanted %q %q %d got %q %q %d", "Jane", "", 800, d.Artist, d.Make, d.ISOSpeed)
+	}
+
+	// IFD1 follows IFD0 and references the thumbnail
+	ifd0, next := ifdValues(t, buf, 8)
+	if _, ok := ifd0[ifds.ExifTag]; !ok || next == 0 {
+		t.Fatalf("Incorrect IFD0 wanted ExifTag and IFD1 got %v %d", ifd0, next)
+	}
+	ifd1, _ := ifdValues(t, buf, next)
+	if ifd1[ifds.JPEGInterchangeFormat] != uint32(len(buf)-len(thumbnail)) || ifd1[ifds.JPEGInterchangeFormatLength] != uint32(len(thumbnail)) {
+		t.Errorf("Incorrect thumbnail wanted %d %d got %v", len(buf)-len(thumbnail), len(thumbnail), ifd1)
+	}
+}
+
+// ifdValues returns the embedded values of the IFD at offset and the offset of the next IFD.
+// Tag IDs must be sorted in ascending order.
+func ifdValues(t *testing.T, buf []byte, offset uint32) (map[tag.ID]uint32, uint32) {
+	t.Helper()
+	bo := utils.BinaryOrder(buf)
+	n := uint32(bo.Uint16(buf[offset:]))
+	values := make(map[tag.ID]uint32, n)
+	var last tag.ID
+	for i := uint32(0); i < n; i++ {
+		entry := buf[offset+2+i*12:]
+		id := tag.ID(bo.Uint16(entry))
+		if id <= last && i > 0 {
+			t.Errorf("Incorrect tag order %s after %s", id, last)
+		}
+		last = id
+		if tag.Type(bo.Uint16(entry[2:])) == tag.TypeShort {
+			values[id] = uint32(bo.Uint16(entry[8:]))
+			continue
+		}
+		values[id] = bo.Uint32(entry[8:])
+	}
+	return values, bo.Uint32(buf[offset+2+n*12:])
+}
+
+func TestRationalU(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want [2]uint32
+	}{
+		{2.8, [2]uint32{14, 5}},
+		{50, [2]uint32{50, 1}},
+		{0, [2]uint32{0, 1}},
+		{0.0001, [2]uint32{1, 10000}},
+	}
+	for _, test := range tests {
+		if r := rationalU(test.f); r != test.want {
+			t.Errorf("Incorrect rational of %f wanted %v got %v", test.f, test.want, r)
+		}
+	}
+	if r := exposureTimeRational(1.0 / 3); r != [2]uint32{1, 3} {
+		t.Errorf("Incorrect exposure time wanted 1/3 got %v", r)
+	}
+}
+
+func TestNewEncoderTiff(t *testing.T) {
+	var e Exif
+	e.Make = "Nikon"
+	e.CameraSerial = "12345"
+	e.GPS.SetLatitude(51.5)
+	e.GPS.SetLongitude(-0.12)
+	buf, err := Encode(e, utils.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail := []byte{0xff, 0xd8, 0xff, 0xd9}
+	enc, err := NewEncoderTiff(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.SetIfd1(NewShortEntry(ifds.Compression, 6))
+	enc.SetThumbnail(thumbnail)
+	if buf, err = enc.EncodeTiff(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode the encoded Tiff block again and remove the GPS and serial number
+	if enc, err = NewEncoderTiff(buf); err != nil {
+		t.Fatal(err)
+	}
+	enc.Filter(func(ifdType ifds.IfdType, id tag.ID) bool {
+		return ifdType != ifds.GPSIFD && id != exififd.BodySerialNumber
+	})
+	if buf, err = enc.EncodeTiff(); err != nil {
+		t.Fatal(err)
+	}
+	d := decodeTiff(t, buf)
+	if d.Make != e.Make || d.CameraSerial != "" || d.GPS.Latitude() != 0 {
+		t.Errorf("Incorrect Exif wanted %q %q %f got %q %q %f", e.Make, "", 0.0, d.Make, d.CameraSerial, d.GPS.Latitude())
+	}
+	if !bytes.HasSuffix(buf, thumbnail) {
+		t.Errorf("Incorrect thumbnail at end of Tiff got %x", buf[len(buf)-4:])
+	}
+
+	// Make, ExifVersion and Compression of IFD1
+	if enc.Len() != 3 {
+		t.Errorf("Incorrect number of entries wanted %d got %d", 3, enc.Len())
+	}
+	enc.Filter(func(ifdType ifds.IfdType, id tag.ID) bool {
+		return ifdType != ifds.NullIFD
+	})
+	if enc.Len() != 2 {
+		t.Errorf("Incorrect number of entries without IFD1 wanted %d got %d", 2, enc.Len())
+	}
+	enc.RemoveThumbnail()
+	if enc.Len() != 2 {
+		t.Errorf("Incorrect number of entries wanted %d got %d", 2, enc.Len())
+	}
+	if _, err = NewEncoderTiff([]byte("II*\x00\xff\x00\x00\x00")); err != ErrEncodeTiff {
+		t.Errorf("Incorrect error wanted %s got %v", ErrEncodeTiff, err)
+	}
+}
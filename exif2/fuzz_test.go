@@ -0,0 +1,24 @@
+package exif2
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, pattern := range []string{"../testImages/*.exif", "../testImages/*.GPR"} {
+		files, _ := filepath.Glob(pattern)
+		for _, file := range files {
+			buf, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Parse(bytes.NewReader(data))
+	})
+}
@@ -3,6 +3,7 @@ package ifds
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
 	"github.com/tdelov/imagemeta/exif2/ifds/gpsifd"
@@ -12,7 +13,6 @@ import (
 	"github.com/tdelov/imagemeta/exif2/ifds/mknote/sony"
 	"github.com/tdelov/imagemeta/exif2/tag"
 	"github.com/tdelov/imagemeta/meta/utils"
-	"github.com/rs/zerolog"
 )
 
 // IfdType is the Type of Information Directory
@@ -130,7 +130,7 @@ func (ifd Ifd) String() string {
 	return fmt.Sprintf("IFD [%s] (%d) at offset (0x%04x)", ifd.Type, ifd.Index, ifd.Offset)
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (ifd Ifd) MarshalZerologObject(e *zerolog.Event) {
-	e.Str("IfdType", ifd.Type.String()).Int8("idx", ifd.Index).Str("offset", fmt.Sprintf("0x%04x", ifd.Offset))
+// LogValue is a slog.LogValuer interface for logging
+func (ifd Ifd) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("IfdType", ifd.Type.String()), slog.Int("idx", int(ifd.Index)), slog.String("offset", fmt.Sprintf("0x%04x", ifd.Offset)))
 }
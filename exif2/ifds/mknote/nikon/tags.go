@@ -11,4 +11,22 @@ func TagNikonString(id tag.ID) string {
 }
 
 // TagNikonIDMap is a Map of tag.ID to string for the NikonMakerNote tags
-var TagNikonIDMap = map[tag.ID]string{}
+var TagNikonIDMap = map[tag.ID]string{
+	MakerNoteVersion: "MakerNoteVersion",
+	ISO:              "ISO",
+	Quality:          "Quality",
+	WhiteBalance:     "WhiteBalance",
+	FocusMode:        "FocusMode",
+	SerialNumber:     "SerialNumber",
+}
+
+// NikonMknoteIFD TagIDs
+// Source: https://exiftool.org/TagNames/Nikon.html
+const (
+	MakerNoteVersion tag.ID = 0x0001
+	ISO              tag.ID = 0x0002
+	Quality          tag.ID = 0x0004
+	WhiteBalance     tag.ID = 0x0005
+	FocusMode        tag.ID = 0x0007
+	SerialNumber     tag.ID = 0x001d
+)
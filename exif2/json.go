@@ -0,0 +1,302 @@
+package exif2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+)
+
+// JSONSchemaVersion is the version of the JSON schema of Exif. It is incremented
+// when the schema changes in a way that is incompatible with decoding older JSON.
+const JSONSchemaVersion = 1
+
+// Errors
+var (
+	ErrJSONSchemaVersion = errors.New("error unsupported Exif JSON schema version")
+)
+
+const (
+	// jsonDateLayout is the layout of dates without a time zone offset
+	jsonDateLayout = "2006-01-02T15:04:05.999"
+	// jsonDateOffsetLayout is the layout of dates with a time zone offset
+	jsonDateOffsetLayout = "2006-01-02T15:04:05.999-07:00"
+)
+
+// exifJSON is the JSON schema of Exif.
+type exifJSON struct {
+	SchemaVersion             int                  `json:"schemaVersion"`
+	ImageType                 imagetype.ImageType  `json:"imageType"`
+	Make                      string               `json:"make,omitempty"`
+	Model                     string               `json:"model,omitempty"`
+	CameraMake                ifds.CameraMake      `json:"cameraMake,omitempty"`
+	CameraModel               ifds.CameraModel     `json:"cameraModel,omitempty"`
+	CameraSerial              string               `json:"cameraSerial,omitempty"`
+	LensMake                  string               `json:"lensMake,omitempty"`
+	LensModel                 string               `json:"lensModel,omitempty"`
+	LensSerial                string               `json:"lensSerial,omitempty"`
+	LensInfo                  *LensInfo            `json:"lensInfo,omitempty"`
+	Software                  string               `json:"software,omitempty"`
+	ProcessingSoftware        string               `json:"processingSoftware,omitempty"`
+	DocumentName              string               `json:"documentName,omitempty"`
+	ImageDescription          string               `json:"imageDescription,omitempty"`
+	Artist                    string               `json:"artist,omitempty"`
+	Copyright                 string               `json:"copyright,omitempty"`
+	OwnerName                 string               `json:"ownerName,omitempty"`
+	ImageUniqueID             string               `json:"imageUniqueID,omitempty"`
+	ModifyDate                string               `json:"modifyDate,omitempty"`
+	DateTimeOriginal          string               `json:"dateTimeOriginal,omitempty"`
+	CreateDate                string               `json:"createDate,omitempty"`
+	GPS                       *gpsJSON             `json:"gps,omitempty"`
+	ImageWidth                uint16               `json:"imageWidth,omitempty"`
+	ImageHeight               uint16               `json:"imageHeight,omitempty"`
+	Orientation               meta.Orientation     `json:"orientation,omitempty"`
+	XResolution               uint32               `json:"xResolution,omitempty"`
+	YResolution               uint32               `json:"yResolution,omitempty"`
+	ResolutionUnit            uint16               `json:"resolutionUnit,omitempty"`
+	Compression               meta.Compression     `json:"compression,omitempty"`
+	PhotometricInterpretation uint16               `json:"photometricInterpretation,omitempty"`
+	ColorSpace                ColorSpace           `json:"colorSpace,omitempty"`
+	SubfileType               uint32               `json:"subfileType,omitempty"`
+	StripOffsets              uint32               `json:"stripOffsets,omitempty"`
+	StripByteCounts           uint32               `json:"stripByteCounts,omitempty"`
+	ThumbnailOffset           uint32               `json:"thumbnailOffset,omitempty"`
+	ThumbnailLength           uint32               `json:"thumbnailLength,omitempty"`
+	ExposureTime              meta.ExposureTime    `json:"exposureTime,omitempty"`
+	FNumber                   meta.Aperture        `json:"fNumber,omitempty"`
+	FocalLength               meta.FocalLength     `json:"focalLength,omitempty"`
+	FocalLengthIn35mmFormat   meta.FocalLength     `json:"focalLengthIn35mmFormat,omitempty"`
+	ISO                       uint16               `json:"iso,omitempty"`
+	ISOSpeed                  uint32               `json:"isoSpeed,omitempty"`
+	ExposureProgram           meta.ExposureProgram `json:"exposureProgram"`
+	ExposureMode              meta.ExposureMode    `json:"exposureMode"`
+	ExposureBias              meta.ExposureBias    `json:"exposureBias"`
+	MeteringMode              string               `json:"meteringMode"`
+	Flash                     meta.Flash           `json:"flash"`
+	SubjectDistance           float32              `json:"subjectDistance,omitempty"`
+	SubjectArea               SubjectArea          `json:"subjectArea,omitempty"`
+	SelfTimerMode             uint16               `json:"selfTimerMode,omitempty"`
+	ImageNumber               uint32               `json:"imageNumber,omitempty"`
+	Rating                    uint16               `json:"rating,omitempty"`
+	ApplicationNotes          []byte               `json:"applicationNotes,omitempty"`
+	Warnings                  []string             `json:"warnings,omitempty"`
+}
+
+// gpsJSON is the JSON schema of GPSInfo.
+type gpsJSON struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float32 `json:"altitude"`
+	Date      string  `json:"date,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The JSON schema is versioned
+// by the "schemaVersion" field (JSONSchemaVersion) and round-trips with UnmarshalJSON:
+//
+//   - Dates are composites of the date, the subsec and the offset time tags, formatted
+//     as "2006-01-02T15:04:05.999-07:00" or "2006-01-02T15:04:05.999" without an offset time tag.
+//   - GPS latitude, longitude and altitude are signed, South, West and below sea level
+//     are negative. The GPS date is in UTC formatted as RFC 3339.
+//   - ImageType, ExposureTime, FNumber, FocalLength, ExposureBias, ExposureProgram, ExposureMode
+//     and MeteringMode are strings of their MarshalText methods.
+//   - Orientation, Flash, Compression and ColorSpace are the numeric Exif values.
+//   - CameraMake and CameraModel are the lookup values of this package.
+//   - Warnings are the error strings. Makernotes are not included.
+func (e Exif) MarshalJSON() ([]byte, error) {
+	mm, _ := e.MeteringMode.MarshalText()
+	ej := exifJSON{
+		SchemaVersion:             JSONSchemaVersion,
+		ImageType:                 e.ImageType,
+		Make:                      e.Make,
+		Model:                     e.Model,
+		CameraMake:                e.CameraMake,
+		CameraModel:               e.CameraModel,
+		CameraSerial:              e.CameraSerial,
+		LensMake:                  e.LensMake,
+		LensModel:                 e.LensModel,
+		LensSerial:                e.LensSerial,
+		Software:                  e.Software,
+		ProcessingSoftware:        e.ProcessingSoftware,
+		DocumentName:              e.DocumentName,
+		ImageDescription:          e.ImageDescription,
+		Artist:                    e.Artist,
+		Copyright:                 e.Copyright,
+		OwnerName:                 e.OwnerName,
+		ImageUniqueID:             e.ImageUniqueID,
+		ModifyDate:                jsonDate(e.ModifyDate(), e.Time.offsetTime),
+		DateTimeOriginal:          jsonDate(e.DateTimeOriginal(), e.Time.offsetTimeOriginal),
+		CreateDate:                jsonDate(e.CreateDate(), e.Time.offsetTimeDigitized),
+		ImageWidth:                e.ImageWidth,
+		ImageHeight:               e.ImageHeight,
+		Orientation:               e.Orientation,
+		XResolution:               e.XResolution,
+		YResolution:               e.YResolution,
+		ResolutionUnit:            e.ResolutionUnit,
+		Compression:               e.Compression,
+		PhotometricInterpretation: e.PhotometricInterpretation,
+		ColorSpace:                e.ColorSpace,
+		SubfileType:               e.SubfileType,
+		StripOffsets:              e.StripOffsets,
+		StripByteCounts:           e.StripByteCounts,
+		ThumbnailOffset:           e.ThumbnailOffset,
+		ThumbnailLength:           e.ThumbnailLength,
+		ExposureTime:              e.ExposureTime,
+		FNumber:                   e.FNumber,
+		FocalLength:               e.FocalLength,
+		FocalLengthIn35mmFormat:   e.FocalLengthIn35mmFormat,
+		ISO:                       e.ISO,
+		ISOSpeed:                  e.ISOSpeed,
+		ExposureProgram:           e.ExposureProgram,
+		ExposureMode:              e.ExposureMode,
+		ExposureBias:              e.ExposureBias,
+		MeteringMode:              string(mm),
+		Flash:                     e.Flash,
+		SubjectDistance:           e.SubjectDistance,
+		SubjectArea:               e.SubjectArea,
+		SelfTimerMode:             e.SelfTimerMode,
+		ImageNumber:               e.ImageNumber,
+		Rating:                    e.Rating,
+		ApplicationNotes:          e.ApplicationNotes,
+	}
+	if e.LensInfo != (LensInfo{}) {
+		ej.LensInfo = &e.LensInfo
+	}
+	if e.GPS != (GPSInfo{}) {
+		ej.GPS = &gpsJSON{Latitude: e.GPS.Latitude(), Longitude: e.GPS.Longitude(), Altitude: e.GPS.Altitude()}
+		if d := e.GPS.Date(); !d.IsZero() {
+			ej.GPS.Date = d.UTC().Format(time.RFC3339)
+		}
+	}
+	for _, w := range e.Warnings {
+		ej.Warnings = append(ej.Warnings, w.Error())
+	}
+	return json.Marshal(ej)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for the JSON schema of MarshalJSON.
+// Returns ErrJSONSchemaVersion when the schema version is newer than JSONSchemaVersion.
+func (e *Exif) UnmarshalJSON(buf []byte) (err error) {
+	var ej exifJSON
+	if err = json.Unmarshal(buf, &ej); err != nil {
+		return err
+	}
+	if ej.SchemaVersion < 1 || ej.SchemaVersion > JSONSchemaVersion {
+		return fmt.Errorf("%w: %d", ErrJSONSchemaVersion, ej.SchemaVersion)
+	}
+	var mm meta.MeteringMode
+	if err = mm.UnmarshalText([]byte(ej.MeteringMode)); err != nil {
+		return err
+	}
+	exif := Exif{
+		ImageType:                 ej.ImageType,
+		Make:                      ej.Make,
+		Model:                     ej.Model,
+		CameraMake:                ej.CameraMake,
+		CameraModel:               ej.CameraModel,
+		CameraSerial:              ej.CameraSerial,
+		LensMake:                  ej.LensMake,
+		LensModel:                 ej.LensModel,
+		LensSerial:                ej.LensSerial,
+		Software:                  ej.Software,
+		ProcessingSoftware:        ej.ProcessingSoftware,
+		DocumentName:              ej.DocumentName,
+		ImageDescription:          ej.ImageDescription,
+		Artist:                    ej.Artist,
+		Copyright:                 ej.Copyright,
+		OwnerName:                 ej.OwnerName,
+		ImageUniqueID:             ej.ImageUniqueID,
+		ImageWidth:                ej.ImageWidth,
+		ImageHeight:               ej.ImageHeight,
+		Orientation:               ej.Orientation,
+		XResolution:               ej.XResolution,
+		YResolution:               ej.YResolution,
+		ResolutionUnit:            ej.ResolutionUnit,
+		Compression:               ej.Compression,
+		PhotometricInterpretation: ej.PhotometricInterpretation,
+		ColorSpace:                ej.ColorSpace,
+		SubfileType:               ej.SubfileType,
+		StripOffsets:              ej.StripOffsets,
+		StripByteCounts:           ej.StripByteCounts,
+		ThumbnailOffset:           ej.ThumbnailOffset,
+		ThumbnailLength:           ej.ThumbnailLength,
+		ExposureTime:              ej.ExposureTime,
+		FNumber:                   ej.FNumber,
+		FocalLength:               ej.FocalLength,
+		FocalLengthIn35mmFormat:   ej.FocalLengthIn35mmFormat,
+		ISO:                       ej.ISO,
+		ISOSpeed:                  ej.ISOSpeed,
+		ExposureProgram:           ej.ExposureProgram,
+		ExposureMode:              ej.ExposureMode,
+		ExposureBias:              ej.ExposureBias,
+		MeteringMode:              mm,
+		Flash:                     ej.Flash,
+		SubjectDistance:           ej.SubjectDistance,
+		SubjectArea:               ej.SubjectArea,
+		SelfTimerMode:             ej.SelfTimerMode,
+		ImageNumber:               ej.ImageNumber,
+		Rating:                    ej.Rating,
+		ApplicationNotes:          ej.ApplicationNotes,
+	}
+	if ej.LensInfo != nil {
+		exif.LensInfo = *ej.LensInfo
+	}
+	dates := []struct {
+		str string
+		set func(time.Time)
+	}{
+		{ej.ModifyDate, exif.SetModifyDate},
+		{ej.DateTimeOriginal, exif.SetDateTimeOriginal},
+		{ej.CreateDate, exif.SetCreateDate},
+	}
+	for _, d := range dates {
+		t, err := parseJSONDate(d.str)
+		if err != nil {
+			return err
+		}
+		d.set(t)
+	}
+	if ej.GPS != nil {
+		exif.GPS.SetLatitude(ej.GPS.Latitude)
+		exif.GPS.SetLongitude(ej.GPS.Longitude)
+		exif.GPS.SetAltitude(ej.GPS.Altitude)
+		if ej.GPS.Date != "" {
+			t, err := time.Parse(time.RFC3339, ej.GPS.Date)
+			if err != nil {
+				return err
+			}
+			exif.GPS.SetDate(t)
+		}
+	}
+	for _, w := range ej.Warnings {
+		exif.Warnings = append(exif.Warnings, errors.New(w))
+	}
+	*e = exif
+	return nil
+}
+
+// jsonDate returns the composite date t formatted with the time zone
+// offset when the offset time tag is present. Returns "" for a zero date.
+func jsonDate(t time.Time, offsetTime *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	if offsetTime == nil {
+		return t.Format(jsonDateLayout)
+	}
+	return t.Format(jsonDateOffsetLayout)
+}
+
+// parseJSONDate parses a date of jsonDate. Dates without a time zone offset are in UTC.
+func parseJSONDate(str string) (time.Time, error) {
+	if str == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(jsonDateOffsetLayout, str); err == nil {
+		return t, nil
+	}
+	return time.Parse(jsonDateLayout, str)
+}
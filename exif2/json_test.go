@@ -0,0 +1,95 @@
+package exif2
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+)
+
+func TestExifJSON(t *testing.T) {
+	loc := time.FixedZone("", -5*60*60)
+	original := time.Date(2023, 6, 15, 10, 30, 45, 250*int(time.Millisecond), loc)
+	modify := time.Date(2023, 6, 16, 8, 0, 0, 0, time.UTC)
+
+	var e Exif
+	e.ImageType = imagetype.ImageCR2
+	e.Make = "Canon"
+	e.Model = "Canon EOS 6D"
+	e.Orientation = meta.OrientationRotate90
+	e.ExposureTime = 1.0 / 250
+	e.FNumber = 2.8
+	e.FocalLength = 50
+	e.ISOSpeed = 400
+	e.ExposureBias = meta.NewExposureBias(-2, 3)
+	e.ExposureProgram = meta.ExposureProgramAperturePriority
+	e.MeteringMode = meta.MeteringModeSpot
+	e.Flash = meta.FlashFired
+	e.LensInfo = LensInfo{24, 1, 105, 1, 4, 1, 4, 1}
+	e.SetDateTimeOriginal(original)
+	e.SetModifyDate(modify)
+	e.GPS.SetLatitude(-33.8688)
+	e.GPS.SetLongitude(151.2093)
+	e.GPS.SetAltitude(-12)
+	e.GPS.SetDate(original)
+	e.Warnings = []error{errors.New("error reading tag")}
+
+	buf, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`"schemaVersion":1`,
+		`"imageType":"image/x-canon-cr2"`,
+		`"dateTimeOriginal":"2023-06-15T10:30:45.25-05:00"`,
+		`"modifyDate":"2023-06-16T08:00:00"`,
+		`"latitude":-33.8688`,
+		`"altitude":-12`,
+		`"date":"2023-06-15T15:30:45Z"`,
+		`"exposureTime":"1/250"`,
+		`"exposureProgram":"Aperture-priority AE"`,
+		`"meteringMode":"Spot"`,
+		`"exposureBias":"-2/3"`,
+	} {
+		if !strings.Contains(string(buf), s) {
+			t.Errorf("Incorrect JSON wanted %s in %s", s, buf)
+		}
+	}
+
+	var d Exif
+	if err = json.Unmarshal(buf, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.DateTimeOriginal().Equal(original) || d.DateTimeOriginal().Format(time.RFC3339) != original.Format(time.RFC3339) {
+		t.Errorf("Incorrect DateTimeOriginal wanted %s got %s", original, d.DateTimeOriginal())
+	}
+	if !d.ModifyDate().Equal(modify) || d.Time.offsetTime != nil {
+		t.Errorf("Incorrect ModifyDate wanted %s got %s", modify, d.ModifyDate())
+	}
+	if d.GPS.Latitude() != e.GPS.Latitude() || d.GPS.Longitude() != e.GPS.Longitude() || d.GPS.Altitude() != e.GPS.Altitude() || !d.GPS.Date().Equal(e.GPS.Date()) {
+		t.Errorf("Incorrect GPS wanted %f %f %f %s got %f %f %f %s", e.GPS.Latitude(), e.GPS.Longitude(), e.GPS.Altitude(), e.GPS.Date(), d.GPS.Latitude(), d.GPS.Longitude(), d.GPS.Altitude(), d.GPS.Date())
+	}
+	if d.ImageType != e.ImageType || d.MeteringMode != e.MeteringMode || d.ExposureProgram != e.ExposureProgram || d.ExposureBias != e.ExposureBias || d.Flash != e.Flash || d.LensInfo != e.LensInfo {
+		t.Errorf("Incorrect values wanted %s %s %s %s %s %v got %s %s %s %s %s %v", e.ImageType, e.MeteringMode, e.ExposureProgram, e.ExposureBias, e.Flash, e.LensInfo, d.ImageType, d.MeteringMode, d.ExposureProgram, d.ExposureBias, d.Flash, d.LensInfo)
+	}
+	if len(d.Warnings) != 1 || d.Warnings[0].Error() != "error reading tag" {
+		t.Errorf("Incorrect Warnings wanted %v got %v", e.Warnings, d.Warnings)
+	}
+
+	// The JSON of the decoded Exif is identical
+	buf2, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != string(buf2) {
+		t.Errorf("Incorrect round-trip JSON wanted %s got %s", buf, buf2)
+	}
+
+	if err = json.Unmarshal([]byte(`{"schemaVersion":2}`), &d); !errors.Is(err, ErrJSONSchemaVersion) {
+		t.Errorf("Incorrect error wanted %v got %v", ErrJSONSchemaVersion, err)
+	}
+}
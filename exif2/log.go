@@ -1,61 +1,60 @@
 package exif2
 
 import (
-	"os"
+	"log/slog"
 	"runtime"
 
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/tdelov/imagemeta/internal/logging"
 )
 
-// Logger default is Panic Level to Console Writer
-var Logger zerolog.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.PanicLevel)
+// Logger default discards all log records
+var Logger *slog.Logger = logging.Discard
 
 func (ir *ifdReader) logLevelTrace() bool {
-	return ir.logger.GetLevel() == zerolog.TraceLevel
+	return logging.Enabled(ir.logger, logging.LevelTrace)
 }
 
 func (ir *ifdReader) logLevelInfo() bool {
-	return ir.logger.GetLevel() <= zerolog.InfoLevel
+	return logging.Enabled(ir.logger, slog.LevelInfo)
 }
 
 func (ir *ifdReader) logLevelDebug() bool {
-	return ir.logger.GetLevel() <= zerolog.DebugLevel
+	return logging.Enabled(ir.logger, slog.LevelDebug)
 }
 
 func (ir *ifdReader) logLevelWarn() bool {
-	return ir.logger.GetLevel() <= zerolog.WarnLevel
+	return logging.Enabled(ir.logger, slog.LevelWarn)
 }
 
 func (ir *ifdReader) logLevelError() bool {
-	return ir.logger.GetLevel() <= zerolog.ErrorLevel
+	return logging.Enabled(ir.logger, slog.LevelError)
 }
 
-func (ir *ifdReader) logInfo() *zerolog.Event {
-	e := ir.logger.Info()
+func (ir *ifdReader) logInfo() *logging.Event {
+	e := logging.NewEvent(ir.logger, slog.LevelInfo)
 	ir.logTraceFunction(e)
 	return e
 }
 
-func (ir *ifdReader) logDebug() *zerolog.Event {
-	e := ir.logger.Debug()
+func (ir *ifdReader) logDebug() *logging.Event {
+	e := logging.NewEvent(ir.logger, slog.LevelDebug)
 	ir.logTraceFunction(e)
 	return e
 }
 
-func (ir *ifdReader) logWarn() *zerolog.Event {
-	e := ir.logger.Warn()
+func (ir *ifdReader) logWarn() *logging.Event {
+	e := logging.NewEvent(ir.logger, slog.LevelWarn)
 	ir.logTraceFunction(e)
 	return e
 }
 
-func (ir *ifdReader) logError(err error) *zerolog.Event {
-	e := ir.logger.Err(err)
+func (ir *ifdReader) logError(err error) *logging.Event {
+	e := logging.NewEvent(ir.logger, slog.LevelError).Err(err)
 	ir.logTraceFunction(e)
 	return e
 }
 
-func (ir *ifdReader) logTraceFunction(ev *zerolog.Event) {
+func (ir *ifdReader) logTraceFunction(ev *logging.Event) {
 	if ir.logLevelTrace() {
 		pc, _, _, ok := runtime.Caller(2)
 		details := runtime.FuncForPC(pc)
@@ -2,6 +2,7 @@ package exif2
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -65,6 +66,10 @@ type Exif struct {
 	ColorSpace                ColorSpace           // ExifIFD / 0xa001
 	ImageType                 imagetype.ImageType
 
+	// Warnings are the non-fatal errors that occurred during decoding.
+	// Errors are a *meta.DecodeError with the location of the error.
+	Warnings []error
+
 	// 0xa20e	FocalPlaneXResolution	rational64u	ExifIFD
 	// 0xa20f	FocalPlaneYResolution	rational64u	ExifIFD
 }
@@ -111,6 +116,36 @@ func (e Exif) CreateDate() time.Time {
 	return t
 }
 
+// SetModifyDate sets the ModifyDate with the subsec and time zone offset of t.
+func (e *Exif) SetModifyDate(t time.Time) {
+	e.Time.modifyDate, e.Time.subSecTime, e.Time.offsetTime = exifTime(t)
+}
+
+// SetDateTimeOriginal sets the DateTimeOriginal with the subsec and time zone offset of t.
+func (e *Exif) SetDateTimeOriginal(t time.Time) {
+	e.Time.dateTimeOriginal, e.Time.subSecTimeOriginal, e.Time.offsetTimeOriginal = exifTime(t)
+}
+
+// SetCreateDate sets the CreateDate with the subsec and time zone offset of t.
+func (e *Exif) SetCreateDate(t time.Time) {
+	e.Time.createDate, e.Time.subSecTimeDigitized, e.Time.offsetTimeDigitized = exifTime(t)
+}
+
+// exifTime returns the date and time of t in UTC, the milliseconds and
+// the time zone of t. The time zone is nil for UTC.
+func exifTime(t time.Time) (time.Time, uint16, *time.Location) {
+	if t.IsZero() {
+		return time.Time{}, 0, nil
+	}
+	d := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	ms := uint16(t.Nanosecond() / int(time.Millisecond))
+	if t.Location() == time.UTC {
+		return d, ms, nil
+	}
+	_, offset := t.Zone()
+	return d, ms, getLocation(int32(offset), []byte(offsetTimeString(offset)))
+}
+
 // Sring implements the Stringer interface for Exif
 func (e Exif) String() string {
 	sb := strings.Builder{}
@@ -200,6 +235,32 @@ func (g GPSInfo) Altitude() float32 {
 	return g.altitude
 }
 
+// SetLatitude sets the GPS Latitude and GPS Latitude Reference. South is negative.
+func (g *GPSInfo) SetLatitude(lat float64) {
+	g.latitude, g.latitudeRef = math.Abs(lat), lat < 0
+}
+
+// SetLongitude sets the GPS Longitude and GPS Longitude Reference. West is negative.
+func (g *GPSInfo) SetLongitude(lng float64) {
+	g.longitude, g.longitudeRef = math.Abs(lng), lng < 0
+}
+
+// SetAltitude sets the GPS Altitude and GPS Altitude Reference. Below sea level is negative.
+func (g *GPSInfo) SetAltitude(alt float32) {
+	g.altitude, g.altitudeRef = float32(math.Abs(float64(alt))), alt < 0
+}
+
+// SetDate sets the GPSDateStamp and GPSTimeStamp tags in UTC.
+func (g *GPSInfo) SetDate(t time.Time) {
+	if t.IsZero() {
+		g.date, g.time = time.Time{}, 0
+		return
+	}
+	t = t.UTC()
+	g.date = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	g.time = uint32(t.Hour()*hoursToSeconds + t.Minute()*minutesToSeconds + t.Second())
+}
+
 // TimeTags contains time Exif tags
 type TimeTags struct {
 	modifyDate          time.Time      // IFD0 / 0x0132
@@ -217,6 +278,27 @@ type TimeTags struct {
 // ApplicationNotes data are stil work in process
 type ApplicationNotes []byte
 
-// MakerNotes are still work in process
+// MakerNotes are the decoded tags of the makernote of the camera, a *CanonMakerNotes
+// or a *NikonMakerNotes. The makernotes of other camera makes are not decoded.
 type MakerNotes interface {
 }
+
+// CanonMakerNotes are the decoded tags of a Canon makernote
+type CanonMakerNotes struct {
+	ImageType            string // MknoteIFD / 0x0006
+	FirmwareVersion      string // MknoteIFD / 0x0007
+	OwnerName            string // MknoteIFD / 0x0009
+	LensModel            string // MknoteIFD / 0x0095
+	InternalSerialNumber string // MknoteIFD / 0x0096
+	SerialNumber         uint32 // MknoteIFD / 0x000c
+}
+
+// NikonMakerNotes are the decoded tags of a Nikon makernote
+type NikonMakerNotes struct {
+	MakerNoteVersion string    // MknoteIFD / 0x0001 (4 digits: "0211")
+	Quality          string    // MknoteIFD / 0x0004
+	WhiteBalance     string    // MknoteIFD / 0x0005
+	FocusMode        string    // MknoteIFD / 0x0007
+	SerialNumber     string    // MknoteIFD / 0x001d
+	ISO              [2]uint16 // MknoteIFD / 0x0002
+}
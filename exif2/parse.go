@@ -2,6 +2,7 @@ package exif2
 
 import (
 	"math"
+	"strings"
 	"time"
 
 	"github.com/tdelov/imagemeta/exif2/ifds"
@@ -9,23 +10,45 @@ import (
 	"github.com/tdelov/imagemeta/exif2/ifds/gpsifd"
 	"github.com/tdelov/imagemeta/exif2/ifds/mknote/apple"
 	"github.com/tdelov/imagemeta/exif2/ifds/mknote/canon"
+	"github.com/tdelov/imagemeta/exif2/ifds/mknote/nikon"
 	"github.com/tdelov/imagemeta/exif2/tag"
 	"github.com/tdelov/imagemeta/imagetype"
 	"github.com/tdelov/imagemeta/meta"
 )
 
+// parseIfd1Tag parses the tags of IFD1, the IFD of the thumbnail image.
+func (ir *ifdReader) parseIfd1Tag(t Tag) {
+	if t.IfdIndex != 1 {
+		return
+	}
+	switch t.ID {
+	case ifds.JPEGInterchangeFormat:
+		ir.Exif.ThumbnailOffset = ir.ParseUint32(t)
+	case ifds.JPEGInterchangeFormatLength:
+		ir.Exif.ThumbnailLength = ir.ParseUint32(t)
+	}
+}
+
 func (ir *ifdReader) parseTag(t Tag) {
 	if ir.customTagParser != nil {
 		if err := ir.customTagParser(ir, t); err != nil {
-			ir.logError(err).Send()
+			ir.warn(err)
 		}
 		return
 	}
 	switch ifds.IfdType(t.Ifd) {
 	case ifds.IFD0:
+		if t.IfdIndex > 0 {
+			// IFD1 tags of the thumbnail image
+			ir.parseIfd1Tag(t)
+			return
+		}
 		switch t.ID {
 		case ifds.Make:
 			ir.Exif.CameraMake, ir.Exif.Make = ir.ParseCameraMake(t)
+			if ir.Exif.ImageType == imagetype.ImageTiff {
+				ir.Exif.ImageType = imageTypeFromMake(ir.Exif.Make)
+			}
 		case ifds.Model:
 			ir.Exif.CameraModel, ir.Exif.Model = ir.ParseCameraModel(t)
 		case ifds.Artist:
@@ -50,7 +73,8 @@ func (ir *ifdReader) parseTag(t Tag) {
 			ir.Exif.Time.modifyDate = ir.ParseDate(t)
 		case ifds.DNGVersion:
 			// TODO: If DNG version > 0 imagetype is DNG
-			if ir.Exif.ImageType == imagetype.ImageTiff {
+			switch ir.Exif.ImageType {
+			case imagetype.ImageTiff, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR:
 				ir.Exif.ImageType = imagetype.ImageDNG
 			}
 
@@ -58,9 +82,18 @@ func (ir *ifdReader) parseTag(t Tag) {
 			if ir.Exif.CameraSerial == "" {
 				ir.Exif.CameraSerial = ir.ParseString(t)
 			}
-		//case ifds.ApplicationNotes:
-		//fmt.Println(ir.parseApplicationNotes(t))
-		//ir.Exif.ApplicationNotes =
+		case ifds.ApplicationNotes:
+			if ir.xmpReader != nil && !t.IsEmbedded() {
+				if err := ir.readTagReader(t, ir.xmpReader); err != nil {
+					ir.warn(err)
+				}
+			}
+		case ifds.InterColorProfile:
+			if ir.iccReader != nil && !t.IsEmbedded() {
+				if err := ir.readTagReader(t, ir.iccReader); err != nil {
+					ir.warn(err)
+				}
+			}
 		default:
 			//t.logTag(ir.logWarn()).Send()
 		}
@@ -81,12 +114,14 @@ func (ir *ifdReader) parseTag(t Tag) {
 				ir.Exif.CameraSerial = ir.ParseString(t)
 			}
 		case exififd.PixelXDimension:
+			ir.pixelDim.Width = ir.ParseUint32(t)
 			if ir.Exif.ImageWidth == 0 {
-				ir.Exif.ImageWidth = uint16(ir.ParseUint32(t))
+				ir.Exif.ImageWidth = uint16(ir.pixelDim.Width)
 			}
 		case exififd.PixelYDimension:
+			ir.pixelDim.Height = ir.ParseUint32(t)
 			if ir.Exif.ImageHeight == 0 {
-				ir.Exif.ImageHeight = uint16(ir.ParseUint32(t))
+				ir.Exif.ImageHeight = uint16(ir.pixelDim.Height)
 			}
 		case exififd.ExposureTime:
 			ir.Exif.ExposureTime = ir.parseExposureTime(t)
@@ -157,6 +192,62 @@ func (ir *ifdReader) parseTag(t Tag) {
 		default:
 			//t.logTag(ir.logWarn()).Send()
 		}
+	case ifds.MknoteIFD:
+		switch ir.Exif.CameraMake {
+		case ifds.Canon:
+			ir.parseCanonTag(t)
+		case ifds.Nikon:
+			ir.parseNikonTag(t)
+		}
+	}
+}
+
+// parseCanonTag parses the tags of a Canon makernote.
+func (ir *ifdReader) parseCanonTag(t Tag) {
+	mn, ok := ir.Exif.Makernotes.(*CanonMakerNotes)
+	if !ok {
+		mn = &CanonMakerNotes{}
+		ir.Exif.Makernotes = mn
+	}
+	switch t.ID {
+	case canon.CanonImageType:
+		mn.ImageType = ir.ParseString(t)
+	case canon.CanonFirmwareVersion:
+		mn.FirmwareVersion = ir.ParseString(t)
+	case canon.OwnerName:
+		mn.OwnerName = ir.ParseString(t)
+	case canon.SerialNumber:
+		mn.SerialNumber = ir.ParseUint32(t)
+	case canon.LensModel:
+		mn.LensModel = ir.ParseString(t)
+	case canon.CanonInternalSerialNumber:
+		mn.InternalSerialNumber = strings.TrimRight(ir.ParseString(t), "\xff")
+	}
+}
+
+// parseNikonTag parses the tags of a Nikon makernote.
+func (ir *ifdReader) parseNikonTag(t Tag) {
+	mn, ok := ir.Exif.Makernotes.(*NikonMakerNotes)
+	if !ok {
+		mn = &NikonMakerNotes{}
+		ir.Exif.Makernotes = mn
+	}
+	switch t.ID {
+	case nikon.MakerNoteVersion:
+		mn.MakerNoteVersion = ir.ParseString(t)
+	case nikon.ISO:
+		if t.IsEmbedded() && t.IsType(tag.TypeShort) && t.UnitCount == 2 {
+			t.EmbeddedValue(ir.buffer.buf[:4])
+			mn.ISO = [2]uint16{t.ByteOrder.Uint16(ir.buffer.buf[:2]), t.ByteOrder.Uint16(ir.buffer.buf[2:4])}
+		}
+	case nikon.Quality:
+		mn.Quality = ir.ParseString(t)
+	case nikon.WhiteBalance:
+		mn.WhiteBalance = ir.ParseString(t)
+	case nikon.FocusMode:
+		mn.FocusMode = ir.ParseString(t)
+	case nikon.SerialNumber:
+		mn.SerialNumber = ir.ParseString(t)
 	}
 }
 
@@ -168,6 +259,21 @@ func (ir *ifdReader) ParseCameraMake(t Tag) (ifds.CameraMake, string) {
 	return ifds.CameraMakeUnknown, string(str)
 }
 
+// imageTypeFromMake returns the Raw image type of the camera make of an image
+// with a Tiff header. PEF, SRW and 3FR images do not have a signature of their own.
+func imageTypeFromMake(make string) imagetype.ImageType {
+	make = strings.ToUpper(make)
+	switch {
+	case strings.HasPrefix(make, "PENTAX"), strings.HasPrefix(make, "RICOH"):
+		return imagetype.ImagePEF
+	case strings.HasPrefix(make, "SAMSUNG"):
+		return imagetype.ImageSRW
+	case strings.HasPrefix(make, "HASSELBLAD"):
+		return imagetype.Image3FR
+	}
+	return imagetype.ImageTiff
+}
+
 func (ir *ifdReader) ParseCameraModel(t Tag) (ifds.CameraModel, string) {
 	str := ir.ParseBuffer(t)
 	switch ir.Exif.CameraMake {
@@ -269,7 +375,7 @@ func (ir *ifdReader) ParseSubSecTime(t Tag) uint16 {
 
 func (ir *ifdReader) parseLensInfo(t Tag) LensInfo {
 	if !t.IsEmbedded() {
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(32)
 		if err != nil {
 			return LensInfo{}
 		}
@@ -287,7 +393,7 @@ func (ir *ifdReader) parseLensInfo(t Tag) LensInfo {
 func (ir *ifdReader) ParseRationalU(t Tag) [2]uint32 {
 	switch t.Type {
 	case tag.TypeSignedRational, tag.TypeRational:
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(8)
 		if err != nil {
 			return [2]uint32{}
 		}
@@ -350,7 +456,8 @@ func (ir *ifdReader) ParseString(t Tag) string {
 
 // ParseBuffer parses an ASCII value.
 // Non-embedded or embedded tag with variable byte length.
-// This function does not allocate.
+// This function does not allocate. The []byte is only valid until the next tag
+// unless the Exif is decoded with DecodeBytes, then it is a slice of its buffer.
 func (ir *ifdReader) ParseBuffer(t Tag) []byte {
 	if t.IsEmbedded() {
 		t.EmbeddedValue(ir.buffer.buf[:4])
@@ -374,7 +481,7 @@ func (ir *ifdReader) ParseBuffer(t Tag) []byte {
 // Non-embedded tag with 20 byte length.
 func (ir *ifdReader) ParseDate(t Tag) time.Time {
 	if t.IsType(tag.TypeASCII) {
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(19)
 		if err != nil {
 			return time.Time{}
 		}
@@ -400,7 +507,7 @@ func (ir *ifdReader) ParseDate(t Tag) time.Time {
 // Non-embedded tag with 6 byte length.
 func (ir *ifdReader) ParseOffsetTime(t Tag) *time.Location {
 	if t.IsType(tag.TypeASCII) {
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(6)
 		if err != nil {
 			return time.UTC
 		}
@@ -434,7 +541,7 @@ func (ir *ifdReader) ParseGPSCoord(t Tag) float64 {
 	if t.UnitCount == 3 {
 		switch t.Type {
 		case tag.TypeRational, tag.TypeSignedRational: // Some cameras write tag out of spec using signed rational. We accept that too.
-			buf, err := ir.readTagValue()
+			buf, err := ir.readTagValueLength(24)
 			if err != nil {
 				return 0.0
 			}
@@ -455,7 +562,7 @@ func (ir *ifdReader) ParseGPSAltitude(t Tag) float32 {
 	if t.UnitCount == 1 {
 		switch t.Type {
 		case tag.TypeRational, tag.TypeSignedRational: // Some cameras write tag out of spec using signed rational. We accept that too.
-			buf, err := ir.readTagValue()
+			buf, err := ir.readTagValueLength(8)
 			if err != nil {
 				return 0.0
 			}
@@ -471,7 +578,7 @@ func (ir *ifdReader) ParseGPSAltitude(t Tag) float32 {
 // parseGPSTimeStamp parses the GPSTimeStamp tag in UTC.
 func (ir *ifdReader) parseGPSTimeStamp(t Tag) uint32 {
 	if t.UnitCount == 3 && t.Type == tag.TypeRational {
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(24)
 		if err != nil {
 			return 0
 		}
@@ -503,7 +610,7 @@ func (ir *ifdReader) parseGPSTimeStamp(t Tag) uint32 {
 // parseGPSDateStamp parses a GPSDateStamp from the tag
 func (ir *ifdReader) parseGPSDateStamp(t Tag) time.Time {
 	if t.IsType(tag.TypeASCII) {
-		buf, err := ir.readTagValue()
+		buf, err := ir.readTagValueLength(10)
 		if err != nil {
 			return time.Time{}
 		}
@@ -512,8 +619,8 @@ func (ir *ifdReader) parseGPSDateStamp(t Tag) time.Time {
 			return time.Date(int(parseStrUint(buf[0:4])), time.Month(parseStrUint(buf[5:7])), int(parseStrUint(buf[8:10])), 0, 0, 0, 0, time.UTC)
 		}
 		// check recieved value
-		if buf[4] == ':' && buf[7] == ':' && buf[10] == ' ' &&
-			buf[13] == ':' && buf[16] == ':' && len(buf) > 19 {
+		if len(buf) > 19 && buf[4] == ':' && buf[7] == ':' && buf[10] == ' ' &&
+			buf[13] == ':' && buf[16] == ':' {
 			return time.Date(
 				int(parseStrUint(buf[0:4])),
 				time.Month(parseStrUint(buf[5:7])),
@@ -558,6 +665,7 @@ type TagParser interface {
 	ParseGPSCoord(t Tag) float64
 	ParseRationalU(t Tag) [2]uint32
 	ParseString(t Tag) string
+	ParseBuffer(t Tag) []byte
 	ParseSubSecTime(t Tag) uint16
 	ParseUint32(t Tag) uint32
 	ParseUint16(t Tag) uint16
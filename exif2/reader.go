@@ -1,7 +1,11 @@
 package exif2
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2/ifds"
 	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
@@ -11,7 +15,20 @@ import (
 	"github.com/tdelov/imagemeta/meta"
 	"github.com/tdelov/imagemeta/meta/utils"
 	"github.com/tdelov/imagemeta/tiff"
-	"github.com/rs/zerolog"
+)
+
+// tiffMaxLength is the default maximum length of the Exif of DecodeTiff.
+const tiffMaxLength = 4 * 1024 * 1024
+
+// Decoder errors
+var (
+	// ErrTagCount is an error for an IFD with more than 128 tags.
+	ErrTagCount = errors.New("error Ifd tag count exceeds maximum")
+	// ErrTagMaxCount is an error for a tag that exceeds the tags of the tag buffer, its value is not read.
+	ErrTagMaxCount = errors.New("error tag buffer is full")
+	// ErrReverseTagOffset is an error for a tag value before the offset of the streaming
+	// decoder, its value is not read. The value is read when decoding from an io.ReaderAt.
+	ErrReverseTagOffset = errors.New("error tag value is before the reader offset")
 )
 
 func Parse(r io.ReadSeeker) (Exif, error) {
@@ -35,15 +52,20 @@ func Parse(r io.ReadSeeker) (Exif, error) {
 func (ir *ifdReader) DecodeTiff(r io.Reader, h meta.ExifHeader) error {
 	// Log Header Info
 	if ir.logLevelInfo() {
-		ir.logger.Info().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
+		ir.logInfo().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
 	}
 	ir.ResetReader(r)
 
 	ir.Exif.ImageType = h.ImageType
+	ir.headerOffset = h.TiffHeaderOffset
 	ir.firstIfdOffset = h.FirstIfdOffset
-	ir.exifLength = 4 * 1024 * 1024 // Max size is 4 MB
+	ir.exifLength = tiffMaxLength
+	if ir.maxLength != 0 {
+		// The maximum length replaces the default
+		ir.exifLength = ir.maxLength
+	}
 	if err := ir.discard(int(h.FirstIfdOffset)); err != nil {
-		return err
+		return ir.decodeError(err, ifds.IfdType(h.FirstIfd), 0, ir.po)
 	}
 	err := ir.readIfd(ifds.NewIFD(h.ByteOrder, ifds.IfdType(h.FirstIfd), 0, ir.tiffHeaderOffset, 0))
 	return err
@@ -52,20 +74,25 @@ func (ir *ifdReader) DecodeTiff(r io.Reader, h meta.ExifHeader) error {
 func (ir *ifdReader) DecodeJPEGIfd(r io.Reader, h meta.ExifHeader) (err error) {
 	// Log Header Info
 	if ir.logLevelInfo() {
-		ir.logger.Info().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
+		ir.logInfo().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
 	}
 	ir.ResetReader(r)
 	ir.Exif.ImageType = h.ImageType
+	ir.headerOffset = h.TiffHeaderOffset
 	ir.firstIfdOffset = h.FirstIfdOffset
-	ir.exifLength = h.ExifLength
+	ir.exifLength = ir.limitLength(h.ExifLength)
 	if err = ir.discard(int(h.FirstIfdOffset)); err != nil {
+		err = ir.decodeError(err, ifds.IfdType(h.FirstIfd), 0, ir.po)
 		if ir.logLevelError() {
 			ir.logError(err).Send()
 		}
+		ir.warn(err)
 	}
 	if err := ir.readIfd(ifds.NewIFD(h.ByteOrder, ifds.IfdType(h.FirstIfd), 0, ir.tiffHeaderOffset, 0)); err != nil {
 		return err
 	}
+	// Discard the remainder of the Exif segment
+	ir.exifLength = h.ExifLength
 	err = ir.discard(int(ir.exifLength) - int(ir.po))
 	return err
 }
@@ -73,23 +100,66 @@ func (ir *ifdReader) DecodeJPEGIfd(r io.Reader, h meta.ExifHeader) (err error) {
 func (ir *ifdReader) DecodeIfd(r io.Reader, h meta.ExifHeader) (err error) {
 	// Log Header Info
 	if ir.logLevelInfo() {
-		ir.logger.Info().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
+		ir.logInfo().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
 	}
 	ir.ResetReader(r)
 	ir.Exif.ImageType = h.ImageType
-	ir.exifLength = h.ExifLength
+	ir.headerOffset = h.TiffHeaderOffset
+	ir.exifLength = ir.limitLength(h.ExifLength)
 	ir.firstIfdOffset = h.FirstIfdOffset
 	ir.po = h.FirstIfdOffset
 	err = ir.readIfd(ifds.NewIFD(h.ByteOrder, ifds.IfdType(h.FirstIfd), 0, ir.tiffHeaderOffset, 0))
 	return err
 }
 
+// DecodeReaderAt decodes the Exif of the Tiff header at h.TiffHeaderOffset from an io.ReaderAt.
+// IFDs and tag values are read at their offsets in any order, this includes offsets
+// before the IFD that references them which are skipped by the streaming decoders.
+func (ir *ifdReader) DecodeReaderAt(r io.ReaderAt, h meta.ExifHeader) error {
+	// Log Header Info
+	if ir.logLevelInfo() {
+		ir.logInfo().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
+	}
+	ir.ResetReader(nil)
+	ir.readerAt = r
+	ir.readerAtOffset = int64(h.TiffHeaderOffset)
+
+	ir.Exif.ImageType = h.ImageType
+	ir.headerOffset = h.TiffHeaderOffset
+	ir.firstIfdOffset = h.FirstIfdOffset
+	ir.exifLength = ir.limitLength(h.ExifLength)
+	ir.po = h.FirstIfdOffset
+	return ir.readIfd(ifds.NewIFD(h.ByteOrder, ifds.IfdType(h.FirstIfd), 0, ir.tiffHeaderOffset, 0))
+}
+
+// DecodeBytes decodes the Exif of the Tiff header at h.TiffHeaderOffset of buf. IFDs and
+// tag values are read at their offsets like DecodeReaderAt and are sliced from buf without
+// copying, ParseBuffer returns a slice of buf.
+func (ir *ifdReader) DecodeBytes(buf []byte, h meta.ExifHeader) error {
+	// Log Header Info
+	if ir.logLevelInfo() {
+		ir.logInfo().Str("imageType", h.ImageType.String()).Uint32("tiffHeader", h.TiffHeaderOffset).Uint32("firstIfdOffset", h.FirstIfdOffset).Uint32("exifLength", h.ExifLength).Send()
+	}
+	ir.ResetReader(nil)
+	ir.readerAt = bytes.NewReader(buf)
+	ir.readerAtOffset = int64(h.TiffHeaderOffset)
+	ir.bytes = buf
+
+	ir.Exif.ImageType = h.ImageType
+	ir.headerOffset = h.TiffHeaderOffset
+	ir.firstIfdOffset = h.FirstIfdOffset
+	ir.exifLength = ir.limitLength(h.ExifLength)
+	ir.po = h.FirstIfdOffset
+	return ir.readIfd(ifds.NewIFD(h.ByteOrder, ifds.IfdType(h.FirstIfd), 0, ir.tiffHeaderOffset, 0))
+}
+
 // NewIfdReader creates a new IfdReader with the given io.Reader
 // Need to call defer IfdReader.Close() when complete
-func NewIfdReader(l zerolog.Logger) ifdReader {
+func NewIfdReader(l *slog.Logger) ifdReader {
 	ir := ifdReader{
 		buffer: bufferPool.Get().(*buffer),
 		logger: l,
+		ctx:    context.Background(),
 	}
 	ir.buffer.clear()
 	return ir
@@ -99,6 +169,8 @@ func NewIfdReader(l zerolog.Logger) ifdReader {
 func (ir *ifdReader) ResetReader(r io.Reader) {
 	ir.buffer.clear()
 	ir.reader = r
+	ir.readerAt = nil
+	ir.bytes = nil
 }
 
 // SetCustomTagParser sets a custom tag parser
@@ -106,6 +178,58 @@ func (ir *ifdReader) SetCustomTagParser(fn TagParserFn) {
 	ir.customTagParser = fn
 }
 
+// SetContext sets the context of the ifdReader. The context is checked before
+// each IFD and tag value is read and ctx.Err() is returned when the context is done.
+func (ir *ifdReader) SetContext(ctx context.Context) {
+	ir.ctx = ctx
+}
+
+// SetMaxLength sets the maximum number of bytes of Exif metadata to read.
+// Tags with values beyond the maximum length are not read. Zero is no limit.
+func (ir *ifdReader) SetMaxLength(n uint32) {
+	ir.maxLength = n
+}
+
+// SetSkipMakerNotes sets whether the MakerNote tag (ExifIFD / 0x927c) is skipped.
+func (ir *ifdReader) SetSkipMakerNotes(skip bool) {
+	ir.skipMakerNotes = skip
+}
+
+// SetSkipGPS sets whether the GPSInfo IFD (IFD0 / 0x8825) is skipped.
+func (ir *ifdReader) SetSkipGPS(skip bool) {
+	ir.skipGPS = skip
+}
+
+// limitLength returns the length limited by the maximum length.
+func (ir *ifdReader) limitLength(length uint32) uint32 {
+	if ir.maxLength != 0 && (length == 0 || length > ir.maxLength) {
+		return ir.maxLength
+	}
+	return length
+}
+
+// SetXMPReader sets a reader for the XMP metadata embedded in the
+// ApplicationNotes tag (IFD0 / 0x02bc).
+func (ir *ifdReader) SetXMPReader(fn func(r io.Reader) error) {
+	ir.xmpReader = fn
+}
+
+// SetICCReader sets a reader for the ICC Profile embedded in the
+// InterColorProfile tag (IFD0 / 0x8773).
+func (ir *ifdReader) SetICCReader(fn func(r io.Reader) error) {
+	ir.iccReader = fn
+}
+
+// Dimensions returns the dimensions of the primary image. The PixelXDimension and
+// PixelYDimension tags (ExifIFD / 0xa002, 0xa003) are preferred over the ImageWidth and
+// ImageLength tags (IFD0 / 0x0100, 0x0101) as IFD0 is a reduced resolution image in many Camera Raw files.
+func (ir *ifdReader) Dimensions() meta.Dimensions {
+	if ir.pixelDim.Width != 0 && ir.pixelDim.Height != 0 {
+		return ir.pixelDim
+	}
+	return meta.NewDimensions(uint32(ir.Exif.ImageWidth), uint32(ir.Exif.ImageHeight))
+}
+
 // Close closes an ifdReader. Should be called with defer following a newIfdReader
 func (ir *ifdReader) Close() {
 	bufferPool.Put(ir.buffer)
@@ -113,23 +237,71 @@ func (ir *ifdReader) Close() {
 
 // ifdReader reads, decodes, and parses tags from an io.Reader
 type ifdReader struct {
-	logger zerolog.Logger
+	logger *slog.Logger
+	ctx    context.Context
 	reader io.Reader
+	// readerAt is set for random access decoding with DecodeReaderAt.
+	// Offsets are relative to readerAtOffset.
+	readerAt       io.ReaderAt
+	readerAtOffset int64
+	// bytes is set by DecodeBytes, values are sliced from it without copying.
+	bytes []byte
 	//bufReader        BufferedReader
 	customTagParser  TagParserFn
+	xmpReader        func(r io.Reader) error
+	iccReader        func(r io.Reader) error
 	buffer           *buffer
+	pixelDim         meta.Dimensions
 	Exif             Exif
 	po               uint32
 	tiffHeaderOffset uint32
 	firstIfdOffset   uint32
 	exifLength       uint32
+	maxLength        uint32
+	skipMakerNotes   bool
+	skipGPS          bool
+	headerOffset     uint32 // absolute offset of the Tiff header
+}
+
+// warn logs a non-fatal error and adds it to the Exif Warnings.
+// Errors after the context is done are not added.
+func (ir *ifdReader) warn(err error) {
+	if err == nil || ir.ctx.Err() != nil {
+		return
+	}
+	if ir.logLevelError() {
+		ir.logError(err).Send()
+	}
+	ir.Exif.Warnings = append(ir.Exif.Warnings, err)
+}
+
+// decodeError returns a DecodeError for err at the offset relative to the Tiff header.
+func (ir *ifdReader) decodeError(err error, ifdType ifds.IfdType, id tag.ID, offset uint32) error {
+	if err == nil {
+		return nil
+	}
+	return &meta.DecodeError{
+		Err:       err,
+		Path:      ifdType.String(),
+		Offset:    int64(ir.headerOffset) + int64(offset),
+		TagID:     id,
+		ImageType: ir.Exif.ImageType,
+	}
 }
 
 func (ir *ifdReader) readIfdHeader(ifd ifds.Ifd) (err error) {
 	loglevelInfo := ir.logLevelInfo()
 	var tagCount uint16 // read tagCount
+	if err = ir.ctx.Err(); err != nil {
+		return err
+	}
 
-	if tagCount, err = ir.readUint16(ifd); err != nil || tagCount > 128 {
+	offset := ir.po
+	if tagCount, err = ir.readUint16(ifd); err == nil && tagCount > 128 {
+		err = ErrTagCount
+	}
+	if err != nil {
+		err = ir.decodeError(err, ifd.Type, 0, offset)
 		// Log Ifd Reading error
 		ir.logError(err).Object("ifd", ifd).Uint32("readerOffset", ir.po).Msgf("error tag count: %d for %s", tagCount, ifd.String())
 		return err
@@ -141,6 +313,7 @@ func (ir *ifdReader) readIfdHeader(ifd ifds.Ifd) (err error) {
 
 	buf, err := ir.fastRead(int(tagCount) * 12) // read Tag Headers
 	if err != nil {
+		err = ir.decodeError(err, ifd.Type, 0, offset+2)
 		if ir.logLevelError() {
 			ir.logError(err).Object("ifd", ifd).Uint16("tagCount", tagCount).Send()
 		}
@@ -149,15 +322,20 @@ func (ir *ifdReader) readIfdHeader(ifd ifds.Ifd) (err error) {
 	var t Tag
 	for i := 0; i < int(tagCount); i++ {
 		if t, err = tagFromBuffer(ifd, buf[i*12:]); err != nil {
+			err = ir.decodeError(err, ifd.Type, t.ID, offset+2+uint32(i)*12)
 			if ir.logLevelWarn() {
 				t.logTag(ir.logWarn().Err(err)).Send()
 			}
+			ir.warn(err)
 			continue
 		}
 		if loglevelInfo { // Log Tag Info
 			t.logTag(ir.logDebug()).Send()
 		}
-		if t.IsEmbedded() {
+		if t.ID == ifds.SubIFDs && t.Ifd == ifds.IFD0 && t.IfdIndex == 0 && t.UnitCount == 1 {
+			// The offset of a single SubIfd is embedded in the tag
+			ir.addTagBuffer(NewTag(t.ID, tag.TypeIfd, tag.TypeIfdSize, t.ValueOffset, ifds.SubIfd0, 0, t.ByteOrder))
+		} else if t.IsEmbedded() {
 			ir.parseTag(t)
 		} else {
 			ir.addTagBuffer(t)
@@ -177,9 +355,15 @@ type BufferedReader interface {
 
 func (ir *ifdReader) readNextIfdTag(ifd ifds.Ifd) error {
 	var err error
-	if uint32(ir.buffer.nextTag().ValueOffset) <= ir.po {
+	readNext := ir.readerAt != nil || uint32(ir.buffer.nextTag().ValueOffset) <= ir.po
+	if ifd.Type == ifds.IFD0 && ifd.Index == 0 {
+		// The offset of IFD1 follows the tags of IFD0 unless a tag value is at the same offset
+		readNext = readNext || !ir.buffer.hasTagBetween(ir.po, ir.po+4)
+	}
+	if readNext {
 		var nextIfd uint32
 		if nextIfd, err = ir.readUint32(ifd); err != nil {
+			err = ir.decodeError(err, ifd.Type, 0, ir.po)
 			if ir.logLevelError() {
 				ir.logError(err).Object("ifd", ifd).Uint32("offset", ir.po).Msgf("error reading nextIFD. Offset: %d Ifd: %s", ir.po, ifd.String())
 			}
@@ -202,26 +386,40 @@ func (ir *ifdReader) readIfd(ifd ifds.Ifd) (err error) {
 	}
 
 	for t := ir.buffer.currentTag(); ir.buffer.validTag(); t = ir.buffer.advanceBuffer() {
+		if err = ir.ctx.Err(); err != nil {
+			return err
+		}
 
 		if t.IsType(tag.TypeIfd) {
 			if err = ir.seekToTag(t); err != nil { // seek to next tag value
-				ir.logError(err).Send()
+				ir.warn(err)
+				continue
 			}
 			ir.buffer.resetPosition() // Reset tagbuffer position to 0
 			switch t.Ifd {
 			case ifds.IFD0:
+				if t.ID == ifds.GPSTag && ir.skipGPS {
+					continue
+				}
 				switch t.ID {
 				case ifds.GPSTag, ifds.ExifTag:
-					if err = ir.readIfdHeader(t.childIfd()); err != nil { // ignore errors from GPSIfd and ExifIfd
-						ir.logError(err).Send()
+					if err = ir.readIfdHeader(t.childIfd()); err != nil { // continue after errors from GPSIfd and ExifIfd
+						ir.warn(err)
+					}
+				case ifds.SubIFDs:
+					// IFD1 is the next IFD after IFD0
+					if t.IfdIndex == 1 {
+						if err = ir.readIfdHeader(ifds.NewIFD(t.ByteOrder, ifds.IFD0, t.IfdIndex, t.ValueOffset, 0)); err != nil {
+							ir.warn(err)
+						}
 					}
 				}
 			case ifds.SubIfd0, ifds.SubIfd1, ifds.SubIfd2, ifds.SubIfd3, ifds.SubIfd4, ifds.SubIfd5:
-				if err = ir.readIfdHeader(t.childIfd()); err != nil { // ignore errors from SubIfd0
-					ir.logError(err).Send()
+				if err = ir.readIfdHeader(t.childIfd()); err != nil { // continue after errors from SubIfds
+					ir.warn(err)
 				}
 			case ifds.ExifIFD:
-				if t.ID == exififd.MakerNote {
+				if t.ID == exififd.MakerNote && !ir.skipMakerNotes {
 					ir.readMakerNotes(t)
 				}
 			}
@@ -249,7 +447,7 @@ func (ir *ifdReader) readSubIfds(t Tag) {
 			}
 			return
 		}
-		for i := 0; i < int(t.UnitCount); i++ {
+		for i := 0; i < int(t.UnitCount) && i < 6 && 4*i+4 <= len(buf); i++ {
 			ir.addTagBuffer(NewTag(t.ID, tag.TypeIfd, tag.TypeIfdSize, t.ByteOrder.Uint32(buf[4*i:]), ifds.SubIfd0+ifds.IfdType(i), 0, t.ByteOrder))
 		}
 	}
@@ -259,20 +457,25 @@ func (ir *ifdReader) readMakerNotes(t Tag) {
 	switch ir.Exif.CameraMake {
 	case ifds.Canon:
 		if err := ir.readIfdHeader(t.childIfd()); err != nil {
-			ir.logError(err).Send()
+			ir.warn(err)
 		}
 	case ifds.Nikon:
 		if t.Size() > 18 { // read Nikon Makernotes header 18 bytes
 			buf, err := ir.fastRead(18)
 			if err != nil {
+				err = ir.decodeError(err, t.Ifd, t.ID, t.ValueOffset)
 				t.logTag(ir.logError(err)).Send()
+				ir.warn(err)
+				return
 			}
 			if nikon.IsNikonMkNoteHeaderBytes(buf[:5]) {
 				ir.Exif.ImageType = imagetype.ImageNEF
 				if byteOrder := utils.BinaryOrder(buf[10:14]); byteOrder != utils.UnknownEndian {
-					err = ir.readIfdHeader(ifds.NewIFD(byteOrder, ifds.MknoteIFD, t.IfdIndex, t.ValueOffset, t.ValueOffset+byteOrder.Uint32(buf[14:18])))
+					// Tag values are relative to the Tiff header that follows the 10 byte Nikon header
+					base := t.ValueOffset + 10
+					err = ir.readIfdHeader(ifds.NewIFD(byteOrder, ifds.MknoteIFD, t.IfdIndex, base+byteOrder.Uint32(buf[14:18]), base))
 					if err != nil {
-						ir.logError(err).Send()
+						ir.warn(err)
 					}
 				}
 			}
@@ -284,6 +487,9 @@ func (ir *ifdReader) fastRead(n int) (buf []byte, err error) {
 	if ir.exifLength != 0 && int(ir.po)+n > int(ir.exifLength) {
 		return nil, imagetype.ErrDataLength
 	}
+	if ir.readerAt != nil {
+		return ir.readAt(n)
+	}
 	if br, ok := ir.reader.(BufferedReader); ok {
 		if buf, err = br.Peek(n); err != nil {
 			if ir.logLevelError() {
@@ -300,7 +506,10 @@ func (ir *ifdReader) fastRead(n int) (buf []byte, err error) {
 		ir.po += uint32(n)
 		return
 	}
-	if n, err = ir.reader.Read(ir.buffer.buf[:n]); err != nil {
+	if n > bufferLength {
+		return nil, meta.ErrBufLength
+	}
+	if n, err = io.ReadFull(ir.reader, ir.buffer.buf[:n]); err != nil {
 		if ir.logLevelError() {
 			ir.logError(err).Msg("Read error")
 		}
@@ -310,28 +519,75 @@ func (ir *ifdReader) fastRead(n int) (buf []byte, err error) {
 	return ir.buffer.buf[:n], err
 }
 
+// readAt reads n bytes at the reader offset from the io.ReaderAt.
+func (ir *ifdReader) readAt(n int) (buf []byte, err error) {
+	if ir.bytes != nil {
+		return ir.sliceBytes(n)
+	}
+	switch {
+	case n > readAtMaxLength:
+		return nil, meta.ErrBufLength
+	case n > bufferLength:
+		buf = make([]byte, n)
+	default:
+		buf = ir.buffer.buf[:n]
+	}
+	// ReadAt may return io.EOF with a full read at the end of the input
+	if m, err := ir.readerAt.ReadAt(buf, ir.readerAtOffset+int64(ir.po)); err != nil && !(m == n && err == io.EOF) {
+		if ir.logLevelError() {
+			ir.logError(err).Msg("ReadAt error")
+		}
+		return nil, err
+	}
+	ir.po += uint32(n)
+	return buf, nil
+}
+
+// sliceBytes returns the n bytes at the reader offset of the bytes of DecodeBytes.
+func (ir *ifdReader) sliceBytes(n int) ([]byte, error) {
+	off := ir.readerAtOffset + int64(ir.po)
+	if off+int64(n) > int64(len(ir.bytes)) {
+		if ir.logLevelError() {
+			ir.logError(io.EOF).Msg("ReadAt error")
+		}
+		return nil, io.EOF
+	}
+	ir.po += uint32(n)
+	return ir.bytes[off : off+int64(n) : off+int64(n)], nil
+}
+
 // ReadUint16 reads a uint16 from an ifdReader.
 func (ir *ifdReader) readUint16(ifd ifds.Ifd) (uint16, error) {
 	buf, err := ir.fastRead(2)
-	return ifd.ByteOrder.Uint16(buf), err
+	if err != nil {
+		return 0, err
+	}
+	return ifd.ByteOrder.Uint16(buf), nil
 }
 
 // ReadUint32 reads a uint32 from an ifdReader.
 func (ir *ifdReader) readUint32(ifd ifds.Ifd) (uint32, error) {
 	buf, err := ir.fastRead(4)
-	return ifd.ByteOrder.Uint32(buf), err
+	if err != nil {
+		return 0, err
+	}
+	return ifd.ByteOrder.Uint32(buf), nil
 }
 
 func tagFromBuffer(ifd ifds.Ifd, buf []byte) (t Tag, err error) {
-	tagID := tag.ID(ifd.ByteOrder.Uint16(buf[:2]))                  // TagID
-	tagType := tag.Type(ifd.ByteOrder.Uint16(buf[2:4]))             // TagType
-	unitCount := ifd.ByteOrder.Uint32(buf[4:8])                     // UnitCount
-	valueOffset := ifd.ByteOrder.Uint32(buf[8:12]) + ifd.BaseOffset // ValueOffset
+	tagID := tag.ID(ifd.ByteOrder.Uint16(buf[:2]))      // TagID
+	tagType := tag.Type(ifd.ByteOrder.Uint16(buf[2:4])) // TagType
+	unitCount := ifd.ByteOrder.Uint32(buf[4:8])         // UnitCount
+	valueOffset := ifd.ByteOrder.Uint32(buf[8:12])      // ValueOffset
 
 	t = NewTag(tagID, tagIsIfd(ifd.Type, tagID, tagType), unitCount, valueOffset, ifd.Type, ifd.Index, ifd.ByteOrder) // NewTag
 	if !t.IsValid() {
 		err = tag.ErrTagTypeNotValid
 	}
+	if !t.IsEmbedded() {
+		// Embedded values are not offsets
+		t.ValueOffset += ifd.BaseOffset
+	}
 	return t, err
 }
 
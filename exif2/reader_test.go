@@ -0,0 +1,284 @@
+package exif2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// backwardTiff returns a little endian Tiff with the tag values written
+// before IFD0 and an ExifIFD that is before IFD0.
+func backwardTiff() []byte {
+	le := binary.LittleEndian
+	buf := make([]byte, 64)
+	copy(buf, "II\x2a\x00")
+	copy(buf[8:], "Canon\x00")         // Make at offset 8
+	copy(buf[16:], "Canon EOS 6D\x00") // Model at offset 16
+
+	// ExifIFD at offset 32: ISOSpeedRatings
+	exifIfd := []byte{}
+	exifIfd = le.AppendUint16(exifIfd, 1)
+	exifIfd = appendEntry(exifIfd, exififd.ISOSpeedRatings, tag.TypeShort, 1, 400)
+	exifIfd = le.AppendUint32(exifIfd, 0)
+	copy(buf[32:], exifIfd)
+
+	// IFD0 at the end of the Tiff
+	le.PutUint32(buf[4:], uint32(len(buf)))
+	buf = le.AppendUint16(buf, 3)
+	buf = appendEntry(buf, ifds.Make, tag.TypeASCII, 6, 8)
+	buf = appendEntry(buf, ifds.Model, tag.TypeASCII, 13, 16)
+	buf = appendEntry(buf, ifds.ExifTag, tag.TypeLong, 1, 32)
+	return le.AppendUint32(buf, 0)
+}
+
+func appendEntry(buf []byte, id tag.ID, t tag.Type, count uint32, value uint32) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(id))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(t))
+	buf = binary.LittleEndian.AppendUint32(buf, count)
+	return binary.LittleEndian.AppendUint32(buf, value)
+}
+
+func TestDecodeReaderAt(t *testing.T) {
+	buf := backwardTiff()
+	h := meta.NewExifHeader(utils.LittleEndian, binary.LittleEndian.Uint32(buf[4:]), 0, 0, imagetype.ImageTiff)
+	h.FirstIfd = ifds.IFD0
+
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err := ir.DecodeReaderAt(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	if ir.Exif.Make != "Canon" || ir.Exif.Model != "Canon EOS 6D" {
+		t.Errorf("Incorrect Make and Model wanted %q %q got %q %q", "Canon", "Canon EOS 6D", ir.Exif.Make, ir.Exif.Model)
+	}
+	if ir.Exif.ISOSpeed != 400 {
+		t.Errorf("Incorrect ISOSpeed wanted %d got %d", 400, ir.Exif.ISOSpeed)
+	}
+
+	// The streaming decoder skips the tag values before IFD0
+	ir2 := NewIfdReader(Logger)
+	defer ir2.Close()
+	if err := ir2.DecodeTiff(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	if ir2.Exif.Make != "" {
+		t.Errorf("Incorrect streaming Make wanted %q got %q", "", ir2.Exif.Make)
+	}
+}
+
+// eofReaderAt is an io.ReaderAt that returns io.EOF with a full read at the end
+// of the data, as the io.ReaderAt contract allows.
+type eofReaderAt []byte
+
+func (r eofReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(r)) {
+		return 0, io.EOF
+	}
+	n := copy(p, r[off:])
+	if off+int64(n) == int64(len(r)) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestDecodeReaderAtEOF(t *testing.T) {
+	// IFD0 at offset 8 with the Make value at the end of the Tiff
+	le := binary.LittleEndian
+	buf := []byte("II\x2a\x00\x08\x00\x00\x00")
+	buf = le.AppendUint16(buf, 1)
+	buf = appendEntry(buf, ifds.Make, tag.TypeASCII, 6, 8+2+12+4)
+	buf = le.AppendUint32(buf, 0)
+	buf = append(buf, "Canon\x00"...)
+
+	h := meta.NewExifHeader(utils.LittleEndian, 8, 0, 0, imagetype.ImageTiff)
+	h.FirstIfd = ifds.IFD0
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err := ir.DecodeReaderAt(eofReaderAt(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	if ir.Exif.Make != "Canon" {
+		t.Errorf("Incorrect Make wanted %q got %q", "Canon", ir.Exif.Make)
+	}
+}
+
+func TestDecodeReverseTagOffset(t *testing.T) {
+	// The tag values of JPEG.jpg are before its IFD0
+	buf, err := os.ReadFile("../testImages/JPEG.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = buf[bytes.Index(buf, []byte("Exif\x00\x00"))+6:]
+	bo := utils.BinaryOrder(buf[:4])
+	h := meta.NewExifHeader(bo, bo.Uint32(buf[4:]), 0, uint32(len(buf)), imagetype.ImageJPEG)
+	h.FirstIfd = ifds.IFD0
+
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err = ir.DecodeTiff(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	var de *meta.DecodeError
+	if len(ir.Exif.Warnings) == 0 || !errors.Is(ir.Exif.Warnings[0], ErrReverseTagOffset) || !errors.As(ir.Exif.Warnings[0], &de) || de.TagID == 0 {
+		t.Fatalf("Incorrect Warnings wanted %v got %v", ErrReverseTagOffset, ir.Exif.Warnings)
+	}
+
+	// The values are read with random access
+	ir2 := NewIfdReader(Logger)
+	defer ir2.Close()
+	if err = ir2.DecodeReaderAt(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	if ir2.Exif.Make != "GoPro" || len(ir2.Exif.Warnings) != 0 {
+		t.Errorf("Incorrect Make wanted %q got %q %v", "GoPro", ir2.Exif.Make, ir2.Exif.Warnings)
+	}
+}
+
+func TestDecodeTagCount(t *testing.T) {
+	// IFD0 with 200 tags
+	le := binary.LittleEndian
+	buf := []byte("II\x2a\x00\x08\x00\x00\x00")
+	buf = le.AppendUint16(buf, 200)
+	buf = append(buf, make([]byte, 200*12+4)...)
+
+	h := meta.NewExifHeader(utils.LittleEndian, 8, 0, uint32(len(buf)), imagetype.ImageTiff)
+	h.FirstIfd = ifds.IFD0
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	err := ir.DecodeTiff(bytes.NewReader(buf), h)
+	var de *meta.DecodeError
+	if !errors.Is(err, ErrTagCount) || !errors.As(err, &de) || de.Offset != 8 {
+		t.Errorf("Incorrect error wanted %v got %v", ErrTagCount, err)
+	}
+}
+
+func TestDecodeMakerNotes(t *testing.T) {
+	makerNotesTests := []struct {
+		filename string
+		wanted   MakerNotes
+	}{
+		{"../testImages/CR2.exif", &CanonMakerNotes{
+			ImageType:            "Canon EOS-1Ds Mark III",
+			FirmwareVersion:      "Firmware Version 2.1.2",
+			LensModel:            "EF50mm f/1.2L USM",
+			InternalSerialNumber: "I01328",
+			SerialNumber:         600005,
+		}},
+		{"../testImages/NEF.exif", &NikonMakerNotes{
+			MakerNoteVersion: "0211",
+			Quality:          "RAW",
+			WhiteBalance:     "SUNNY",
+			FocusMode:        "MANUAL",
+			SerialNumber:     "7302381",
+			ISO:              [2]uint16{0, 100},
+		}},
+	}
+	for _, mt := range makerNotesTests {
+		t.Run(mt.filename, func(t *testing.T) {
+			buf, err := os.ReadFile(mt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bo := utils.BinaryOrder(buf[:4])
+			h := meta.NewExifHeader(bo, bo.Uint32(buf[4:]), 0, uint32(len(buf)), imagetype.ImageTiff)
+			h.FirstIfd = ifds.IFD0
+
+			// The streaming decoder and DecodeBytes decode the same makernote
+			ir := NewIfdReader(Logger)
+			defer ir.Close()
+			if err = ir.DecodeTiff(bytes.NewReader(buf), h); err != nil {
+				t.Fatal(err)
+			}
+			ir2 := NewIfdReader(Logger)
+			defer ir2.Close()
+			if err = ir2.DecodeBytes(buf, h); err != nil {
+				t.Fatal(err)
+			}
+			for _, mn := range []MakerNotes{ir.Exif.Makernotes, ir2.Exif.Makernotes} {
+				if !reflect.DeepEqual(mn, mt.wanted) {
+					t.Errorf("Incorrect Makernotes wanted %+v got %+v", mt.wanted, mn)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	buf := backwardTiff()
+	h := meta.NewExifHeader(utils.LittleEndian, binary.LittleEndian.Uint32(buf[4:]), 0, 0, imagetype.ImageTiff)
+	h.FirstIfd = ifds.IFD0
+
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err := ir.DecodeBytes(buf, h); err != nil {
+		t.Fatal(err)
+	}
+	if ir.Exif.Make != "Canon" || ir.Exif.ISOSpeed != 400 {
+		t.Errorf("Incorrect Make and ISOSpeed wanted %q %d got %q %d", "Canon", 400, ir.Exif.Make, ir.Exif.ISOSpeed)
+	}
+
+	// The Model value is a slice of buf
+	var model []byte
+	ir.SetCustomTagParser(func(p TagParser, t Tag) error {
+		if t.ID == ifds.Model {
+			model = p.ParseBuffer(t)
+		}
+		return nil
+	})
+	if err := ir.DecodeBytes(buf, h); err != nil {
+		t.Fatal(err)
+	}
+	if string(model) != "Canon EOS 6D" || &model[0] != &buf[16] {
+		t.Errorf("Incorrect Model wanted a slice of buf got %q", model)
+	}
+
+	// Values beyond the end of buf
+	ir2 := NewIfdReader(Logger)
+	defer ir2.Close()
+	if err := ir2.DecodeBytes(buf[:24], h); err == nil {
+		t.Error("Incorrect error wanted an error for a truncated buffer")
+	}
+}
+
+func TestDecodeWarnings(t *testing.T) {
+	le := binary.LittleEndian
+	buf := []byte("II\x2a\x00\x08\x00\x00\x00")
+
+	// IFD0 with a GPS IFD pointer beyond the end of the Tiff
+	buf = le.AppendUint16(buf, 2)
+	buf = appendEntry(buf, ifds.Make, tag.TypeASCII, 6, 38)
+	buf = appendEntry(buf, ifds.GPSTag, tag.TypeLong, 1, 0x1000)
+	buf = le.AppendUint32(buf, 0)
+	buf = append(buf, "Canon\x00"...)
+
+	h := meta.NewExifHeader(utils.LittleEndian, 8, 0, uint32(len(buf)), imagetype.ImageTiff)
+	ir := NewIfdReader(Logger)
+	defer ir.Close()
+	if err := ir.DecodeTiff(bytes.NewReader(buf), h); err != nil {
+		t.Fatal(err)
+	}
+	if ir.Exif.Make != "Canon" {
+		t.Errorf("Incorrect Make wanted %q got %q", "Canon", ir.Exif.Make)
+	}
+	if len(ir.Exif.Warnings) != 1 {
+		t.Fatalf("Incorrect Warnings wanted 1 got %v", ir.Exif.Warnings)
+	}
+	var de *meta.DecodeError
+	if !errors.As(ir.Exif.Warnings[0], &de) {
+		t.Fatalf("Incorrect Warning wanted *meta.DecodeError got %T", ir.Exif.Warnings[0])
+	}
+	if de.TagID != ifds.GPSTag || de.Offset != 0x1000 {
+		t.Errorf("Incorrect Warning wanted GPSTag at offset %d got %v", 0x1000, de)
+	}
+}
@@ -0,0 +1,130 @@
+package exif2
+
+import (
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// NewEncoderTiff returns a new Encoder with the entries of IFD0, ExifIFD, GPSIFD,
+// the Interoperability IFD, IFD1 and the thumbnail of the Tiff block buf. An "Exif\x00\x00"
+// prefix is removed. Values are copied unchanged with the byte order of buf.
+//
+// Offsets that are not pointers to these IFDs or to the thumbnail are not updated
+// when the Tiff block is encoded, such as the offsets inside of MakerNotes.
+//
+// Returns ErrEncodeTiff if buf is not a valid Tiff block.
+func NewEncoderTiff(buf []byte) (*Encoder, error) {
+	if len(buf) >= len(exifPrefix) && string(buf[:len(exifPrefix)]) == string(exifPrefix) {
+		buf = buf[len(exifPrefix):]
+	}
+	if len(buf) < tiffHeaderLength {
+		return nil, ErrEncodeTiff
+	}
+	bo := utils.BinaryOrder(buf)
+	if bo == utils.UnknownEndian {
+		return nil, ErrEncodeTiff
+	}
+	enc := NewEncoder(bo)
+	next, err := enc.readIfd(buf, ifds.IFD0, bo.Uint32(buf[4:8]))
+	if err != nil {
+		return nil, err
+	}
+	if next != 0 {
+		if _, err = enc.readIfd(buf, ifds.NullIFD, next); err != nil {
+			return nil, err
+		}
+	}
+	return enc, nil
+}
+
+// readIfd reads the entries of the IFD at offset into the Encoder. The entries
+// of IFD1 are read with ifds.NullIFD. Returns the offset of the next IFD.
+func (enc *Encoder) readIfd(buf []byte, ifdType ifds.IfdType, offset uint32) (next uint32, err error) {
+	bo := enc.byteOrder
+	if uint64(offset)+2 > uint64(len(buf)) {
+		return 0, ErrEncodeTiff
+	}
+	n := uint32(bo.Uint16(buf[offset:]))
+	end := uint64(offset) + 2 + uint64(n)*ifdEntryLength
+	if end+4 > uint64(len(buf)) {
+		return 0, ErrEncodeTiff
+	}
+	var thumbnailOffset, thumbnailLength uint32
+	for i := uint32(0); i < n; i++ {
+		entry := buf[offset+2+i*ifdEntryLength:]
+		id := tag.ID(bo.Uint16(entry))
+		t := tag.Type(bo.Uint16(entry[2:]))
+		count := bo.Uint32(entry[4:])
+		if t.Size() == 0 {
+			continue
+		}
+		size := uint64(t.Size()) * uint64(count)
+		value := entry[8:12]
+		if size > 4 {
+			valueOffset := uint64(bo.Uint32(entry[8:]))
+			if valueOffset+size > uint64(len(buf)) {
+				return 0, ErrEncodeTiff
+			}
+			value = buf[valueOffset : valueOffset+size]
+		}
+
+		// Pointers are set by the Encoder
+		switch {
+		case ifdType == ifds.IFD0 && id == ifds.ExifTag:
+			_, err = enc.readIfd(buf, ifds.ExifIFD, bo.Uint32(value))
+		case ifdType == ifds.IFD0 && id == ifds.GPSTag:
+			_, err = enc.readIfd(buf, ifds.GPSIFD, bo.Uint32(value))
+		case ifdType == ifds.ExifIFD && id == exififd.InteroperabilityTag:
+			_, err = enc.readIfd(buf, ifds.IopIFD, bo.Uint32(value))
+		case ifdType == ifds.NullIFD && id == ifds.JPEGInterchangeFormat:
+			thumbnailOffset = bo.Uint32(value)
+		case ifdType == ifds.NullIFD && id == ifds.JPEGInterchangeFormatLength:
+			thumbnailLength = bo.Uint32(value)
+		case ifdType == ifds.NullIFD:
+			enc.SetIfd1(newRawEntry(id, t, append([]byte(nil), value[:size]...)))
+		default:
+			err = enc.Set(ifdType, newRawEntry(id, t, append([]byte(nil), value[:size]...)))
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if thumbnailLength > 0 {
+		if uint64(thumbnailOffset)+uint64(thumbnailLength) > uint64(len(buf)) {
+			return 0, ErrEncodeTiff
+		}
+		enc.SetThumbnail(append([]byte(nil), buf[thumbnailOffset:thumbnailOffset+thumbnailLength]...))
+	}
+	return bo.Uint32(buf[end:]), nil
+}
+
+// Filter removes the entries of IFD0, ExifIFD, GPSIFD, the Interoperability IFD
+// and IFD1 for which keep returns false. The entries of IFD1 are filtered with ifds.NullIFD.
+func (enc *Encoder) Filter(keep func(ifdType ifds.IfdType, id tag.ID) bool) {
+	for _, ifdType := range []ifds.IfdType{ifds.IFD0, ifds.ExifIFD, ifds.GPSIFD, ifds.IopIFD, ifds.NullIFD} {
+		entries := &enc.ifd1
+		if ifdType != ifds.NullIFD {
+			entries, _ = enc.entries(ifdType)
+		}
+		filtered := (*entries)[:0]
+		for _, e := range *entries {
+			if keep(ifdType, e.ID) {
+				filtered = append(filtered, e)
+			}
+		}
+		*entries = filtered
+	}
+}
+
+// RemoveThumbnail removes IFD1 and the thumbnail image.
+func (enc *Encoder) RemoveThumbnail() {
+	enc.ifd1 = nil
+	enc.thumbnail = nil
+}
+
+// Len returns the number of entries of all IFDs of the Encoder.
+func (enc *Encoder) Len() int {
+	return len(enc.ifd0) + len(enc.exif) + len(enc.gps) + len(enc.iop) + len(enc.ifd1)
+}
@@ -2,12 +2,13 @@ package exif2
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2/ifds"
 	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
 	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/internal/logging"
 	"github.com/tdelov/imagemeta/meta/utils"
-	"github.com/rs/zerolog"
 )
 
 // Tag is an Exif Tag (16 bytes)
@@ -35,14 +36,13 @@ func NewTag(tagID tag.ID, tagType tag.Type, unitCount uint32, valueOffset uint32
 	}
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (t Tag) MarshalZerologObject(e *zerolog.Event) {
-	e.Stringer("id", t.ID).Str("name", t.Name()).Stringer("type", t.Type).Stringer("ifd", t.Ifd).Uint32("units", t.UnitCount).Str("offset", fmt.Sprintf("0x%04x", t.ValueOffset))
+// LogValue is a slog.LogValuer interface for logging
+func (t Tag) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", t.ID.String()), slog.String("name", t.Name()), slog.String("type", t.Type.String()), slog.String("ifd", t.Ifd.String()), slog.Uint64("units", uint64(t.UnitCount)), slog.String("offset", fmt.Sprintf("0x%04x", t.ValueOffset)))
 }
 
-func (t Tag) logTag(e *zerolog.Event) *zerolog.Event {
-	t.MarshalZerologObject(e)
-	return e
+func (t Tag) logTag(e *logging.Event) *logging.Event {
+	return e.Object("tag", t)
 }
 
 // Name returns the Tag name as a string
@@ -0,0 +1,399 @@
+package imagemeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/xmp"
+)
+
+// ExiftoolTags are the tags of an image keyed by group-qualified exiftool tag names
+// such as "EXIF:Make", "XMP-dc:Creator" or "Composite:GPSPosition".
+type ExiftoolTags map[string]interface{}
+
+// exiftoolNumber matches the values that exiftool writes as JSON numbers.
+var exiftoolNumber = regexp.MustCompile(`^-?(\d|[1-9]\d{1,14})(\.\d{1,16})?(e[-+]?\d{1,3})?$`)
+
+// exiftool date layouts
+const (
+	exiftoolDateLayout    = "2006:01:02 15:04:05"
+	exiftoolXMPDateLayout = "2006:01:02 15:04:05.999Z07:00"
+)
+
+// Exiftool returns the metadata of m as the tags of "exiftool -j -G", with the Exif tags in the
+// "EXIF" group and the XMP tags in the "XMP-dc", "XMP-xmp", "XMP-aux", "XMP-tiff" and "XMP-exif" groups.
+// The values are formatted as the print conversions of exiftool, values that exiftool writes as
+// numbers are json.Number.
+//
+// The decoded tags of Canon and Nikon makernotes (see exif2.MakerNotes) are in the "MakerNotes"
+// group, the other makernote tags are not present.
+//
+// Tags with zero values are not present. The JSON of ExiftoolTags with a "SourceFile" tag is an
+// element of the JSON array of "exiftool -j -G".
+func Exiftool(m Metadata) ExiftoolTags {
+	tags := ExiftoolTags{}
+	if !m.ImageType.IsUnknown() {
+		tags.set("File:MIMEType", m.ImageType.String())
+	}
+	if m.Dimensions.Width != 0 && m.Dimensions.Height != 0 {
+		tags.set("Composite:ImageSize", fmt.Sprintf("%dx%d", m.Dimensions.Width, m.Dimensions.Height))
+	}
+	tags.exif(m.Exif, m.ImageType)
+	tags.xmp(m.XMP)
+	return tags
+}
+
+// set sets the tag key to a string value, or a json.Number when exiftool writes the value as a number.
+// Empty values are not set.
+func (tags ExiftoolTags) set(key string, value string) {
+	if value == "" {
+		return
+	}
+	if exiftoolNumber.MatchString(value) {
+		tags[key] = json.Number(value)
+		return
+	}
+	tags[key] = value
+}
+
+// setUint sets the tag key to a non-zero number.
+func (tags ExiftoolTags) setUint(key string, value uint64) {
+	if value != 0 {
+		tags[key] = json.Number(strconv.FormatUint(value, 10))
+	}
+}
+
+// setList sets the tag key to a list, or to a string for a single value.
+func (tags ExiftoolTags) setList(key string, values []string) {
+	switch len(values) {
+	case 0:
+	case 1:
+		tags.set(key, values[0])
+	default:
+		tags[key] = values
+	}
+}
+
+func (tags ExiftoolTags) exif(e exif2.Exif, it imagetype.ImageType) {
+	tags.set("EXIF:ImageDescription", e.ImageDescription)
+	tags.set("EXIF:Make", e.Make)
+	tags.set("EXIF:Model", e.Model)
+	tags.set("EXIF:Orientation", exiftoolOrientation(e.Orientation))
+	tags.setUint("EXIF:XResolution", uint64(e.XResolution))
+	tags.setUint("EXIF:YResolution", uint64(e.YResolution))
+	tags.set("EXIF:ResolutionUnit", exiftoolResolutionUnit(e.ResolutionUnit))
+	tags.set("EXIF:Software", e.Software)
+	tags.set("EXIF:ProcessingSoftware", e.ProcessingSoftware)
+	tags.set("EXIF:DocumentName", e.DocumentName)
+	tags.set("EXIF:Artist", e.Artist)
+	tags.set("EXIF:Copyright", e.Copyright)
+	if e.Compression != 0 {
+		tags.set("EXIF:Compression", e.Compression.String())
+	}
+	tags.setUint("EXIF:ThumbnailOffset", uint64(e.ThumbnailOffset))
+	tags.setUint("EXIF:ThumbnailLength", uint64(e.ThumbnailLength))
+
+	tags.set("EXIF:ExposureTime", exiftoolExposureTime(e.ExposureTime))
+	if e.FNumber > 0 {
+		format := "%.1f"
+		if e.FNumber < 1 {
+			format = "%.2f"
+		}
+		tags.set("EXIF:FNumber", fmt.Sprintf(format, e.FNumber))
+	}
+	if e.ExposureProgram != 0 {
+		tags.set("EXIF:ExposureProgram", e.ExposureProgram.String())
+	}
+	tags.setUint("EXIF:ISO", uint64(e.ISOSpeed))
+	tags.set("EXIF:ExposureCompensation", exiftoolExposureBias(e.ExposureBias))
+	if e.MeteringMode != 0 {
+		tags.set("EXIF:MeteringMode", e.MeteringMode.String())
+	}
+	if e.Flash != 0 {
+		tags.set("EXIF:Flash", e.Flash.String())
+	}
+	if e.FocalLength > 0 {
+		tags.set("EXIF:FocalLength", fmt.Sprintf("%.1f mm", e.FocalLength))
+	}
+	if e.FocalLengthIn35mmFormat > 0 {
+		tags.set("EXIF:FocalLengthIn35mmFormat", fmt.Sprintf("%d mm", int(e.FocalLengthIn35mmFormat+0.5)))
+	}
+	if e.SubjectDistance > 0 {
+		tags.set("EXIF:SubjectDistance", strconv.FormatFloat(float64(e.SubjectDistance), 'f', -1, 32)+" m")
+	}
+	if e.ExposureMode != 0 {
+		tags.set("EXIF:ExposureMode", e.ExposureMode.String())
+	}
+	tags.set("EXIF:ColorSpace", exiftoolColorSpace(e.ColorSpace))
+	width, height := "EXIF:ImageWidth", "EXIF:ImageHeight"
+	switch it {
+	case imagetype.ImageJPEG, imagetype.ImagePNG, imagetype.ImageWebP, imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
+		width, height = "EXIF:ExifImageWidth", "EXIF:ExifImageHeight"
+	}
+	tags.setUint(width, uint64(e.ImageWidth))
+	tags.setUint(height, uint64(e.ImageHeight))
+	tags.setUint("EXIF:Rating", uint64(e.Rating))
+	tags.setUint("EXIF:ImageNumber", uint64(e.ImageNumber))
+	tags.set("EXIF:OwnerName", e.OwnerName)
+	tags.set("EXIF:SerialNumber", e.CameraSerial)
+	tags.set("EXIF:LensInfo", exiftoolLensInfo(e.LensInfo))
+	tags.set("EXIF:LensMake", e.LensMake)
+	tags.set("EXIF:LensModel", e.LensModel)
+	tags.set("EXIF:LensSerialNumber", e.LensSerial)
+	tags.set("EXIF:ImageUniqueID", e.ImageUniqueID)
+
+	tags.date("EXIF:ModifyDate", "EXIF:OffsetTime", e.ModifyDate())
+	tags.date("EXIF:DateTimeOriginal", "EXIF:OffsetTimeOriginal", e.DateTimeOriginal())
+	tags.date("EXIF:CreateDate", "EXIF:OffsetTimeDigitized", e.CreateDate())
+
+	tags.gps(e.GPS)
+	tags.makerNotes(e.Makernotes, e.Model)
+}
+
+// makerNotes sets the tags of the decoded makernote of the camera model.
+func (tags ExiftoolTags) makerNotes(mn exif2.MakerNotes, model string) {
+	switch mn := mn.(type) {
+	case *exif2.CanonMakerNotes:
+		tags.set("MakerNotes:CanonImageType", mn.ImageType)
+		tags.set("MakerNotes:CanonFirmwareVersion", mn.FirmwareVersion)
+		tags.set("MakerNotes:OwnerName", mn.OwnerName)
+		tags.set("MakerNotes:SerialNumber", exiftoolCanonSerialNumber(mn.SerialNumber, model))
+		tags.set("MakerNotes:LensModel", mn.LensModel)
+		tags.set("MakerNotes:InternalSerialNumber", mn.InternalSerialNumber)
+	case *exif2.NikonMakerNotes:
+		tags.set("MakerNotes:MakerNoteVersion", exiftoolNikonVersion(mn.MakerNoteVersion))
+		if mn.ISO[1] != 0 {
+			iso := strconv.Itoa(int(mn.ISO[1]))
+			if mn.ISO[0] != 0 {
+				iso = strconv.Itoa(int(mn.ISO[0])) + " " + iso
+			}
+			tags.set("MakerNotes:ISO", iso)
+		}
+		tags.set("MakerNotes:Quality", mn.Quality)
+		tags.set("MakerNotes:WhiteBalance", mn.WhiteBalance)
+		tags.set("MakerNotes:FocusMode", mn.FocusMode)
+		tags.set("MakerNotes:SerialNumber", mn.SerialNumber)
+	}
+}
+
+// date sets the date tag and the offset time tag when the date has a time zone offset.
+func (tags ExiftoolTags) date(key, offsetKey string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	tags.set(key, t.Format(exiftoolDateLayout))
+	if t.Location() != time.UTC {
+		tags.set(offsetKey, t.Format("-07:00"))
+	}
+}
+
+func (tags ExiftoolTags) gps(g exif2.GPSInfo) {
+	if g == (exif2.GPSInfo{}) {
+		return
+	}
+	lat, latRef := exiftoolCoordinate(g.Latitude(), "North", "South")
+	lng, lngRef := exiftoolCoordinate(g.Longitude(), "East", "West")
+	tags.set("EXIF:GPSLatitudeRef", latRef)
+	tags.set("EXIF:GPSLatitude", lat)
+	tags.set("EXIF:GPSLongitudeRef", lngRef)
+	tags.set("EXIF:GPSLongitude", lng)
+	latitude, longitude := lat+" "+latRef[:1], lng+" "+lngRef[:1]
+	tags.set("Composite:GPSLatitude", latitude)
+	tags.set("Composite:GPSLongitude", longitude)
+	tags.set("Composite:GPSPosition", latitude+", "+longitude)
+
+	if alt := g.Altitude(); alt != 0 {
+		altRef := "Above Sea Level"
+		if alt < 0 {
+			altRef = "Below Sea Level"
+		}
+		altitude := strconv.FormatFloat(math.Abs(float64(alt)), 'f', -1, 32) + " m"
+		tags.set("EXIF:GPSAltitudeRef", altRef)
+		tags.set("EXIF:GPSAltitude", altitude)
+		tags.set("Composite:GPSAltitude", altitude+" "+altRef)
+	}
+	if d := g.Date(); !d.IsZero() {
+		d = d.UTC()
+		tags.set("EXIF:GPSDateStamp", d.Format("2006:01:02"))
+		tags.set("EXIF:GPSTimeStamp", d.Format("15:04:05"))
+		tags.set("Composite:GPSDateTime", d.Format(exiftoolDateLayout)+"Z")
+	}
+}
+
+func (tags ExiftoolTags) xmp(x xmp.XMP) {
+	tags.setList("XMP-dc:Creator", x.DC.Creator)
+	tags.setList("XMP-dc:Title", x.DC.Title)
+	tags.setList("XMP-dc:Description", x.DC.Description)
+	tags.setList("XMP-dc:Rights", x.DC.Rights)
+	tags.setList("XMP-dc:Subject", x.DC.Subject)
+
+	tags.set("XMP-xmp:CreatorTool", x.Basic.CreatorTool)
+	tags.set("XMP-xmp:Label", x.Basic.Label)
+	if x.Basic.Rating != 0 {
+		tags.set("XMP-xmp:Rating", strconv.Itoa(int(x.Basic.Rating)))
+	}
+	tags.xmpDate("XMP-xmp:CreateDate", x.Basic.CreateDate)
+	tags.xmpDate("XMP-xmp:ModifyDate", x.Basic.ModifyDate)
+	tags.xmpDate("XMP-xmp:MetadataDate", x.Basic.MetadataDate)
+
+	tags.set("XMP-aux:SerialNumber", x.Aux.SerialNumber)
+	tags.set("XMP-aux:LensInfo", x.Aux.LensInfo)
+	tags.set("XMP-aux:Lens", x.Aux.Lens)
+	tags.set("XMP-aux:LensSerialNumber", x.Aux.LensSerialNumber)
+
+	tags.set("XMP-tiff:Make", x.Tiff.Make)
+	tags.set("XMP-tiff:Model", x.Tiff.Model)
+
+	tags.xmpDate("XMP-exif:DateTimeOriginal", x.Exif.DateTimeOriginal)
+}
+
+func (tags ExiftoolTags) xmpDate(key string, t time.Time) {
+	if !t.IsZero() {
+		tags.set(key, t.Format(exiftoolXMPDateLayout))
+	}
+}
+
+// exiftoolOrientation is the print conversion of the Orientation tag.
+func exiftoolOrientation(o meta.Orientation) string {
+	switch o {
+	case 0:
+		return ""
+	case meta.OrientationHorizontal:
+		return "Horizontal (normal)"
+	}
+	return o.String()
+}
+
+// exiftoolResolutionUnit is the print conversion of the ResolutionUnit tag.
+func exiftoolResolutionUnit(u uint16) string {
+	switch u {
+	case 1:
+		return "None"
+	case 2:
+		return "inches"
+	case 3:
+		return "cm"
+	}
+	return ""
+}
+
+// exiftoolColorSpace is the print conversion of the ColorSpace tag.
+func exiftoolColorSpace(cs exif2.ColorSpace) string {
+	switch cs {
+	case 1:
+		return "sRGB"
+	case 2:
+		return "Adobe RGB"
+	case 0xffff:
+		return "Uncalibrated"
+	}
+	return ""
+}
+
+// exiftoolExposureTime is the print conversion of exposure times: "1/250" or "0.3", "2".
+func exiftoolExposureTime(et meta.ExposureTime) string {
+	secs := float64(et)
+	if secs <= 0 || math.IsNaN(secs) {
+		return ""
+	}
+	if secs < 0.25001 {
+		return fmt.Sprintf("1/%d", int(0.5+1/secs))
+	}
+	return strconv.FormatFloat(math.Round(secs*10)/10, 'f', -1, 64)
+}
+
+// exiftoolExposureBias is the print conversion of fractions: "0", "+1", "-1/2" or "+2/3".
+func exiftoolExposureBias(eb meta.ExposureBias) string {
+	d := int16(uint16(eb) << 8 >> 8)
+	if d == 0 {
+		return ""
+	}
+	val := float64(eb>>8) / float64(d) * 1.00001
+	switch {
+	case val == 0:
+		return "0"
+	case exiftoolIsInt(val):
+		return fmt.Sprintf("%+d", int(val))
+	case exiftoolIsInt(val * 2):
+		return fmt.Sprintf("%+d/2", int(val*2))
+	case exiftoolIsInt(val * 3):
+		return fmt.Sprintf("%+d/3", int(val*3))
+	}
+	return fmt.Sprintf("%+.3g", val)
+}
+
+// exiftoolIsInt returns true when val is within 0.1% of an integer.
+func exiftoolIsInt(val float64) bool {
+	return float64(int(val))/val > 0.999
+}
+
+// exiftoolLensInfo is the print conversion of the LensInfo tag: "24-105mm f/4".
+func exiftoolLensInfo(li exif2.LensInfo) string {
+	rational := func(n, d uint32) float64 {
+		if d == 0 {
+			return 0
+		}
+		return float64(n) / float64(d)
+	}
+	minFocal, maxFocal := rational(li[0], li[1]), rational(li[2], li[3])
+	minAperture, maxAperture := rational(li[4], li[5]), rational(li[6], li[7])
+	if minFocal == 0 {
+		return ""
+	}
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	str := format(minFocal)
+	if maxFocal != 0 && maxFocal != minFocal {
+		str += "-" + format(maxFocal)
+	}
+	str += "mm f/"
+	if minAperture == 0 {
+		return str + "?"
+	}
+	str += format(minAperture)
+	if maxAperture != 0 && maxAperture != minAperture {
+		str += "-" + format(maxAperture)
+	}
+	return str
+}
+
+// exiftoolCanonSerialNumber is the print conversion of the Canon SerialNumber tag, it
+// depends on the camera model: "0123456789", "012345" for EOS-1D models or "a-01234" for the EOS D30.
+func exiftoolCanonSerialNumber(sn uint32, model string) string {
+	switch {
+	case sn == 0:
+		return ""
+	case strings.Contains(model, "EOS D30"):
+		return fmt.Sprintf("%x-%.5d", sn>>16, sn&0xffff)
+	case strings.Contains(model, "EOS-1D"):
+		return fmt.Sprintf("%.6d", sn)
+	}
+	return fmt.Sprintf("%.10d", sn)
+}
+
+// exiftoolNikonVersion is the print conversion of the Nikon MakerNoteVersion tag: "0211" is "2.11".
+func exiftoolNikonVersion(v string) string {
+	if len(v) < 3 {
+		return v
+	}
+	return strings.TrimPrefix(v[:2]+"."+v[2:], "0")
+}
+
+// exiftoolCoordinate is the print conversion of a GPS coordinate: 33 deg 52' 7.68" and its reference.
+func exiftoolCoordinate(coord float64, positive, negative string) (string, string) {
+	ref := positive
+	if coord < 0 {
+		ref, coord = negative, -coord
+	}
+	deg := math.Floor(coord)
+	min := math.Floor((coord - deg) * 60)
+	sec := (coord - deg - min/60) * 3600
+	return fmt.Sprintf("%d deg %d' %.2f\"", int(deg), int(min), sec), ref
+}
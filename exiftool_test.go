@@ -0,0 +1,170 @@
+package imagemeta
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/xmp"
+)
+
+func TestExiftool(t *testing.T) {
+	var e exif2.Exif
+	e.Make = "Canon"
+	e.Model = "Canon EOS 6D"
+	e.Orientation = meta.OrientationHorizontal
+	e.ExposureTime = 1.0 / 250
+	e.FNumber = 8
+	e.FocalLength = 50
+	e.FocalLengthIn35mmFormat = 75
+	e.ISOSpeed = 400
+	e.ExposureBias = meta.NewExposureBias(-2, 3)
+	e.MeteringMode = meta.MeteringModeMultisegment
+	e.LensInfo = exif2.LensInfo{24, 1, 105, 1, 4, 1, 4, 1}
+	e.ColorSpace = 1
+	e.SetDateTimeOriginal(time.Date(2023, 6, 15, 10, 30, 45, 0, time.FixedZone("", -5*60*60)))
+	e.GPS.SetLatitude(-33.8688)
+	e.GPS.SetLongitude(151.2093)
+	e.GPS.SetAltitude(58)
+	var x xmp.XMP
+	x.DC.Creator = []string{"Jane Doe"}
+	x.DC.Subject = []string{"beach", "sunset"}
+	x.Basic.Rating = 5
+
+	m := Metadata{Exif: e, XMP: x, ImageType: imagetype.ImageJPEG, Dimensions: meta.NewDimensions(5472, 3648)}
+	buf, err := json.Marshal(Exiftool(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags map[string]interface{}
+	if err = json.Unmarshal(buf, &tags); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]interface{}{
+		"File:MIMEType":                "image/jpeg",
+		"Composite:ImageSize":          "5472x3648",
+		"EXIF:Make":                    "Canon",
+		"EXIF:Orientation":             "Horizontal (normal)",
+		"EXIF:ExposureTime":            "1/250",
+		"EXIF:FNumber":                 8.0,
+		"EXIF:FocalLength":             "50.0 mm",
+		"EXIF:FocalLengthIn35mmFormat": "75 mm",
+		"EXIF:ISO":                     400.0,
+		"EXIF:ExposureCompensation":    "-2/3",
+		"EXIF:MeteringMode":            "Multi-segment",
+		"EXIF:LensInfo":                "24-105mm f/4",
+		"EXIF:ColorSpace":              "sRGB",
+		"EXIF:DateTimeOriginal":        "2023:06:15 10:30:45",
+		"EXIF:OffsetTimeOriginal":      "-05:00",
+		"EXIF:GPSLatitude":             `33 deg 52' 7.68"`,
+		"EXIF:GPSLatitudeRef":          "South",
+		"EXIF:GPSAltitude":             "58 m",
+		"Composite:GPSPosition":        `33 deg 52' 7.68" S, 151 deg 12' 33.48" E`,
+		"XMP-dc:Creator":               "Jane Doe",
+		"XMP-xmp:Rating":               5.0,
+		"EXIF:ExifImageWidth":          nil,
+		"EXIF:Flash":                   nil,
+		"EXIF:OffsetTime":              nil,
+		"Composite:GPSDateTime":        nil,
+		"XMP-aux:Lens":                 nil,
+	}
+	for key, wanted := range tests {
+		if got := tags[key]; got != wanted {
+			t.Errorf("Incorrect %s wanted %v got %v", key, wanted, got)
+		}
+	}
+	if subject, ok := tags["XMP-dc:Subject"].([]interface{}); !ok || len(subject) != 2 {
+		t.Errorf("Incorrect XMP-dc:Subject wanted %v got %v", x.DC.Subject, tags["XMP-dc:Subject"])
+	}
+}
+
+func TestExiftoolMakerNotes(t *testing.T) {
+	makerNotesTests := []struct {
+		filename string
+		wanted   map[string]interface{}
+	}{
+		{"testImages/CR2.exif", map[string]interface{}{
+			"MakerNotes:CanonImageType":       "Canon EOS-1Ds Mark III",
+			"MakerNotes:CanonFirmwareVersion": "Firmware Version 2.1.2",
+			"MakerNotes:SerialNumber":         600005.0,
+			"MakerNotes:LensModel":            "EF50mm f/1.2L USM",
+			"MakerNotes:InternalSerialNumber": "I01328",
+			"MakerNotes:OwnerName":            nil,
+		}},
+		{"testImages/NEF.exif", map[string]interface{}{
+			"MakerNotes:MakerNoteVersion": 2.11,
+			"MakerNotes:ISO":              100.0,
+			"MakerNotes:Quality":          "RAW",
+			"MakerNotes:WhiteBalance":     "SUNNY",
+			"MakerNotes:FocusMode":        "MANUAL",
+			"MakerNotes:SerialNumber":     7302381.0,
+		}},
+		{"testImages/ARW.exif", map[string]interface{}{
+			"MakerNotes:SerialNumber": nil,
+		}},
+	}
+	for _, mt := range makerNotesTests {
+		t.Run(mt.filename, func(t *testing.T) {
+			f, err := os.Open(mt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			m, err := DecodeAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := json.Marshal(Exiftool(m))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tags map[string]interface{}
+			if err = json.Unmarshal(buf, &tags); err != nil {
+				t.Fatal(err)
+			}
+			for key, wanted := range mt.wanted {
+				if got := tags[key]; got != wanted {
+					t.Errorf("Incorrect %s wanted %v got %v", key, wanted, got)
+				}
+			}
+		})
+	}
+
+	// The SerialNumber of a Canon makernote depends on the camera model
+	serialNumbers := map[string]string{"Canon EOS 6D": "0012345678", "Canon EOS-1D X": "12345678", "Canon EOS D30": "bc-24910"}
+	for model, wanted := range serialNumbers {
+		if got := exiftoolCanonSerialNumber(12345678, model); got != wanted {
+			t.Errorf("Incorrect SerialNumber of %s wanted %q got %q", model, wanted, got)
+		}
+	}
+}
+
+func TestExiftoolValues(t *testing.T) {
+	exposureTimes := map[meta.ExposureTime]string{1.0 / 4000: "1/4000", 0.3: "0.3", 2: "2", 1.3: "1.3", 0: ""}
+	for et, wanted := range exposureTimes {
+		if got := exiftoolExposureTime(et); got != wanted {
+			t.Errorf("Incorrect ExposureTime wanted %q got %q", wanted, got)
+		}
+	}
+	exposureBiases := map[meta.ExposureBias]string{
+		meta.NewExposureBias(0, 1):  "0",
+		meta.NewExposureBias(1, 1):  "+1",
+		meta.NewExposureBias(-1, 2): "-1/2",
+		meta.NewExposureBias(1, 3):  "+1/3",
+		meta.NewExposureBias(6, 3):  "+2",
+		0:                           "",
+	}
+	for eb, wanted := range exposureBiases {
+		if got := exiftoolExposureBias(eb); got != wanted {
+			t.Errorf("Incorrect ExposureCompensation of %s wanted %q got %q", eb, wanted, got)
+		}
+	}
+	if got := exiftoolLensInfo(exif2.LensInfo{50, 1, 50, 1, 18, 10, 18, 10}); got != "50mm f/1.8" {
+		t.Errorf("Incorrect LensInfo wanted %q got %q", "50mm f/1.8", got)
+	}
+}
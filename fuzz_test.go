@@ -0,0 +1,24 @@
+package imagemeta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzDecodeAll(f *testing.F) {
+	files, _ := filepath.Glob("testImages/*")
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DecodeAll(bytes.NewReader(data))
+		_, _ = Previews(bytes.NewReader(data))
+		_, _ = Thumbnail(bytes.NewReader(data))
+	})
+}
@@ -1,13 +1,12 @@
 module github.com/tdelov/imagemeta
 
-go 1.20
+go 1.21
 
 require (
 	github.com/golang/geo v0.0.0-20210211234256-740aa86cb551
 	github.com/klauspost/cpuid/v2 v2.2.4
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/pkg/errors v0.9.1
-	github.com/rs/zerolog v1.29.0
 	github.com/stretchr/testify v1.8.1
 	github.com/tidwall/pretty v1.2.1
 	github.com/tinylib/msgp v1.1.8
@@ -15,8 +14,6 @@ require (
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.17 // indirect
 	github.com/philhofer/fwd v1.1.2 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
@@ -0,0 +1,168 @@
+package imagemeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/xmp"
+)
+
+// update regenerates the golden snapshots: go test -run TestGolden -update
+var update = flag.Bool("update", false, "regenerate the golden snapshots of testdata/golden")
+
+// goldenSnapshot is the snapshot of the decoded metadata of a file of testImages.
+type goldenSnapshot struct {
+	File       string
+	ImageType  string
+	ScanError  string          `json:",omitempty"`
+	Dimensions meta.Dimensions `json:",omitempty"`
+	Exif       *exif2.Exif     `json:",omitempty"`
+	XMP        *xmp.XMP        `json:",omitempty"`
+	ICCProfile int             `json:",omitempty"`
+	Previews   []PreviewImage  `json:",omitempty"`
+	Warnings   []string        `json:",omitempty"`
+	Error      string          `json:",omitempty"`
+}
+
+// newGoldenSnapshot decodes the image type, metadata and preview images of buf.
+func newGoldenSnapshot(name string, buf []byte) goldenSnapshot {
+	s := goldenSnapshot{File: name}
+	it, err := imagetype.Scan(bytes.NewReader(buf))
+	s.ImageType = it.String()
+	if err != nil {
+		s.ScanError = err.Error()
+	}
+
+	if it == imagetype.ImageXMP {
+		x, err := xmp.ParseXmp(bytes.NewReader(buf))
+		if err != nil {
+			s.Error = err.Error()
+		}
+		s.XMP = &x
+		return s
+	}
+
+	m, err := DecodeAll(bytes.NewReader(buf))
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.Dimensions, s.Exif, s.ICCProfile = m.Dimensions, &m.Exif, len(m.ICCProfile)
+	if !reflect.ValueOf(m.XMP).IsZero() {
+		s.XMP = &m.XMP
+	}
+	for _, w := range m.Warnings {
+		s.Warnings = append(s.Warnings, w.Error())
+	}
+	// Images without preview images are not an error
+	s.Previews, _ = Previews(bytes.NewReader(buf))
+	return s
+}
+
+// TestGolden compares the decoded metadata of each image of testImages with
+// its golden snapshot in testdata/golden, the snapshot is of DecodeAll and the Exif
+// of DecodeReaderAt and DecodeBytes must be equal to its Exif. The JSON sidecar files
+// of testImages are not images and are skipped.
+func TestGolden(t *testing.T) {
+	files, err := os.ReadDir("testImages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err = os.MkdirAll(filepath.Join("testdata", "golden"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, f := range files {
+		if !f.Type().IsRegular() || filepath.Ext(f.Name()) == ".json" {
+			continue
+		}
+		name := f.Name()
+		t.Run(name, func(t *testing.T) {
+			buf, err := os.ReadFile(filepath.Join("testImages", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := newGoldenSnapshot(name, buf)
+			got, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			testGoldenDecoders(t, name, buf, s.Exif)
+
+			golden := filepath.Join("testdata", "golden", name+".json")
+			if *update {
+				if err = os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			wanted, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v: regenerate the golden snapshots with -update", err)
+			}
+			if !bytes.Equal(got, wanted) {
+				t.Errorf("Incorrect snapshot of %s, regenerate with -update if the change is intended:\n%s", name, goldenDiff(string(wanted), string(got)))
+			}
+		})
+	}
+}
+
+// testGoldenDecoders compares the Exif of DecodeReaderAt and DecodeBytes with the
+// Exif of DecodeAll of the golden snapshot of name.
+func testGoldenDecoders(t *testing.T, name string, buf []byte, exif *exif2.Exif) {
+	if exif == nil {
+		return
+	}
+	wanted, err := json.MarshalIndent(exif, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func() (exif2.Exif, error){
+		"DecodeReaderAt": func() (exif2.Exif, error) { return DecodeReaderAt(bytes.NewReader(buf)) },
+		"DecodeBytes":    func() (exif2.Exif, error) { return DecodeBytes(buf) },
+	}
+	for decoder, decode := range decoders {
+		e, err := decode()
+		if err != nil {
+			t.Errorf("Incorrect %s error of %s: %v", decoder, name, err)
+			continue
+		}
+		got, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, wanted) {
+			t.Errorf("Incorrect %s Exif of %s wanted the Exif of DecodeAll:\n%s", decoder, name, goldenDiff(string(wanted), string(got)))
+		}
+	}
+}
+
+// goldenDiff returns the lines of wanted and got that differ.
+func goldenDiff(wanted, got string) string {
+	w, g := strings.Split(wanted, "\n"), strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			sb.WriteString("- " + wl + "\n+ " + gl + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -4,18 +4,23 @@ package imagemeta
 
 import (
 	"bufio"
-
+	"bytes"
+	"context"
 	"io"
+	"log/slog"
+	"math"
 	"sync"
 
 	"github.com/tdelov/imagemeta/exif2"
 	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/internal/logging"
 	"github.com/tdelov/imagemeta/isobmff"
 	"github.com/tdelov/imagemeta/jpeg"
 	"github.com/tdelov/imagemeta/meta"
 	"github.com/tdelov/imagemeta/png"
 	"github.com/tdelov/imagemeta/preview"
 	"github.com/tdelov/imagemeta/tiff"
+	"github.com/tdelov/imagemeta/xmp"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +31,8 @@ var (
 	ErrNoXmpDecodeFn        = errors.New("error no Xmp Decode Func set")
 	ErrImageTypeNotFound    = imagetype.ErrImageTypeNotFound
 	ErrMetadataNotSupported = errors.New("error metadata reading not supported for this imagetype")
+	ErrNoThumbnail          = errors.New("error no thumbnail image")
+	ErrNoPreview            = errors.New("error no preview image")
 )
 
 // readerPool for buffer
@@ -33,25 +40,55 @@ var readerPool = sync.Pool{
 	New: func() interface{} { return bufio.NewReaderSize(nil, 4*1024) },
 }
 
+// Decode decodes the Exif metadata from an io.ReadSeeker. Supports JPEG, TIFF, Camera Raw,
+// HEIF, AVIF and CR3 images.
 func Decode(r io.ReadSeeker) (exif2.Exif, error) {
+	return DecodeWithOptions(r)
+}
+
+// DecodeWithOptions decodes the Exif metadata from an io.ReadSeeker with the given options.
+func DecodeWithOptions(r io.ReadSeeker, opts ...Option) (exif2.Exif, error) {
+	return DecodeContext(context.Background(), r, opts...)
+}
+
+// DecodeContext decodes the Exif metadata from an io.ReadSeeker with the given options.
+// The context is checked between JPEG markers, IFDs and ISOBMFF boxes and ctx.Err()
+// is returned when the context is done.
+func DecodeContext(ctx context.Context, r io.ReadSeeker, opts ...Option) (e exif2.Exif, err error) {
+	// Only the blocks of a RangeReader that hold metadata are fetched
+	if ra, ok := r.(*RangeReader); ok {
+		return decodeReaderAt(ctx, ra.withContext(ctx), opts)
+	}
+	o := newOptions(opts)
 	rr := readerPool.Get().(*bufio.Reader)
 	rr.Reset(r)
 	defer readerPool.Put(rr)
 
-	ir := exif2.NewIfdReader(exif2.Logger)
+	ir := exif2.NewIfdReader(o.exifLogger())
 	defer ir.Close()
+	ir.SetMaxLength(o.maxBytes)
+	ir.SetSkipMakerNotes(o.skipMakerNotes)
+	ir.SetSkipGPS(o.skipGPS)
+	ir.SetContext(ctx)
 
 	it, err := imagetype.ScanBuf(rr)
 	if err != nil {
 		return exif2.Exif{}, err
 	}
+	defer func() {
+		if it.IsRegistered() {
+			e.ImageType = it
+		}
+		err = meta.ImageTypeError(err, it)
+	}()
 	ir.Exif.ImageType = it
-	switch it {
+	switch decodeType(it) {
 	case imagetype.ImageJPEG:
-		if err = jpeg.ScanJPEG(rr, ir.DecodeJPEGIfd, nil); err != nil {
+		s := jpeg.Scanner{ExifReader: ir.DecodeJPEGIfd, Logger: o.logger}
+		if _, err = s.ScanContext(ctx, rr); err != nil {
 			return exif2.Exif{}, err
 		}
-	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageDNG:
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR:
 		header, err := tiff.ScanTiffHeader(rr, it)
 		if err != nil {
 			return exif2.Exif{}, err
@@ -59,18 +96,24 @@ func Decode(r io.ReadSeeker) (exif2.Exif, error) {
 		if err := ir.DecodeTiff(rr, header); err != nil {
 			return ir.Exif, err
 		}
-	case imagetype.ImageCR3, imagetype.ImageAVIF:
+	case imagetype.ImageCR3, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
 		bmr := isobmff.NewReader(rr)
 		defer bmr.Close()
+		if o.logger != nil {
+			bmr.Logger = o.logger
+		}
+		bmr.SetContext(ctx)
 		bmr.ExifReader = ir.DecodeIfd
 		if err := bmr.ReadFTYP(); err != nil {
 			return ir.Exif, errors.Wrapf(err, "ReadFtypBox")
 		}
-		if err := bmr.ReadMetadata(); err != nil {
+		err := bmr.ReadMetadata()
+		ir.Exif.Warnings = append(ir.Exif.Warnings, bmr.Warnings()...)
+		if err != nil {
 			return ir.Exif, err
 		}
 
-	case imagetype.ImageHEIF:
+	case imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence:
 		header, err := tiff.ScanTiffHeader(rr, it)
 		if err != nil {
 			return exif2.Exif{}, err
@@ -85,6 +128,310 @@ func Decode(r io.ReadSeeker) (exif2.Exif, error) {
 	return ir.Exif, nil
 }
 
+// DecodeReaderAt decodes the Exif metadata from an io.ReaderAt with the given options.
+// IFDs and tag values are read at their offsets in any order, this recovers the metadata
+// that is skipped by Decode when an offset is before the IFD that references it.
+// Supports JPEG, TIFF, Camera Raw, HEIF, AVIF and CR3 images.
+func DecodeReaderAt(r io.ReaderAt, opts ...Option) (e exif2.Exif, err error) {
+	return decodeReaderAt(context.Background(), r, opts)
+}
+
+// decodeReaderAt decodes the Exif metadata from an io.ReaderAt, the context is checked
+// between JPEG markers, IFDs and ISOBMFF boxes.
+func decodeReaderAt(ctx context.Context, r io.ReaderAt, opts []Option) (e exif2.Exif, err error) {
+	o := newOptions(opts)
+	rr := readerPool.Get().(*bufio.Reader)
+	rr.Reset(io.NewSectionReader(r, 0, math.MaxInt64))
+	defer readerPool.Put(rr)
+
+	ir := exif2.NewIfdReader(o.exifLogger())
+	defer ir.Close()
+	ir.SetMaxLength(o.maxBytes)
+	ir.SetSkipMakerNotes(o.skipMakerNotes)
+	ir.SetSkipGPS(o.skipGPS)
+	ir.SetContext(ctx)
+
+	it, err := imagetype.ScanBuf(rr)
+	if err != nil {
+		return exif2.Exif{}, err
+	}
+	defer func() {
+		if it.IsRegistered() {
+			e.ImageType = it
+		}
+		err = meta.ImageTypeError(err, it)
+	}()
+	ir.Exif.ImageType = it
+	switch decodeType(it) {
+	case imagetype.ImageJPEG:
+		exifReader := func(er io.Reader, h meta.ExifHeader) error {
+			if err := ir.DecodeReaderAt(r, h); err != nil {
+				return err
+			}
+			// Discard the Exif segment from the JPEG scan
+			_, err := io.CopyN(io.Discard, er, int64(h.ExifLength))
+			return err
+		}
+		s := jpeg.Scanner{ExifReader: exifReader, Logger: o.logger}
+		if _, err = s.ScanContext(ctx, rr); err != nil {
+			return exif2.Exif{}, err
+		}
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR, imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence:
+		header, err := tiff.ScanTiffHeader(rr, it)
+		if err != nil {
+			return exif2.Exif{}, err
+		}
+		if err := ir.DecodeReaderAt(r, header); err != nil {
+			return ir.Exif, err
+		}
+	case imagetype.ImageCR3, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
+		bmr := isobmff.NewReader(rr)
+		defer bmr.Close()
+		if o.logger != nil {
+			bmr.Logger = o.logger
+		}
+		bmr.SetContext(ctx)
+		bmr.ExifReader = func(er io.Reader, h meta.ExifHeader) error {
+			// The 8 byte Tiff header has been read by the isobmff.Reader.
+			// The ExifLength is untrusted and only preallocated up to 64KB.
+			buf := bytes.NewBuffer(make([]byte, 8, 8+min(h.ExifLength, 1<<16)))
+			if _, err := buf.ReadFrom(er); err != nil {
+				return err
+			}
+			h.TiffHeaderOffset = 0
+			return ir.DecodeReaderAt(bytes.NewReader(buf.Bytes()), h)
+		}
+		if err := bmr.ReadFTYP(); err != nil {
+			return ir.Exif, errors.Wrapf(err, "ReadFtypBox")
+		}
+		err := bmr.ReadMetadata()
+		ir.Exif.Warnings = append(ir.Exif.Warnings, bmr.Warnings()...)
+		if err != nil {
+			return ir.Exif, err
+		}
+	default:
+		return exif2.Exif{}, ErrMetadataNotSupported
+	}
+	return ir.Exif, nil
+}
+
+// DecodeBytes decodes the Exif metadata of an image that is held in memory with the given
+// options. The image is not copied through a bufio.Reader, IFDs and tag values are read at
+// their offsets like DecodeReaderAt and are sliced directly from buf.
+// Supports JPEG, TIFF, Camera Raw, PNG, HEIF, AVIF and CR3 images.
+func DecodeBytes(buf []byte, opts ...Option) (e exif2.Exif, err error) {
+	o := newOptions(opts)
+	ir := exif2.NewIfdReader(o.exifLogger())
+	defer ir.Close()
+	ir.SetMaxLength(o.maxBytes)
+	ir.SetSkipMakerNotes(o.skipMakerNotes)
+	ir.SetSkipGPS(o.skipGPS)
+
+	it, err := imagetype.Buf(buf)
+	if err != nil {
+		return exif2.Exif{}, err
+	}
+	defer func() {
+		if it.IsRegistered() {
+			e.ImageType = it
+		}
+		err = meta.ImageTypeError(err, it)
+	}()
+	ir.Exif.ImageType = it
+	var header meta.ExifHeader
+	switch decodeType(it) {
+	case imagetype.ImageJPEG:
+		if header, err = jpeg.ExifHeaderBytes(buf); err == meta.ErrNoExif {
+			// A JPEG image without Exif is not an error
+			return ir.Exif, nil
+		}
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR, imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence:
+		header, err = tiff.ScanTiffHeaderBytes(buf, it)
+	case imagetype.ImagePNG:
+		header, err = png.ScanPngHeader(bytes.NewReader(buf))
+	case imagetype.ImageCR3, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
+		// The boxes are read from buf, the Exif is decoded at its offset in buf
+		bmr := isobmff.NewReader(bytes.NewReader(buf))
+		defer bmr.Close()
+		if o.logger != nil {
+			bmr.Logger = o.logger
+		}
+		bmr.ExifReader = func(_ io.Reader, h meta.ExifHeader) error {
+			return ir.DecodeBytes(buf, h)
+		}
+		if err := bmr.ReadFTYP(); err != nil {
+			return ir.Exif, errors.Wrapf(err, "ReadFtypBox")
+		}
+		err := bmr.ReadMetadata()
+		ir.Exif.Warnings = append(ir.Exif.Warnings, bmr.Warnings()...)
+		return ir.Exif, err
+	default:
+		return exif2.Exif{}, ErrMetadataNotSupported
+	}
+	if err != nil {
+		return exif2.Exif{}, err
+	}
+	if err = ir.DecodeBytes(buf, header); err != nil {
+		return ir.Exif, err
+	}
+	return ir.Exif, nil
+}
+
+// Metadata is the metadata of an image decoded with DecodeAll.
+type Metadata struct {
+	Exif       exif2.Exif
+	XMP        xmp.XMP
+	ICCProfile []byte          // raw ICC Profile
+	Dimensions meta.Dimensions // dimensions of the primary image
+	ImageType  imagetype.ImageType
+
+	// Warnings are the non-fatal errors that occurred during decoding,
+	// including the Warnings of Exif and errors decoding XMP.
+	Warnings []error
+}
+
+// DecodeAll decodes the Exif, XMP, ICC Profile and the dimensions of the primary image
+// from an io.ReadSeeker in a single scan. Supports JPEG, PNG, TIFF, Camera Raw, HEIF, AVIF and CR3 images.
+//
+// Tag values that are before the IFD that references them are skipped by the scan. When r is an
+// io.ReaderAt the Exif of JPEG and Tiff images is then decoded again with random access like
+// DecodeReaderAt, otherwise the skipped tags are Warnings wrapping exif2.ErrReverseTagOffset.
+func DecodeAll(r io.ReadSeeker) (Metadata, error) {
+	return DecodeAllContext(context.Background(), r)
+}
+
+// DecodeAllContext is DecodeAll with a context. The context is checked between
+// JPEG markers, PNG chunks, IFDs and ISOBMFF boxes and ctx.Err() is returned when the context is done.
+func DecodeAllContext(ctx context.Context, r io.ReadSeeker) (m Metadata, err error) {
+	rr := readerPool.Get().(*bufio.Reader)
+	rr.Reset(r)
+	defer readerPool.Put(rr)
+
+	ir := exif2.NewIfdReader(exif2.Logger)
+	defer ir.Close()
+	ir.SetContext(ctx)
+
+	xmpReader := func(r io.Reader) (err error) {
+		if m.XMP, err = xmp.ParseXmp(r); err != nil {
+			// XMP errors are not fatal to decoding the remaining metadata
+			logging.NewEvent(logger, slog.LevelError).Err(err).Msg("error decoding XMP")
+			m.Warnings = append(m.Warnings, errors.Wrap(err, "XMP"))
+		}
+		return nil
+	}
+	iccReader := func(r io.Reader) (err error) {
+		buf := bytes.NewBuffer(m.ICCProfile)
+		_, err = buf.ReadFrom(r)
+		m.ICCProfile = buf.Bytes()
+		return err
+	}
+
+	// exifHeader is the header of the Exif of a JPEG or Tiff image that is
+	// decoded again with random access when tag values were skipped.
+	var exifHeader meta.ExifHeader
+	var tiffValues bool
+
+	if m.ImageType, err = imagetype.ScanBuf(rr); err != nil {
+		return m, err
+	}
+	defer func() { err = meta.ImageTypeError(err, m.ImageType) }()
+	ir.Exif.ImageType = m.ImageType
+	switch decodeType(m.ImageType) {
+	case imagetype.ImageJPEG:
+		exifReader := func(er io.Reader, h meta.ExifHeader) error {
+			exifHeader = h
+			return ir.DecodeJPEGIfd(er, h)
+		}
+		s := jpeg.Scanner{ExifReader: exifReader, XMPReader: xmpReader, ICCReader: iccReader}
+		if m.Dimensions, err = s.ScanContext(ctx, rr); err != nil {
+			return m, err
+		}
+	case imagetype.ImagePNG:
+		// PNG chunks are read directly from the io.ReadSeeker
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return m, err
+		}
+		s := png.Scanner{ExifReader: ir.DecodeTiff, XMPReader: xmpReader, ICCReader: iccReader}
+		if m.Dimensions, err = s.ScanContext(ctx, r); err != nil {
+			return m, err
+		}
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR:
+		ir.SetXMPReader(xmpReader)
+		ir.SetICCReader(iccReader)
+		header, err := tiff.ScanTiffHeader(rr, m.ImageType)
+		if err != nil {
+			return m, err
+		}
+		exifHeader, tiffValues = header, true
+		if err = ir.DecodeTiff(rr, header); err != nil {
+			return m, err
+		}
+		if !m.ImageType.IsRegistered() {
+			m.ImageType = ir.Exif.ImageType
+		}
+	case imagetype.ImageCR3, imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
+		bmr := isobmff.NewReader(rr)
+		defer bmr.Close()
+		bmr.ExifReader = ir.DecodeIfd
+		bmr.XMPReader = xmpReader
+		bmr.ICCReader = iccReader
+		bmr.SetContext(ctx)
+		if err = bmr.ReadFTYP(); err != nil {
+			return m, errors.Wrapf(err, "ReadFtypBox")
+		}
+		// All of the compatible brands of the ftyp box
+		if it := bmr.FileType().ImageType; !it.IsUnknown() && !m.ImageType.IsRegistered() {
+			m.ImageType = it
+		}
+		err = bmr.ReadAll()
+		m.Warnings = append(m.Warnings, bmr.Warnings()...)
+		if err != nil {
+			return m, err
+		}
+		m.Dimensions = bmr.Dimensions()
+		ir.Exif.ImageType = m.ImageType
+	default:
+		return m, ErrMetadataNotSupported
+	}
+	if ra, ok := r.(io.ReaderAt); ok && exifHeader.IsValid() && hasReverseTagOffset(ir.Exif.Warnings) {
+		// The streaming decode skipped the tag values before the reader offset,
+		// the Exif is decoded again with random access.
+		rir := exif2.NewIfdReader(exif2.Logger)
+		defer rir.Close()
+		rir.SetContext(ctx)
+		if tiffValues {
+			// XMP and ICC Profile tag values of a Tiff image are decoded again
+			m.XMP, m.ICCProfile, m.Warnings = xmp.XMP{}, nil, nil
+			rir.SetXMPReader(xmpReader)
+			rir.SetICCReader(iccReader)
+		}
+		if err = rir.DecodeReaderAt(ra, exifHeader); err != nil {
+			return m, err
+		}
+		ir = rir
+	}
+	m.Exif = ir.Exif
+	if m.ImageType.IsRegistered() {
+		m.Exif.ImageType = m.ImageType
+	}
+	m.Warnings = append(m.Warnings, m.Exif.Warnings...)
+	if m.Dimensions == (meta.Dimensions{}) {
+		m.Dimensions = ir.Dimensions()
+	}
+	return m, nil
+}
+
+// hasReverseTagOffset returns true if a tag value was skipped because it is before the
+// offset of the streaming decoder.
+func hasReverseTagOffset(warnings []error) bool {
+	for _, w := range warnings {
+		if errors.Is(w, exif2.ErrReverseTagOffset) {
+			return true
+		}
+	}
+	return false
+}
+
 // DecodeCR3 decodes a CR3 file from an io.Reader returning Exif or an error.
 func DecodeCR3(r io.ReadSeeker) (exif2.Exif, error) {
 	rr := readerPool.Get().(*bufio.Reader)
@@ -218,3 +565,40 @@ func PreviewCR3(r io.ReadSeeker) ([]byte, error) {
 
 	return pr.PreviewImage, nil
 }
+
+// WritePreviewCR3 copies the preview image of a CR3 file from an io.Reader to w without
+// allocating the whole image. Returns the header of the preview image or an error.
+func WritePreviewCR3(w io.Writer, r io.ReadSeeker) (meta.PreviewHeader, error) {
+	rr := readerPool.Get().(*bufio.Reader)
+	defer readerPool.Put(rr)
+	rr.Reset(r)
+
+	pw := preview.NewPreviewWriter(w, preview.Logger)
+
+	// Errors of the PreviewImageReader are warnings of the isobmff.Reader
+	var writeErr error
+	bmr := isobmff.NewReader(rr)
+	bmr.PreviewImageReader = func(r io.Reader, h meta.PreviewHeader) error {
+		writeErr = pw.WritePreview(r, h)
+		return writeErr
+	}
+	defer bmr.Close()
+
+	if err := bmr.ReadFTYP(); err != nil {
+		return pw.Header, errors.Wrapf(err, "ReadFtypBox")
+	}
+
+	// moov, uuid xpacket and uuid preview
+	for i := 0; i < 3; i++ {
+		if err := bmr.ReadMetadata(); err != nil {
+			return pw.Header, err
+		}
+	}
+	if writeErr != nil {
+		return pw.Header, writeErr
+	}
+	if pw.Header.Size == 0 {
+		return pw.Header, ErrNoPreview
+	}
+	return pw.Header, nil
+}
@@ -0,0 +1,433 @@
+// Copyright (c) 2018-2023 Evan Oberholster. All rights reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package imagemeta
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	goimagepng "image/png"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/png"
+)
+
+func TestDecodeAll(t *testing.T) {
+	testImages := []struct {
+		filename   string
+		imageType  imagetype.ImageType
+		make       string
+		dimensions meta.Dimensions
+		xmp        bool
+		icc        bool
+	}{
+		{"testImages/ARW.exif", imagetype.ImageTiff, "Sony", meta.NewDimensions(4928, 3280), false, false},
+		{"testImages/CR2.exif", imagetype.ImageCR2, "Canon", meta.NewDimensions(5616, 3744), false, false},
+		{"testImages/Heic.exif", imagetype.ImageHEIC, "Canon", meta.NewDimensions(3648, 5472), false, false},
+		{"testImages/Hero8.GPR", imagetype.ImageDNG, "GoPro", meta.NewDimensions(4000, 3000), false, false},
+		{"testImages/JPEG.jpg", imagetype.ImageJPEG, "GoPro", meta.NewDimensions(1000, 563), true, true},
+		{"testImages/NoExif.jpg", imagetype.ImageJPEG, "", meta.NewDimensions(50, 50), false, true},
+		{"testImages/AVIF.avif", imagetype.ImageAVIF, "", meta.NewDimensions(1280, 720), false, false},
+		{"assets/a1.jpg", imagetype.ImageJPEG, "Canon", meta.NewDimensions(389, 259), false, false},
+	}
+	for _, ti := range testImages {
+		t.Run(ti.filename, func(t *testing.T) {
+			f, err := os.Open(ti.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			m, err := DecodeAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.ImageType != ti.imageType {
+				t.Errorf("Incorrect ImageType wanted %s got %s", ti.imageType, m.ImageType)
+			}
+			if m.Exif.Make != ti.make {
+				t.Errorf("Incorrect Make wanted %s got %s", ti.make, m.Exif.Make)
+			}
+			if m.Dimensions != ti.dimensions {
+				t.Errorf("Incorrect Dimensions wanted %s got %s", ti.dimensions, m.Dimensions)
+			}
+			if (m.XMP.Basic.CreatorTool != "") != ti.xmp {
+				t.Errorf("Incorrect XMP wanted %t got %q", ti.xmp, m.XMP.Basic.CreatorTool)
+			}
+			if (len(m.ICCProfile) > 0) != ti.icc {
+				t.Errorf("Incorrect ICC Profile wanted %t got %d bytes", ti.icc, len(m.ICCProfile))
+			}
+			if hasReverseTagOffset(m.Warnings) {
+				t.Errorf("Incorrect Warnings wanted the skipped tags to be decoded got %v", m.Warnings)
+			}
+		})
+	}
+
+	// The tag values of JPEG.jpg are before its IFD0, they are skipped without an io.ReaderAt
+	buf, err := os.ReadFile("testImages/JPEG.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := DecodeAll(struct{ io.ReadSeeker }{bytes.NewReader(buf)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Exif.Make != "" || !hasReverseTagOffset(m.Warnings) || m.XMP.Basic.CreatorTool == "" {
+		t.Errorf("Incorrect DecodeAll wanted %v got %q %v", exif2.ErrReverseTagOffset, m.Exif.Make, m.Warnings)
+	}
+}
+
+func TestDecodeAllNotSupported(t *testing.T) {
+	f, err := os.Open("testImages/GIF.gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = DecodeAll(f); err != ErrMetadataNotSupported {
+		t.Errorf("Incorrect error wanted %s got %v", ErrMetadataNotSupported, err)
+	}
+}
+
+func TestDecodeWithOptions(t *testing.T) {
+	decode := func(filename string, opts ...Option) exif2.Exif {
+		f, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		e, err := DecodeWithOptions(f, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+
+	if e := decode("testImages/ARW.exif"); e.GPS.Latitude() == 0 {
+		t.Errorf("Incorrect GPS Latitude wanted non-zero got %f", e.GPS.Latitude())
+	}
+	if e := decode("testImages/ARW.exif", SkipGPS()); e.GPS.Latitude() != 0 || e.Make != "Sony" {
+		t.Errorf("Incorrect SkipGPS got Latitude %f and Make %s", e.GPS.Latitude(), e.Make)
+	}
+	if e := decode("testImages/NEF.exif", SkipMakerNotes()); e.ImageType != imagetype.ImageTiff || e.Make != "Nikon" {
+		t.Errorf("Incorrect SkipMakerNotes got ImageType %s and Make %s", e.ImageType, e.Make)
+	}
+	f, err := os.Open("assets/a1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = DecodeWithOptions(f, MaxMetadataBytes(64))
+	if !errors.Is(err, imagetype.ErrDataLength) {
+		t.Errorf("Incorrect MaxMetadataBytes error wanted %s got %v", imagetype.ErrDataLength, err)
+	}
+	var de *meta.DecodeError
+	if !errors.As(err, &de) || de.Path != "APP1/Ifd" || de.ImageType != imagetype.ImageJPEG || de.Offset <= 0 {
+		t.Errorf("Incorrect DecodeError got %v", err)
+	}
+
+	// A Tiff with the Make value beyond the default maximum of 4 MB
+	le := binary.LittleEndian
+	tiff := []byte("II\x2a\x00\x08\x00\x00\x00")
+	tiff = le.AppendUint16(tiff, 1)
+	tiff = le.AppendUint16(tiff, 0x010f)
+	tiff = le.AppendUint16(tiff, 2)
+	tiff = le.AppendUint32(tiff, 6)
+	tiff = le.AppendUint32(tiff, 5<<20)
+	tiff = le.AppendUint32(tiff, 0)
+	tiff = append(tiff, make([]byte, 5<<20-len(tiff))...)
+	tiff = append(tiff, "Canon\x00"...)
+	if e, err := DecodeWithOptions(bytes.NewReader(tiff)); err != nil || e.Make != "" {
+		t.Errorf("Incorrect Make beyond 4 MB wanted %q got %q: %v", "", e.Make, err)
+	}
+	if e, err := DecodeWithOptions(bytes.NewReader(tiff), MaxMetadataBytes(16<<20)); err != nil || e.Make != "Canon" {
+		t.Errorf("Incorrect MaxMetadataBytes(16 MB) Make wanted %q got %q: %v", "Canon", e.Make, err)
+	}
+
+	var buf bytes.Buffer
+	if e := decode("assets/a1.jpg", WithLogger(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))); e.Make != "Canon" {
+		t.Errorf("Incorrect Make wanted Canon got %s", e.Make)
+	}
+	if buf.Len() == 0 {
+		t.Error("Incorrect WithLogger wanted log output got none")
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, filename := range []string{"assets/a1.jpg", "testImages/CR2.exif", "testImages/Heic.exif", "testImages/AVIF.avif"} {
+		t.Run(filename, func(t *testing.T) {
+			f, err := os.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err = DecodeContext(ctx, f); !errors.Is(err, context.Canceled) {
+				t.Errorf("Incorrect error wanted %s got %v", context.Canceled, err)
+			}
+			if _, err = f.Seek(0, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = DecodeAllContext(ctx, f); !errors.Is(err, context.Canceled) {
+				t.Errorf("Incorrect DecodeAll error wanted %s got %v", context.Canceled, err)
+			}
+		})
+	}
+}
+
+func TestDecodeReaderAt(t *testing.T) {
+	for _, filename := range []string{"testImages/ARW.exif", "testImages/CR2.exif", "testImages/NEF.exif", "testImages/Heic.exif", "testImages/Hero8.GPR", "assets/a1.jpg"} {
+		t.Run(filename, func(t *testing.T) {
+			f, err := os.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			want, err := Decode(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeReaderAt(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Make != want.Make || got.Model != want.Model || got.ImageType != want.ImageType || got.LensModel != want.LensModel || got.DateTimeOriginal() != want.DateTimeOriginal() {
+				t.Errorf("Incorrect Exif wanted %s %s %s got %s %s %s", want.ImageType, want.Make, want.Model, got.ImageType, got.Make, got.Model)
+			}
+		})
+	}
+
+	// IFD0 of JPEG.jpg is after its tag values
+	f, err := os.Open("testImages/JPEG.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	e, err := DecodeReaderAt(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Make != "GoPro" || e.Model != "HERO4 Silver" {
+		t.Errorf("Incorrect Make and Model wanted %s %s got %s %s", "GoPro", "HERO4 Silver", e.Make, e.Model)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	files, err := filepath.Glob("testImages/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, filename := range append(files, "isobmff/samples/canonR6.sample", "assets/a1.jpg") {
+		t.Run(filename, func(t *testing.T) {
+			buf, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, wantErr := DecodeReaderAt(bytes.NewReader(buf))
+			got, err := DecodeBytes(buf)
+			if (err == nil) != (wantErr == nil) {
+				t.Fatalf("Incorrect error wanted %v got %v", wantErr, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Incorrect Exif wanted %s %s %s got %s %s %s", want.ImageType, want.Make, want.Model, got.ImageType, got.Make, got.Model)
+			}
+		})
+	}
+
+	// PNG image with an eXIf chunk
+	_, exif := testStripExif(t)
+	var buf bytes.Buffer
+	if err = goimagepng.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	var rw png.Rewriter
+	_ = rw.Set(png.ChunkExif, exif[6:])
+	src := new(bytes.Buffer)
+	if err = rw.Rewrite(src, &buf); err != nil {
+		t.Fatal(err)
+	}
+	e, err := DecodeBytes(src.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Make != "Canon" || e.ImageType != imagetype.ImagePNG {
+		t.Errorf("Incorrect Exif wanted %s %s got %s %s", imagetype.ImagePNG, "Canon", e.ImageType, e.Make)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		filename string
+		length   int
+		err      error
+	}{
+		{"testImages/JPEG.jpg", 12917, nil},
+		{"testImages/CR2.exif", 5622, nil},
+		{"testImages/ARW.exif", 4193, nil},
+		{"testImages/NEF.exif", 0, ErrNoThumbnail},
+		{"assets/a1.jpg", 0, ErrNoThumbnail},
+	}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			f, err := os.Open(test.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			buf, err := Thumbnail(f)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Incorrect error wanted %v got %v", test.err, err)
+			}
+			if len(buf) != test.length {
+				t.Errorf("Incorrect thumbnail length wanted %d got %d", test.length, len(buf))
+			}
+			if test.length > 0 && !bytes.HasSuffix(buf, []byte{0xFF, 0xD9}) {
+				t.Errorf("Incorrect thumbnail wanted JPEG EOI marker got %X", buf[len(buf)-2:])
+			}
+		})
+	}
+}
+
+func TestPreviews(t *testing.T) {
+	tests := []struct {
+		filename          string
+		count             int
+		largest, smallest meta.Dimensions
+	}{
+		{"testImages/JPEG.jpg", 1, meta.NewDimensions(256, 144), meta.NewDimensions(256, 144)},
+		{"testImages/CR2.exif", 2, meta.NewDimensions(2784, 1856), meta.NewDimensions(160, 120)},
+		{"testImages/ARW.exif", 2, meta.NewDimensions(1616, 1080), meta.NewDimensions(160, 120)},
+		{"testImages/NEF.exif", 2, meta.NewDimensions(6000, 4000), meta.NewDimensions(1620, 1080)},
+		// The thmb items of the truncated sample are not within the file
+		{"isobmff/samples/6.sample", 0, meta.Dimensions{}, meta.Dimensions{}},
+		{"isobmff/samples/prvw.sample", 1, meta.NewDimensions(160, 120), meta.NewDimensions(160, 120)},
+		{"testImages/Hero8.GPR", 0, meta.Dimensions{}, meta.Dimensions{}},
+	}
+	for _, test := range tests {
+		t.Run(test.filename, func(t *testing.T) {
+			f, err := os.Open(test.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			previews, err := Previews(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(previews) != test.count {
+				t.Fatalf("Incorrect number of previews wanted %d got %d", test.count, len(previews))
+			}
+			largest, err := LargestPreview(previews)
+			if test.count == 0 {
+				if !errors.Is(err, ErrNoPreview) {
+					t.Errorf("Incorrect error wanted %v got %v", ErrNoPreview, err)
+				}
+				return
+			}
+			smallest, _ := SmallestPreview(previews)
+			if largest.Dimensions != test.largest || smallest.Dimensions != test.smallest {
+				t.Errorf("Incorrect previews wanted %s and %s got %s and %s", test.largest, test.smallest, largest.Dimensions, smallest.Dimensions)
+			}
+			if largest.ImageType != imagetype.ImageJPEG {
+				return
+			}
+			buf := make([]byte, 2)
+			if _, err = f.ReadAt(buf, largest.Offset); err != nil || buf[0] != 0xFF || buf[1] != 0xD8 {
+				t.Errorf("Incorrect preview wanted JPEG SOI marker got %X %v", buf, err)
+			}
+		})
+	}
+}
+
+func TestWritePreview(t *testing.T) {
+	f, err := os.Open("testImages/ARW.exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	previews, err := Previews(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LargestPreview(previews)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := WritePreview(&buf, f, p)
+	if err != nil || n != p.Length {
+		t.Fatalf("Incorrect preview wanted %d bytes got %d %v", p.Length, n, err)
+	}
+	want, err := io.ReadAll(PreviewSectionReader(f, p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) || !bytes.HasPrefix(want, []byte{0xFF, 0xD8}) {
+		t.Error("Incorrect preview image")
+	}
+
+	// Preview image beyond the end of the file
+	p.Length += 16
+	if _, err = WritePreview(io.Discard, f, p); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Incorrect error wanted %v got %v", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	// Scanner format of an 8 byte header followed by Tiff IFDs. The matcher and decoder
+	// can not be unregistered, they stay registered for every later test and fuzz seed
+	// of the package. Their 8 byte header is not of another test image.
+	it := imagetype.Register("ScannerTiff", "image/x-scanner-tiff", "sct", func(buf []byte) bool {
+		return string(buf[:8]) == "SCNTIFF\x00"
+	})
+	tiffBuf, err := os.ReadFile("testImages/NEF.exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := append([]byte("SCNTIFF\x00"), tiffBuf...)
+
+	if _, err = Decode(bytes.NewReader(buf)); !errors.Is(err, ErrMetadataNotSupported) {
+		t.Errorf("Incorrect error wanted %v got %v", ErrMetadataNotSupported, err)
+	}
+	if err = RegisterDecoder(imagetype.ImageTiff, imagetype.ImageTiff); !errors.Is(err, ErrDecoderNotRegistered) {
+		t.Errorf("Incorrect error wanted %v got %v", ErrDecoderNotRegistered, err)
+	}
+	if err = RegisterDecoder(it, imagetype.ImageTiff); err != nil {
+		t.Fatal(err)
+	}
+
+	wanted, err := Decode(bytes.NewReader(tiffBuf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func() (exif2.Exif, error){
+		"Decode":         func() (exif2.Exif, error) { return Decode(bytes.NewReader(buf)) },
+		"DecodeReaderAt": func() (exif2.Exif, error) { return DecodeReaderAt(bytes.NewReader(buf)) },
+		"DecodeBytes":    func() (exif2.Exif, error) { return DecodeBytes(buf) },
+		"DecodeAll": func() (exif2.Exif, error) {
+			m, err := DecodeAll(bytes.NewReader(buf))
+			return m.Exif, err
+		},
+	}
+	for name, decode := range decoders {
+		e, err := decode()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if e.ImageType != it || e.Make != wanted.Make || e.Model != wanted.Model || e.DateTimeOriginal() != wanted.DateTimeOriginal() {
+			t.Errorf("%s: Incorrect Exif wanted %s %s %s got %s %s %s", name, it, wanted.Make, wanted.Model, e.ImageType, e.Make, e.Model)
+		}
+	}
+}
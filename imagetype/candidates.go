@@ -0,0 +1,284 @@
+package imagetype
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// candidateScanLength is the number of bytes read by ScanCandidates. The IFD0, the Exif IFD
+	// and the start of the makernote of Tiff based Raw images are usually within them.
+	candidateScanLength = 64 * 1024
+
+	// maxCandidateIfdEntries is the maximum number of IFD entries read by ScanCandidates.
+	maxCandidateIfdEntries = 512
+)
+
+// Confidence of the candidates of ScanCandidates
+const (
+	confidenceSignature = 1.0  // magic number of the format
+	confidenceTag       = 0.95 // DNGVersion tag, or Make tag and makernote signature of a Raw image
+	confidenceFormat    = 0.9  // DNG of a GPR image, Tiff without the Make of a Raw image
+	confidenceWeak      = 0.75 // short magic number that is shared with other data
+	confidenceMake      = 0.7  // Make tag or makernote signature of a Raw image
+	confidenceHeader    = 0.5  // Tiff header with an IFD0 that is not within the data
+	confidenceTiff      = 0.3  // Tiff of a Raw image
+)
+
+// Tiff tags read by ScanCandidates
+const (
+	tagMake       = 0x010f
+	tagExifIFD    = 0x8769
+	tagMakerNote  = 0x927c
+	tagDNGVersion = 0xc612
+)
+
+// Candidate is a possible ImageType of an image and the Confidence, from 0 to 1,
+// that the image is of that ImageType.
+type Candidate struct {
+	ImageType  ImageType
+	Confidence float64
+}
+
+// ScanCandidates reads up to 64KB from the reader and returns the candidate ImageTypes
+// of the image ranked by Confidence, highest first. Tiff based Raw images (DNG, GPR, CR2,
+// NEF, ARW, PEF, SRW, 3FR and Tiff) are told apart by the CR2 signature, the DNGVersion and Make tags of
+// IFD0 and the signature of the makernote of the Exif IFD, that Scan does not read. CR3 images
+// and CRM movies are told apart by the sample count of the CRAW tracks of the 'moov' box.
+//
+// Scan is the fast header-only guess, ScanCandidates is the slower deep detection.
+// Returns ErrImageTypeNotFound if the imageType was not identified.
+func ScanCandidates(r io.Reader) ([]Candidate, error) {
+	buf := make([]byte, candidateScanLength)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return BufCandidates(buf[:n])
+}
+
+// BufCandidates returns the candidate ImageTypes of the image in buf ranked by
+// Confidence like ScanCandidates. If []byte is less than searchHeaderLength returns
+// ErrDataLength.
+func BufCandidates(buf []byte) ([]Candidate, error) {
+	it, err := Buf(buf)
+	if err != nil {
+		return nil, err
+	}
+	var c candidates
+	switch {
+	case it == ImageTiff || it == ImageCR2 || it == ImagePEF || it == ImageSRW || it == Image3FR:
+		c.tiff(buf, it)
+	case it == ImageCR3:
+		// CRM movies share the 'crx ' brand of CR3 images, they are told apart by the CRAW tracks
+		switch crxImageType(buf) {
+		case ImageCR3:
+			c.add(ImageCR3, confidenceTag)
+			c.add(ImageCRM, 1-confidenceTag)
+		case ImageCRM:
+			c.add(ImageCRM, confidenceTag)
+			c.add(ImageCR3, 1-confidenceTag)
+		default:
+			c.add(ImageCR3, confidenceWeak)
+			c.add(ImageCRM, 1-confidenceWeak)
+		}
+	case it == ImageBMP, it == ImageICO, it == ImageMOV && !isFTYPBox(buf):
+		c.add(it, confidenceWeak)
+	default:
+		c.add(it, confidenceSignature)
+	}
+	sort.SliceStable(c, func(i, j int) bool { return c[i].Confidence > c[j].Confidence })
+	return c, nil
+}
+
+// candidates is a list of Candidates.
+type candidates []Candidate
+
+// add adds the ImageType or raises the Confidence of an ImageType of the list.
+func (c *candidates) add(it ImageType, confidence float64) {
+	for i := range *c {
+		if (*c)[i].ImageType == it {
+			(*c)[i].Confidence = max((*c)[i].Confidence, confidence)
+			return
+		}
+	}
+	*c = append(*c, Candidate{ImageType: it, Confidence: confidence})
+}
+
+// tiff adds the candidates of an image with a Tiff header. The header type
+// it is ImageCR2, or ImageTiff or the ImageType of the Make of IFD0.
+func (c *candidates) tiff(buf []byte, it ImageType) {
+	if it == ImageCR2 {
+		// CR2 signature of the header
+		c.add(ImageCR2, confidenceSignature)
+		return
+	}
+	info, ok := readTiffInfo(buf)
+	if !ok {
+		c.add(ImageTiff, confidenceHeader)
+		return
+	}
+	rt, mt := info.makeImageType(), info.makerNoteImageType()
+	if info.dng {
+		// GPR images are DNG images of GoPro cameras
+		if rt == ImageGPR {
+			c.add(ImageGPR, confidenceTag)
+			c.add(ImageDNG, confidenceFormat)
+		} else {
+			c.add(ImageDNG, confidenceTag)
+		}
+		c.add(ImageTiff, 1-confidenceTag)
+		return
+	}
+	if rt == ImageGPR {
+		rt = ImageUnknown
+	}
+	switch {
+	case rt != ImageUnknown && mt == rt:
+		c.add(rt, confidenceTag)
+		c.add(ImageTiff, 1-confidenceTag)
+	case rt != ImageUnknown:
+		c.add(rt, confidenceMake)
+		c.add(ImageTiff, confidenceTiff)
+	case mt != ImageUnknown:
+		c.add(mt, confidenceMake)
+		c.add(ImageTiff, confidenceTiff)
+	default:
+		c.add(ImageTiff, confidenceFormat)
+	}
+}
+
+// tiffImageType returns the Raw ImageType of the Make tag of IFD0 of the Tiff header
+// at the start of buf, PEF, SRW or 3FR, otherwise ImageTiff. DNG images are ImageTiff.
+func tiffImageType(buf []byte) ImageType {
+	if len(buf) < searchHeaderLength {
+		return ImageTiff
+	}
+	info, ok := readTiffInfo(buf)
+	if !ok || info.dng {
+		return ImageTiff
+	}
+	switch it := info.makeImageType(); it {
+	case ImagePEF, ImageSRW, Image3FR:
+		return it
+	}
+	return ImageTiff
+}
+
+// tiffInfo are the tags of a Tiff header that identify a Tiff based Raw image.
+type tiffInfo struct {
+	make      string
+	signature []byte // start of the makernote
+	dng       bool
+}
+
+// makeImageType returns the Raw ImageType of the camera make or ImageUnknown.
+func (ti tiffInfo) makeImageType() ImageType {
+	mk := strings.ToUpper(ti.make)
+	switch {
+	case strings.HasPrefix(mk, "NIKON"):
+		return ImageNEF
+	case strings.HasPrefix(mk, "SONY"):
+		return ImageARW
+	case strings.HasPrefix(mk, "PENTAX"), strings.HasPrefix(mk, "RICOH"):
+		return ImagePEF
+	case strings.HasPrefix(mk, "SAMSUNG"):
+		return ImageSRW
+	case strings.HasPrefix(mk, "HASSELBLAD"):
+		return Image3FR
+	case strings.HasPrefix(mk, "GOPRO"):
+		return ImageGPR
+	}
+	return ImageUnknown
+}
+
+// makerNoteImageType returns the Raw ImageType of the signature of the makernote
+// or ImageUnknown.
+func (ti tiffInfo) makerNoteImageType() ImageType {
+	sig := string(ti.signature)
+	switch {
+	case strings.HasPrefix(sig, "Nikon\x00"):
+		return ImageNEF
+	case strings.HasPrefix(sig, "SONY DSC "), strings.HasPrefix(sig, "SONY CAM "):
+		return ImageARW
+	case strings.HasPrefix(sig, "AOC\x00"), strings.HasPrefix(sig, "PENTAX "):
+		return ImagePEF
+	}
+	return ImageUnknown
+}
+
+// readTiffInfo reads the Make and DNGVersion tags of IFD0 and the makernote
+// signature of the Exif IFD of the Tiff header at the start of buf. Returns false if
+// IFD0 is not within buf.
+func readTiffInfo(buf []byte) (info tiffInfo, ok bool) {
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if IsTiffBigEndian(buf) {
+		byteOrder = binary.BigEndian
+	}
+	var exifIFD uint32
+	ok = readCandidateIfd(buf, byteOrder, byteOrder.Uint32(buf[4:8]), func(tag uint16, value []byte) {
+		switch tag {
+		case tagMake:
+			info.make = strings.TrimRight(string(value), "\x00 ")
+		case tagDNGVersion:
+			info.dng = true
+		case tagExifIFD:
+			if len(value) >= 4 {
+				exifIFD = byteOrder.Uint32(value)
+			}
+		}
+	})
+	if !ok || exifIFD == 0 {
+		return info, ok
+	}
+	readCandidateIfd(buf, byteOrder, exifIFD, func(tag uint16, value []byte) {
+		if tag == tagMakerNote {
+			info.signature = value[:min(len(value), 10)]
+		}
+	})
+	return info, true
+}
+
+// readCandidateIfd calls fn with the tag and the value of each entry of the
+// IFD at offset in buf. The value is truncated or empty if it is not within buf. Returns false if
+// the IFD is not within buf.
+func readCandidateIfd(buf []byte, byteOrder binary.ByteOrder, offset uint32, fn func(tag uint16, value []byte)) bool {
+	if offset < 8 || int64(offset)+2 > int64(len(buf)) {
+		return false
+	}
+	n := int(byteOrder.Uint16(buf[offset:]))
+	entries := buf[offset+2:]
+	for i := 0; i < n && i < maxCandidateIfdEntries && 12*i+12 <= len(entries); i++ {
+		entry := entries[12*i : 12*i+12]
+		tag := byteOrder.Uint16(entry[:2])
+		size := int64(byteOrder.Uint32(entry[4:8])) * int64(tiffTypeSize(byteOrder.Uint16(entry[2:4])))
+		var value []byte
+		switch {
+		case size <= 4:
+			value = entry[8 : 8+size]
+		case int64(byteOrder.Uint32(entry[8:12]))+size <= int64(len(buf)):
+			off := int64(byteOrder.Uint32(entry[8:12]))
+			value = buf[off : off+size]
+		case int64(byteOrder.Uint32(entry[8:12])) < int64(len(buf)):
+			// Value is truncated by the end of buf
+			value = buf[byteOrder.Uint32(entry[8:12]):]
+		}
+		fn(tag, value)
+	}
+	return true
+}
+
+// tiffTypeSize returns the size in bytes of a Tiff tag type, 1 for unknown types.
+func tiffTypeSize(t uint16) int {
+	switch t {
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11, 13: // LONG, SLONG, FLOAT, IFD
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return 1
+}
@@ -0,0 +1,176 @@
+package imagetype
+
+import "encoding/binary"
+
+// FromFTYP returns the ImageType of an ISO Base Media File from the payload of
+// its 'ftyp' box: the major brand, the minor version and the compatible brands.
+//
+//	heic, heix:             ImageHEIC
+//	mif1 (and miaf, mif2):  ImageHEIF
+//	msf1, hevc:             ImageHEIFSequence
+//	avif:                   ImageAVIF
+//	avis:                   ImageAVIFSequence
+//	crx:                    ImageCR3, Canon CRM movies share the brand of CR3 (see ScanCandidates)
+//	qt:                     ImageMOV
+//	isom, mp41, mp42, ...:  ImageMP4
+//
+// The major brand is checked first, then the compatible brands. Returns
+// ImageUnknown if the brands are not recognized.
+func FromFTYP(buf []byte) ImageType {
+	if len(buf) < 4 {
+		return ImageUnknown
+	}
+	major := buf[:4]
+	switch string(major) {
+	case "crx ":
+		return ImageCR3
+	case "avif":
+		return ImageAVIF
+	case "avis":
+		return ImageAVIFSequence
+	case "heic", "heix", "heim", "heis":
+		return ImageHEIC
+	case "hevc", "hevx", "hevm", "hevs":
+		return ImageHEIFSequence
+	case "qt  ":
+		return ImageMOV
+	}
+
+	// Compatible brands follow the 4 byte minor version
+	var compatible []byte
+	if len(buf) > 8 {
+		compatible = buf[8:]
+	}
+	// The image brands of a major brand of an image take precedence over the sequence brands
+	image := isFTYPBrand(major, "mif1") || isFTYPBrand(major, "mif2") || isFTYPBrand(major, "miaf")
+	switch {
+	case !image && hasFTYPBrand(compatible, "avis"):
+		return ImageAVIFSequence
+	case !image && (isFTYPBrand(major, "msf1") || hasFTYPBrand(compatible, "msf1") || hasFTYPBrand(compatible, "hevc")):
+		return ImageHEIFSequence
+	case hasFTYPBrand(compatible, "avif"):
+		return ImageAVIF
+	case hasFTYPBrand(compatible, "heic"), hasFTYPBrand(compatible, "heix"):
+		return ImageHEIC
+	case image, hasFTYPBrand(compatible, "mif1"):
+		return ImageHEIF
+	case hasFTYPBrand(compatible, "qt  "):
+		return ImageMOV
+	case isMP4Brand(major):
+		return ImageMP4
+	}
+	return ImageUnknown
+}
+
+// ftypImageType returns the ImageType of the 'ftyp' box at the start of buf.
+// The brands are limited to the box and to buf.
+func ftypImageType(buf []byte) ImageType {
+	size := int(buf[0])<<24 | int(buf[1])<<16 | int(buf[2])<<8 | int(buf[3])
+	if size < 8 || size > len(buf) {
+		// Box extends to the end of the file or beyond the header
+		size = len(buf)
+	}
+	return FromFTYP(buf[8:size])
+}
+
+// hasFTYPBrand returns true if the list of compatible brands in buf contains brand.
+func hasFTYPBrand(buf []byte, brand string) bool {
+	for i := 0; i+4 <= len(buf); i += 4 {
+		if isFTYPBrand(buf[i:i+4], brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMP4Brand returns true if the major brand is an MPEG-4 or 3GPP video brand.
+func isMP4Brand(brand []byte) bool {
+	switch string(brand) {
+	case "isom", "iso2", "iso3", "iso4", "iso5", "iso6", "iso8", "iso9",
+		"mp41", "mp42", "mp71", "avc1", "M4V ", "M4VH", "M4VP", "MSNV", "dash", "mmp4", "f4v ", "XAVC",
+		"3gp4", "3gp5", "3gp6", "3gp7", "3ge6", "3ge7", "3gg6", "3g2a", "3g2b", "3g2c":
+		return true
+	}
+	return false
+}
+
+// isMOVAtom returns true if the header starts with a QuickTime atom that is
+// found at the start of QuickTime movies without an 'ftyp' box.
+func isMOVAtom(buf []byte) bool {
+	if buf[0] != 0x00 {
+		return false
+	}
+	switch string(buf[4:8]) {
+	case "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// crxImageType returns ImageCR3 or ImageCRM from the 'moov' box of a Canon Raw file in
+// buf. CR3 images and CRM movies share the 'crx ' brand and the minor version, the
+// 'CRAW' tracks of a CR3 image have a single sample (the image) and the 'CRAW' tracks
+// of a CRM movie have a sample for each frame. Returns ImageUnknown if the 'stsz' box
+// of a 'CRAW' track is not within buf.
+func crxImageType(buf []byte) ImageType {
+	moov := findBox(buf, "moov")
+	for boxType, payload, rest, ok := nextBox(moov); ok; boxType, payload, rest, ok = nextBox(rest) {
+		if boxType != "trak" {
+			continue
+		}
+		stbl := findBox(findBox(findBox(payload, "mdia"), "minf"), "stbl")
+		// Sample description: version and flags, entry count and the first sample entry
+		if stsd := findBox(stbl, "stsd"); len(stsd) < 16 || string(stsd[12:16]) != "CRAW" {
+			continue
+		}
+		stsz := findBox(stbl, "stsz")
+		// Sample size: version and flags, sample size and sample count
+		if len(stsz) < 12 {
+			return ImageUnknown
+		}
+		if binary.BigEndian.Uint32(stsz[8:12]) > 1 {
+			return ImageCRM
+		}
+		return ImageCR3
+	}
+	return ImageUnknown
+}
+
+// findBox returns the payload of the first box of boxType in buf. The payload
+// is truncated by the end of buf.
+func findBox(buf []byte, boxType string) []byte {
+	for bt, payload, rest, ok := nextBox(buf); ok; bt, payload, rest, ok = nextBox(rest) {
+		if bt == boxType {
+			return payload
+		}
+	}
+	return nil
+}
+
+// nextBox returns the type and the payload of the box at the start of buf and the
+// rest of buf. The payload of a box that is not within buf is truncated and the
+// rest is empty. Returns false if there is not a box header in buf.
+func nextBox(buf []byte) (boxType string, payload, rest []byte, ok bool) {
+	if len(buf) < 8 {
+		return "", nil, nil, false
+	}
+	size, header := uint64(binary.BigEndian.Uint32(buf)), uint64(8)
+	switch size {
+	case 0:
+		// Box extends to the end of the file
+		size = uint64(len(buf))
+	case 1:
+		// 64 bit size after the box type
+		if len(buf) < 16 {
+			return "", nil, nil, false
+		}
+		size, header = binary.BigEndian.Uint64(buf[8:16]), 16
+	}
+	if size < header {
+		return "", nil, nil, false
+	}
+	if size > uint64(len(buf)) {
+		return string(buf[4:8]), buf[header:], nil, true
+	}
+	return string(buf[4:8]), buf[header:size], buf[size:], true
+}
@@ -22,19 +22,19 @@ var (
 		{".JPG/NoExif", "20.jpg", "image/jpeg"},
 		{".JPG/GoPro", "hero6.jpg", "image/jpeg"},
 		{".JPEG", "21.jpeg", "image/jpeg"},
-		{".HEIC/iPhone", "1.heic", "image/heif"},
-		{".HEIC/Conv", "3.heic", "image/heif"},
-		{".HEIC/Alt", "4.heic", "image/heif"},
+		{".HEIC/iPhone", "1.heic", "image/heic"},
+		{".HEIC/Conv", "3.heic", "image/heic"},
+		{".HEIC/Alt", "4.heic", "image/heic"},
 		{".WEBP", "4.webp", "image/webp"},
 		{".GPR/GoPro", "hero6.gpr", "image/tiff"},
 		{".NEF/Nikon", "2.NEF", "image/tiff"},
 		{".ARW/Sony", "2.ARW", "image/tiff"},
 		{".DNG/Adobe", "1.DNG", "image/tiff"},
 		{".PNG", "0.png", "image/png"},
-		{".RW2", "4.RW2", "image/x-panasonic-raw"},
+		{".RW2", "4.RW2", "image/x-panasonic-rw2"},
 		{".XMP", "test.xmp", "application/rdf+xml"},
 		{".PSD", "0.psd", "image/vnd.adobe.photoshop"},
-		{".JP2/JPEG2000", "0.jp2", "image/jpeg"},
+		{".JP2/JPEG2000", "0.jp2", "image/jp2"},
 		{".BMP", "0.bmp", "image/bmp"},
 	}
 )
@@ -11,18 +11,18 @@ var (
 	ErrDataLength = errors.New("error the data is not long enough")
 
 	// ImageType stringer Index
-	_ImageTypeIndex = [...]uint{0, 24, 34, 43, 52, 61, 71, 81, 90, 100, 117, 134, 155, 171, 188, 205, 222, 239, 264, 283, 293, 316, 325, 338, 350}
+	_ImageTypeIndex = [...]uint{0, 24, 34, 43, 52, 61, 71, 81, 90, 100, 117, 134, 155, 171, 188, 205, 222, 239, 264, 283, 293, 316, 325, 338, 350, 359, 378, 394, 415, 433, 452, 469, 489, 511, 522, 531, 543, 553, 572, 591, 608, 617, 632}
 
 	// ImageType extension Index
-	_ImageTypeExtIndex = [...]uint{0, 0, 3, 6, 9, 12, 16, 20, 23, 27, 30, 33, 36, 39, 42, 45, 48, 51, 54, 57, 61, 64, 67, 70, 76}
+	_ImageTypeExtIndex = [...]uint{0, 0, 3, 6, 9, 12, 16, 20, 23, 27, 30, 33, 36, 39, 42, 45, 48, 51, 54, 57, 61, 64, 67, 70, 76, 79, 82, 85, 88, 91, 94, 97, 100, 103, 106, 109, 112, 116, 121, 126, 129, 132, 135}
 )
 
 const (
 	// ImageType stringer Names
-	_ImageTypeString = "application/octet-streamimage/jpegimage/pngimage/gifimage/bmpimage/webpimage/heifimage/rawimage/tiffimage/x-adobe-dngimage/x-nikon-nefimage/x-panasonic-rawimage/x-sony-arwimage/x-canon-crwimage/x-gopro-gprimage/x-canon-cr3image/x-canon-cr2image/vnd.adobe.photoshopapplication/rdf+xmlimage/avifimage/x-portable-pixmapimage/jp2image/svg+xmlimage/magick"
+	_ImageTypeString = "application/octet-streamimage/jpegimage/pngimage/gifimage/bmpimage/webpimage/heifimage/rawimage/tiffimage/x-adobe-dngimage/x-nikon-nefimage/x-panasonic-rawimage/x-sony-arwimage/x-canon-crwimage/x-gopro-gprimage/x-canon-cr3image/x-canon-cr2image/vnd.adobe.photoshopapplication/rdf+xmlimage/avifimage/x-portable-pixmapimage/jp2image/svg+xmlimage/magickimage/jxlimage/x-olympus-orfimage/x-fuji-rafimage/x-panasonic-rw2image/x-pentax-pefimage/x-samsung-srwimage/x-sigma-x3fimage/x-phaseone-iiqimage/x-hasselblad-3frimage/x-exrimage/qoiimage/x-iconimage/heicimage/heif-sequenceimage/avif-sequencevideo/x-canon-crmvideo/mp4video/quicktime"
 
 	// ImageType extension Names
-	_ImageTypeExtString = "jpgpnggifbmpwebpheifRAWTIFFDNGNEFRW2ARWCRWGPRCR3CR2PSDXMPavifppmjp2svgmagick"
+	_ImageTypeExtString = "jpgpnggifbmpwebpheifRAWTIFFDNGNEFRAWARWCRWGPRCR3CR2PSDXMPavifppmjp2svgmagickjxlORFRAFRW2PEFSRWX3FIIQ3FRexrqoiicoheicheifsavifsCRMmp4mov"
 )
 
 //go:generate msgp
@@ -50,6 +50,27 @@ const (
 //	ImageXMP:     "application/rdf+xml"
 //	ImageAVIF:    "image/avif"
 //	ImagePPM:     "image/x-portable-pixmap"
+//	ImageJP2K:    "image/jp2"
+//	ImageSVG:     "image/svg+xml"
+//	ImageMAGICK:  "image/magick"
+//	ImageJXL:     "image/jxl"
+//	ImageORF:     "image/x-olympus-orf"
+//	ImageRAF:     "image/x-fuji-raf"
+//	ImageRW2:     "image/x-panasonic-rw2"
+//	ImagePEF:     "image/x-pentax-pef"
+//	ImageSRW:     "image/x-samsung-srw"
+//	ImageX3F:     "image/x-sigma-x3f"
+//	ImageIIQ:     "image/x-phaseone-iiq"
+//	Image3FR:     "image/x-hasselblad-3fr"
+//	ImageEXR:     "image/x-exr"
+//	ImageQOI:     "image/qoi"
+//	ImageICO:     "image/x-icon"
+//	ImageHEIC:    "image/heic"
+//	ImageHEIFSequence: "image/heif-sequence"
+//	ImageAVIFSequence: "image/avif-sequence"
+//	ImageCRM:     "video/x-canon-crm"
+//	ImageMP4:     "video/mp4"
+//	ImageMOV:     "video/quicktime"
 type ImageType uint8
 
 // IsUnknown returns true if the Image Type is unknown
@@ -74,6 +95,9 @@ func (it ImageType) String() string {
 	if int(it) < len(_ImageTypeIndex)-1 {
 		return _ImageTypeString[_ImageTypeIndex[it]:_ImageTypeIndex[it+1]]
 	}
+	if rt, ok := registeredImageType(it); ok {
+		return rt.mime
+	}
 	return _ImageTypeString[:_ImageTypeIndex[1]]
 }
 
@@ -82,6 +106,9 @@ func (it ImageType) Extension() string {
 	if int(it) < len(_ImageTypeExtIndex)-1 {
 		return _ImageTypeExtString[_ImageTypeExtIndex[it]:_ImageTypeExtIndex[it+1]]
 	}
+	if rt, ok := registeredImageType(it); ok {
+		return rt.ext
+	}
 	return _ImageTypeExtString[:_ImageTypeExtIndex[1]]
 }
 
@@ -95,7 +122,8 @@ func FromString(str string) ImageType {
 	if it, ok := imageTypeExtensions[strings.ToLower(str)]; ok {
 		return it
 	}
-	return ImageUnknown
+	// from the registered content-types and extensions
+	return registeredFromString(str)
 }
 
 // Image file types Raw/Compressed/JPEG
@@ -121,9 +149,27 @@ const (
 	ImageXMP
 	ImageAVIF
 	ImagePPM
-	ImageJP2K   // JP2K represents the JPEG 2000 image type.
-	ImageSVG    // SVG represents the SVG image type.
-	ImageMAGICK // MAGICK represents the libmagick compatible genetic image type.
+	ImageJP2K         // JP2K represents the JPEG 2000 image type.
+	ImageSVG          // SVG represents the SVG image type.
+	ImageMAGICK       // MAGICK represents the libmagick compatible genetic image type.
+	ImageJXL          // JXL represents the JPEG XL codestream and container image type.
+	ImageORF          // ORF represents the Olympus Raw image type.
+	ImageRAF          // RAF represents the Fujifilm Raw image type.
+	ImageRW2          // RW2 represents the Panasonic RW2 Raw image type, ImagePanaRAW is the older Panasonic RAW.
+	ImagePEF          // PEF represents the Pentax Raw image type, identified by the Make of its Exif.
+	ImageSRW          // SRW represents the Samsung Raw image type, identified by the Make of its Exif.
+	ImageX3F          // X3F represents the Sigma Raw image type.
+	ImageIIQ          // IIQ represents the Phase One Raw image type.
+	Image3FR          // 3FR represents the Hasselblad Raw image type, identified by the Make of its Exif.
+	ImageEXR          // EXR represents the OpenEXR image type.
+	ImageQOI          // QOI represents the Quite OK Image type.
+	ImageICO          // ICO represents the Windows icon image type.
+	ImageHEIC         // HEIC represents the HEVC coded HEIF image type, the 'heic' and 'heix' brands.
+	ImageHEIFSequence // HEIFSequence represents the HEIF image sequence type, the 'msf1' and 'hevc' brands.
+	ImageAVIFSequence // AVIFSequence represents the AVIF image sequence type, the 'avis' brand.
+	ImageCRM          // CRM represents the Canon Raw movie type, it shares the 'crx ' brand of CR3 and is identified by ScanCandidates.
+	ImageMP4          // MP4 represents the MPEG-4 video type.
+	ImageMOV          // MOV represents the QuickTime video type.
 )
 
 // ImageTypeValues maps a content-type string with an imagetype.
@@ -152,6 +198,26 @@ var imageTypeValues = map[string]ImageType{
 	"image/jp2":                 ImageJP2K,
 	"image/svg+xml":             ImageSVG,
 	"image/magick":              ImageMAGICK,
+	"image/jxl":                 ImageJXL,
+	"image/x-olympus-orf":       ImageORF,
+	"image/x-fuji-raf":          ImageRAF,
+	"image/x-panasonic-rw2":     ImageRW2,
+	"image/x-pentax-pef":        ImagePEF,
+	"image/x-samsung-srw":       ImageSRW,
+	"image/x-sigma-x3f":         ImageX3F,
+	"image/x-phaseone-iiq":      ImageIIQ,
+	"image/x-hasselblad-3fr":    Image3FR,
+	"image/x-exr":               ImageEXR,
+	"image/qoi":                 ImageQOI,
+	"image/x-icon":              ImageICO,
+	"image/vnd.microsoft.icon":  ImageICO,
+	"image/heic":                ImageHEIC,
+	"image/heif-sequence":       ImageHEIFSequence,
+	"image/heic-sequence":       ImageHEIFSequence,
+	"image/avif-sequence":       ImageAVIFSequence,
+	"video/x-canon-crm":         ImageCRM,
+	"video/mp4":                 ImageMP4,
+	"video/quicktime":           ImageMOV,
 }
 
 // ImageTypeExtensions maps filename extensions with an imagetype.
@@ -167,7 +233,7 @@ var imageTypeExtensions = map[string]ImageType{
 	".tiff":   ImageTiff,
 	".dng":    ImageDNG,
 	".nef":    ImageNEF,
-	".rw2":    ImagePanaRAW,
+	".rw2":    ImageRW2,
 	".arw":    ImageARW,
 	".crw":    ImageCRW,
 	".gpr":    ImageGPR,
@@ -180,6 +246,34 @@ var imageTypeExtensions = map[string]ImageType{
 	".jp2":    ImageJP2K,
 	".svg":    ImageSVG,
 	".magick": ImageMAGICK,
+	".jxl":    ImageJXL,
+	".orf":    ImageORF,
+	".raf":    ImageRAF,
+	".pef":    ImagePEF,
+	".srw":    ImageSRW,
+	".x3f":    ImageX3F,
+	".iiq":    ImageIIQ,
+	".3fr":    Image3FR,
+	".exr":    ImageEXR,
+	".qoi":    ImageQOI,
+	".ico":    ImageICO,
+	".heic":   ImageHEIC,
+	".heifs":  ImageHEIFSequence,
+	".heics":  ImageHEIFSequence,
+	".avifs":  ImageAVIFSequence,
+	".crm":    ImageCRM,
+	".mp4":    ImageMP4,
+	".m4v":    ImageMP4,
+	".mov":    ImageMOV,
+	".qt":     ImageMOV,
+
+	// Common aliases of the extensions
+	".jpeg": ImageJPEG,
+	".jpe":  ImageJPEG,
+	".jfif": ImageJPEG,
+	".tif":  ImageTiff,
+	".hif":  ImageHEIC,
+	".j2k":  ImageJP2K,
 }
 
 // isTiff() Checks to see if an Image has the tiff format header.
@@ -238,27 +332,6 @@ func isCR2(buf []byte) bool {
 		buf[11] == 0x00
 }
 
-// isCR3 returns true if it matches an image/x-canon-cr3.
-//
-// ftyp box with major_brand: 'crx ' and compatible_brands: 'crx ' 'isom'
-func isCR3(buf []byte) bool {
-	return isFTYPBox(buf) &&
-		isFTYPBrand(buf[8:12], "crx ")
-}
-
-// isHeif returns true if the header matches the start of a HEIF file.
-//
-// Major brands: heic, mif1, heix
-// Minor brand contains:
-func isHeif(buf []byte) bool {
-	return isFTYPBox(buf) &&
-		(isFTYPBrand(buf[8:12], "heic") ||
-			isFTYPBrand(buf[8:12], "heix") ||
-			(isFTYPBrand(buf[8:12], "mif1") && isFTYPBrand(buf[16:20], "heic")) ||
-			(isFTYPBrand(buf[8:12], "mif1") && isFTYPBrand(buf[20:24], "heic")) ||
-			(isFTYPBrand(buf[8:12], "msf1") && isFTYPBrand(buf[20:24], "hevc")))
-}
-
 // isFTYPBrand returns true if the Brand in []byte matches the brand in str.
 // the Limit is 4 bytes
 func isFTYPBrand(buf []byte, str string) bool {
@@ -278,14 +351,6 @@ func isFTYPBox(buf []byte) bool {
 		buf[7] == 0x70
 }
 
-// isAVIF returns true if the header matches an ftyp box and
-// an avif box.
-func isAVIF(buf []byte) bool {
-	return isFTYPBox(buf) &&
-		(isFTYPBrand(buf[8:12], "avif") ||
-			(isFTYPBrand(buf[8:12], "mif1") && isFTYPBrand(buf[20:24], "avif")))
-}
-
 // isBMP returns true if the header matches the start of a BMP file
 // Bitmap Image
 func isBMP(buf []byte) bool {
@@ -293,13 +358,19 @@ func isBMP(buf []byte) bool {
 		buf[1] == 0x4D
 }
 
-// isRW2 returns true if the first 4 bytes match the Panasonic Tiff alternate
-// header and bytes 8 through 12 match the RW2 header
-func isRW2(buf []byte) bool {
+// isPanaRAW returns true if the first 4 bytes match the Panasonic Tiff alternate
+// header
+func isPanaRAW(buf []byte) bool {
 	return buf[0] == 0x49 &&
 		buf[1] == 0x49 &&
 		buf[2] == 0x55 &&
-		buf[3] == 0x00 &&
+		buf[3] == 0x00
+}
+
+// isRW2 returns true if the first 4 bytes match the Panasonic Tiff alternate
+// header and bytes 8 through 12 match the RW2 header
+func isRW2(buf []byte) bool {
+	return isPanaRAW(buf) &&
 		buf[8] == 0x88 &&
 		buf[9] == 0xe7 &&
 		buf[10] == 0x74 &&
@@ -351,6 +422,14 @@ func isJPEG2000(buf []byte) bool {
 		buf[11] == 0xA
 }
 
+// isJ2K returns true if the first 4 bytes match the SOC and SIZ markers of a JPEG2000 codestream.
+func isJ2K(buf []byte) bool {
+	return buf[0] == 0xFF &&
+		buf[1] == 0x4F &&
+		buf[2] == 0xFF &&
+		buf[3] == 0x51
+}
+
 // isPSD returns true if the header matches a PSDImage.
 //
 // PSD Photoshop document
@@ -391,3 +470,62 @@ func isPPM(buf []byte) bool {
 		(buf[1] == '3' || buf[1] == '6') &&
 		(buf[2] == '\n' || buf[2] == '\r' || buf[2] == '\t' || buf[2] == ' ')
 }
+
+// isJXL returns true if the header matches a JPEG XL codestream (FF 0A) or
+// the JPEG XL signature box of the container.
+func isJXL(buf []byte) bool {
+	return buf[0] == 0xff && buf[1] == 0x0a ||
+		string(buf[:12]) == "\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a"
+}
+
+// isORF returns true if the header matches an Olympus Raw image, a Tiff
+// header with the signatures "IIRO", "IIRS" or "MMOR".
+func isORF(buf []byte) bool {
+	return string(buf[:4]) == "IIRO" ||
+		string(buf[:4]) == "IIRS" ||
+		string(buf[:4]) == "MMOR"
+}
+
+// isRAF returns true if the header matches a Fujifilm Raw image.
+func isRAF(buf []byte) bool {
+	return string(buf[:15]) == "FUJIFILMCCD-RAW"
+}
+
+// isX3F returns true if the header matches a Sigma Raw image.
+func isX3F(buf []byte) bool {
+	return string(buf[:4]) == "FOVb"
+}
+
+// isIIQ returns true if the header matches a Phase One Raw image, "IIII" or
+// "MMMM" followed by the "Raw" signature in the byte order of the image.
+func isIIQ(buf []byte) bool {
+	return string(buf[:4]) == "IIII" && string(buf[5:8]) == "waR" ||
+		string(buf[:4]) == "MMMM" && string(buf[4:7]) == "Raw"
+}
+
+// isEXR returns true if the header matches the magic number of an OpenEXR image.
+func isEXR(buf []byte) bool {
+	return buf[0] == 0x76 &&
+		buf[1] == 0x2f &&
+		buf[2] == 0x31 &&
+		buf[3] == 0x01
+}
+
+// isQOI returns true if the header matches the magic number of a QOI image.
+func isQOI(buf []byte) bool {
+	return string(buf[:4]) == "qoif"
+}
+
+// isICO returns true if the header matches an icon directory with at least one
+// image, the reserved byte and the color planes of the first image are checked
+// as the signature is short.
+func isICO(buf []byte) bool {
+	return buf[0] == 0x00 &&
+		buf[1] == 0x00 &&
+		buf[2] == 0x01 &&
+		buf[3] == 0x00 &&
+		(buf[4] != 0x00 || buf[5] != 0x00) &&
+		buf[9] == 0x00 &&
+		buf[10] <= 0x01 &&
+		buf[11] == 0x00
+}
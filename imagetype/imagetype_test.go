@@ -1,11 +1,17 @@
 package imagetype
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tinylib/msgp/msgp"
 )
@@ -19,7 +25,7 @@ func TestScan(t *testing.T) {
 		{"../testImages/ARW.exif", ImageTiff},
 		{"../testImages/NEF.exif", ImageTiff},
 		{"../testImages/CR2.exif", ImageCR2},
-		{"../testImages/Heic.exif", ImageHEIF},
+		{"../testImages/Heic.exif", ImageHEIC},
 		{"../testImages/AVIF.avif", ImageAVIF},
 		{"../testImages/AVIF2.avif", ImageAVIF},
 		{"../testImages/CRW.CRW", ImageCRW},
@@ -66,6 +72,253 @@ func TestScan(t *testing.T) {
 	}
 }
 
+func TestBufSignatures(t *testing.T) {
+	signatureTests := []struct {
+		name      string
+		header    []byte
+		imageType ImageType
+	}{
+		{"JXL/Codestream", []byte{0xff, 0x0a, 0xfa, 0x1f}, ImageJXL},
+		{"JXL/Container", []byte("\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a\x00\x00\x00\x14ftypjxl "), ImageJXL},
+		{"ORF/LittleEndian", []byte("IIRO\x08\x00\x00\x00"), ImageORF},
+		{"ORF/IIRS", []byte("IIRS\x08\x00\x00\x00"), ImageORF},
+		{"ORF/BigEndian", []byte("MMOR\x00\x00\x00\x08"), ImageORF},
+		{"RAF", []byte("FUJIFILMCCD-RAW 0201FF383501"), ImageRAF},
+		{"RW2", []byte{0x49, 0x49, 0x55, 0x00, 0x18, 0x00, 0x00, 0x00, 0x88, 0xe7, 0x74, 0xd8}, ImageRW2},
+		{"PanaRAW", []byte{0x49, 0x49, 0x55, 0x00, 0x08, 0x00, 0x00, 0x00}, ImagePanaRAW},
+		{"X3F", []byte("FOVb\x00\x00\x03\x00"), ImageX3F},
+		{"IIQ/LittleEndian", []byte("IIII\x01waR\x08\x00\x00\x00"), ImageIIQ},
+		{"IIQ/BigEndian", []byte("MMMMRaw\x01\x00\x00\x00\x08"), ImageIIQ},
+		{"EXR", []byte{0x76, 0x2f, 0x31, 0x01, 0x02, 0x00, 0x00, 0x00}, ImageEXR},
+		{"QOI", []byte("qoif\x00\x00\x01\x00\x00\x00\x01\x00\x04\x00"), ImageQOI},
+		{"ICO", []byte{0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x10, 0x10, 0x00, 0x00, 0x01, 0x00, 0x20, 0x00}, ImageICO},
+		{"ICO/NoImages", []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x10, 0x00, 0x00, 0x01, 0x00, 0x20, 0x00}, ImageUnknown},
+		{"Tiff", []byte("II*\x00\x08\x00\x00\x00"), ImageTiff},
+	}
+	for _, header := range signatureTests {
+		t.Run(header.name, func(t *testing.T) {
+			buf := make([]byte, searchHeaderLength)
+			copy(buf, header.header)
+			imageType, err := Buf(buf)
+			if header.imageType == ImageUnknown {
+				if err != ErrImageTypeNotFound {
+					t.Errorf("Incorrect error wanted %v got %v", ErrImageTypeNotFound, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if header.imageType != imageType {
+				t.Errorf("Incorrect Imagetype wanted %s got %s", header.imageType.String(), imageType.String())
+			}
+		})
+	}
+
+	extensionTests := map[string]ImageType{
+		"image.jxl": ImageJXL, "image.ORF": ImageORF, "image.raf": ImageRAF, "image.RW2": ImageRW2,
+		"image.PEF": ImagePEF, "image.srw": ImageSRW, "image.X3F": ImageX3F, "image.IIQ": ImageIIQ,
+		"image.3FR": Image3FR, "image.exr": ImageEXR, "image.qoi": ImageQOI, "image.ico": ImageICO,
+	}
+	for filename, it := range extensionTests {
+		if got := FromString(filepath.Ext(filename)); got != it {
+			t.Errorf("Incorrect Imagetype of %s wanted %s got %s", filename, it, got)
+		}
+		if got := FromString(it.String()); got != it {
+			t.Errorf("Incorrect Imagetype FromString(%s) got %s", it, got)
+		}
+	}
+	if got := FromString("image/vnd.microsoft.icon"); got != ImageICO {
+		t.Errorf("Incorrect Imagetype wanted %s got %s", ImageICO, got)
+	}
+}
+
+func TestFTYPBrands(t *testing.T) {
+	// ftyp box of the major brand, the minor version and the compatible brands
+	ftyp := func(brands ...string) []byte {
+		buf := []byte{0x00, 0x00, 0x00, byte(12 + 4*len(brands)), 'f', 't', 'y', 'p'}
+		buf = append(buf, brands[0]...)
+		buf = append(buf, 0x00, 0x00, 0x00, 0x00)
+		for _, b := range brands[1:] {
+			buf = append(buf, b...)
+		}
+		return append(buf, make([]byte, searchHeaderLength)...)
+	}
+	brandTests := []struct {
+		name      string
+		header    []byte
+		imageType ImageType
+	}{
+		{"HEIC/heic", ftyp("heic", "mif1", "heic"), ImageHEIC},
+		{"HEIC/heix", ftyp("heix", "mif1", "heix"), ImageHEIC},
+		{"HEIC/mif1", ftyp("mif1", "mif1", "heic"), ImageHEIC},
+		{"HEIF/mif1", ftyp("mif1", "mif1", "miaf"), ImageHEIF},
+		{"HEIF/mif1+msf1", ftyp("mif1", "msf1", "mif1"), ImageHEIF},
+		{"HEIFSequence/msf1", ftyp("msf1", "msf1", "hevc"), ImageHEIFSequence},
+		{"HEIFSequence/hevc", ftyp("hevc", "mif1", "msf1"), ImageHEIFSequence},
+		{"AVIF/avif", ftyp("avif", "avif", "mif1"), ImageAVIF},
+		{"AVIF/mif1", ftyp("mif1", "mif1", "avif"), ImageAVIF},
+		{"AVIFSequence/avis", ftyp("avis", "avis", "msf1"), ImageAVIFSequence},
+		{"AVIFSequence/msf1", ftyp("msf1", "avis", "msf1"), ImageAVIFSequence},
+		{"CR3", ftyp("crx ", "crx ", "isom"), ImageCR3},
+		{"MOV/qt", ftyp("qt  ", "qt  "), ImageMOV},
+		{"MOV/compatible", ftyp("3gp4", "qt  "), ImageMOV},
+		{"MP4/isom", ftyp("isom", "isom", "iso2"), ImageMP4},
+		{"MP4/mp42", ftyp("mp42", "mp41", "isom"), ImageMP4},
+		{"MP4/3gp", ftyp("3gp5", "3gp5", "isom"), ImageMP4},
+		{"Unknown", ftyp("M4A ", "M4A ", "isom"), ImageUnknown},
+		{"MOV/moov", append([]byte{0x00, 0x00, 0x10, 0x00, 'm', 'o', 'o', 'v'}, make([]byte, searchHeaderLength)...), ImageMOV},
+		{"MOV/wide", append([]byte{0x00, 0x00, 0x00, 0x08, 'w', 'i', 'd', 'e'}, make([]byte, searchHeaderLength)...), ImageMOV},
+	}
+	for _, header := range brandTests {
+		t.Run(header.name, func(t *testing.T) {
+			imageType, _ := Buf(header.header)
+			if header.imageType != imageType {
+				t.Errorf("Incorrect Imagetype wanted %s got %s", header.imageType.String(), imageType.String())
+			}
+			// Compatible brands beyond the search header
+			if isFTYPBox(header.header) {
+				if imageType = FromFTYP(header.header[8:header.header[3]]); header.imageType != imageType {
+					t.Errorf("Incorrect FromFTYP wanted %s got %s", header.imageType.String(), imageType.String())
+				}
+			}
+		})
+	}
+
+	// The compatible brands are limited to the size of the ftyp box
+	buf := ftyp("isom", "isom")
+	copy(buf[20:], "avif")
+	if imageType, _ := Buf(buf); imageType != ImageMP4 {
+		t.Errorf("Incorrect Imagetype wanted %s got %s", ImageMP4, imageType)
+	}
+}
+
+func TestScanCandidates(t *testing.T) {
+	candidateTests := []struct {
+		filename   string
+		candidates []Candidate
+	}{
+		{"../testImages/ARW.exif", []Candidate{{ImageARW, confidenceMake}, {ImageTiff, confidenceTiff}}},
+		{"../testImages/NEF.exif", []Candidate{{ImageNEF, confidenceTag}, {ImageTiff, 1 - confidenceTag}}},
+		{"../testImages/CR2.exif", []Candidate{{ImageCR2, confidenceSignature}}},
+		{"../testImages/Hero8.GPR", []Candidate{{ImageGPR, confidenceTag}, {ImageDNG, confidenceFormat}, {ImageTiff, 1 - confidenceTag}}},
+		{"../testImages/JPEG.jpg", []Candidate{{ImageJPEG, confidenceSignature}}},
+		{"../testImages/Heic.exif", []Candidate{{ImageHEIC, confidenceSignature}}},
+	}
+	for _, ct := range candidateTests {
+		t.Run(ct.filename, func(t *testing.T) {
+			f, err := os.Open(ct.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			candidates, err := ScanCandidates(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(candidates, ct.candidates) {
+				t.Errorf("Incorrect candidates wanted %v got %v", ct.candidates, candidates)
+			}
+		})
+	}
+
+	// Canon Raw file with a CRAW track of count samples
+	crx := func(count uint32) []byte {
+		box := func(boxType string, payload ...[]byte) []byte {
+			buf := binary.BigEndian.AppendUint32(nil, 8)
+			buf = append(buf, boxType...)
+			for _, p := range payload {
+				buf = append(buf, p...)
+			}
+			binary.BigEndian.PutUint32(buf, uint32(len(buf)))
+			return buf
+		}
+		stsd := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, box("CRAW", make([]byte, 16))...)
+		stsz := binary.BigEndian.AppendUint32(make([]byte, 8), count)
+		trak := box("trak", box("tkhd", make([]byte, 84)), box("mdia", box("minf", box("stbl", box("stsd", stsd), box("stsz", stsz)))))
+		return append([]byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01crx isom"), box("moov", box("mvhd", make([]byte, 100)), trak)...)
+	}
+	bufTests := []struct {
+		name       string
+		buf        []byte
+		candidates []Candidate
+	}{
+		{"PEF", tiffWithMake("PENTAX Corporation", false), []Candidate{{ImagePEF, confidenceMake}, {ImageTiff, confidenceTiff}}},
+		{"SRW", tiffWithMake("SAMSUNG", false), []Candidate{{ImageSRW, confidenceMake}, {ImageTiff, confidenceTiff}}},
+		{"3FR", tiffWithMake("Hasselblad", false), []Candidate{{Image3FR, confidenceMake}, {ImageTiff, confidenceTiff}}},
+		{"DNG", tiffWithMake("PENTAX", true), []Candidate{{ImageDNG, confidenceTag}, {ImageTiff, 1 - confidenceTag}}},
+		{"Tiff", tiffWithMake("Epson", false), []Candidate{{ImageTiff, confidenceFormat}}},
+		{"Tiff/Truncated", append([]byte("MM\x00*\x00\x01\x00\x00"), make([]byte, 16)...), []Candidate{{ImageTiff, confidenceHeader}}},
+		{"CR3", append([]byte("\x00\x00\x00\x18ftypcrx \x00\x00\x00\x01crx isom"), make([]byte, 8)...), []Candidate{{ImageCR3, confidenceWeak}, {ImageCRM, 1 - confidenceWeak}}},
+		{"CR3/CRAW", crx(1), []Candidate{{ImageCR3, confidenceTag}, {ImageCRM, 1 - confidenceTag}}},
+		{"CRM/CRAW", crx(24), []Candidate{{ImageCRM, confidenceTag}, {ImageCR3, 1 - confidenceTag}}},
+		{"BMP", append([]byte("BM"), make([]byte, 24)...), []Candidate{{ImageBMP, confidenceWeak}}},
+	}
+	for _, bt := range bufTests {
+		t.Run(bt.name, func(t *testing.T) {
+			candidates, err := BufCandidates(bt.buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(candidates, bt.candidates) {
+				t.Errorf("Incorrect candidates wanted %v got %v", bt.candidates, candidates)
+			}
+		})
+	}
+
+	if _, err := ScanCandidates(bytes.NewReader([]byte("abcdefghijklmnop1234567890abcdefghijklmnopqrs"))); err != ErrImageTypeNotFound {
+		t.Errorf("Incorrect error wanted %v got %v", ErrImageTypeNotFound, err)
+	}
+	if _, err := BufCandidates(make([]byte, 10)); err != ErrDataLength {
+		t.Errorf("Incorrect error wanted %v got %v", ErrDataLength, err)
+	}
+}
+
+// tiffWithMake returns a Tiff header with an IFD0 of the Make and DNGVersion tags.
+func tiffWithMake(make string, dng bool) []byte {
+	buf := []byte("II*\x00\x08\x00\x00\x00\x02\x00")
+	buf = append(buf, 0x0f, 0x01, 0x02, 0x00, byte(len(make)+1), 0x00, 0x00, 0x00, 38, 0x00, 0x00, 0x00)
+	if dng {
+		buf = append(buf, 0x12, 0xc6, 0x01, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x04, 0x00, 0x00)
+	} else {
+		buf = append(buf, 0x00, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00)
+	}
+	buf = append(buf, 0x00, 0x00, 0x00, 0x00)
+	return append(append(buf, make...), 0x00)
+}
+
+func TestScanTiffMake(t *testing.T) {
+	tests := []struct {
+		name      string
+		buf       []byte
+		imageType ImageType
+	}{
+		{"PEF", tiffWithMake("PENTAX Corporation", false), ImagePEF},
+		{"SRW", tiffWithMake("SAMSUNG", false), ImageSRW},
+		{"3FR", tiffWithMake("Hasselblad", false), Image3FR},
+		{"DNG", tiffWithMake("PENTAX", true), ImageTiff},
+		{"Tiff", tiffWithMake("NIKON CORPORATION", false), ImageTiff},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if it, err := Buf(test.buf); it != test.imageType || err != nil {
+				t.Errorf("Incorrect Buf wanted %s got %s %v", test.imageType, it, err)
+			}
+			if it, err := Scan(bytes.NewReader(test.buf)); it != test.imageType || err != nil {
+				t.Errorf("Incorrect Scan wanted %s got %s %v", test.imageType, it, err)
+			}
+			if it, err := ScanBuf(bufio.NewReader(bytes.NewReader(test.buf))); it != test.imageType || err != nil {
+				t.Errorf("Incorrect ScanBuf wanted %s got %s %v", test.imageType, it, err)
+			}
+			if it, err := ReadAt(bytes.NewReader(test.buf)); it != test.imageType || err != nil {
+				t.Errorf("Incorrect ReadAt wanted %s got %s %v", test.imageType, it, err)
+			}
+			// IFD0 is not within the header
+			if it, _ := Buf(test.buf[:searchHeaderLength]); it != ImageTiff {
+				t.Errorf("Incorrect Buf of the header wanted %s got %s", ImageTiff, it)
+			}
+		})
+	}
+}
+
 func TestImageTypeIndices(t *testing.T) {
 	cases := map[ImageType]struct {
 		ext string
@@ -82,7 +335,7 @@ func TestImageTypeIndices(t *testing.T) {
 		ImageTiff:    {"TIFF", "image/tiff"},
 		ImageDNG:     {"DNG", "image/x-adobe-dng"},
 		ImageNEF:     {"NEF", "image/x-nikon-nef"},
-		ImagePanaRAW: {"RW2", "image/x-panasonic-raw"},
+		ImagePanaRAW: {"RAW", "image/x-panasonic-raw"},
 		ImageARW:     {"ARW", "image/x-sony-arw"},
 		ImageCRW:     {"CRW", "image/x-canon-crw"},
 		ImageGPR:     {"GPR", "image/x-gopro-gpr"},
@@ -92,6 +345,28 @@ func TestImageTypeIndices(t *testing.T) {
 		ImageXMP:     {"XMP", "application/rdf+xml"},
 		ImageAVIF:    {"avif", "image/avif"},
 		ImagePPM:     {"ppm", "image/x-portable-pixmap"},
+		ImageJP2K:    {"jp2", "image/jp2"},
+		ImageSVG:     {"svg", "image/svg+xml"},
+		ImageMAGICK:  {"magick", "image/magick"},
+		ImageJXL:     {"jxl", "image/jxl"},
+		ImageORF:     {"ORF", "image/x-olympus-orf"},
+		ImageRAF:     {"RAF", "image/x-fuji-raf"},
+		ImageRW2:     {"RW2", "image/x-panasonic-rw2"},
+		ImagePEF:     {"PEF", "image/x-pentax-pef"},
+		ImageSRW:     {"SRW", "image/x-samsung-srw"},
+		ImageX3F:     {"X3F", "image/x-sigma-x3f"},
+		ImageIIQ:     {"IIQ", "image/x-phaseone-iiq"},
+		Image3FR:     {"3FR", "image/x-hasselblad-3fr"},
+		ImageEXR:     {"exr", "image/x-exr"},
+		ImageQOI:     {"qoi", "image/qoi"},
+		ImageICO:     {"ico", "image/x-icon"},
+
+		ImageHEIC:         {"heic", "image/heic"},
+		ImageHEIFSequence: {"heifs", "image/heif-sequence"},
+		ImageAVIFSequence: {"avifs", "image/avif-sequence"},
+		ImageCRM:          {"CRM", "video/x-canon-crm"},
+		ImageMP4:          {"mp4", "video/mp4"},
+		ImageMOV:          {"mov", "video/quicktime"},
 	}
 
 	for it, exp := range cases {
@@ -186,19 +461,19 @@ func TestScanImageType(t *testing.T) {
 		{".JPG/NoExif", "20.jpg", "image/jpeg"},
 		{".JPG/GoPro", "hero6.jpg", "image/jpeg"},
 		{".JPEG", "21.jpeg", "image/jpeg"},
-		{".HEIC/iPhone", "1.heic", "image/heif"},
-		{".HEIC/Conv", "3.heic", "image/heif"},
-		{".HEIC/Alt", "4.heic", "image/heif"},
+		{".HEIC/iPhone", "1.heic", "image/heic"},
+		{".HEIC/Conv", "3.heic", "image/heic"},
+		{".HEIC/Alt", "4.heic", "image/heic"},
 		{".WEBP", "4.webp", "image/webp"},
 		{".GPR/GoPro", "hero6.gpr", "image/tiff"},
 		{".NEF/Nikon", "2.NEF", "image/tiff"},
 		{".ARW/Sony", "2.ARW", "image/tiff"},
 		{".DNG/Adobe", "1.DNG", "image/tiff"},
 		{".PNG", "0.png", "image/png"},
-		{".RW2", "4.RW2", "image/x-panasonic-raw"},
+		{".RW2", "4.RW2", "image/x-panasonic-rw2"},
 		{".XMP", "test.xmp", "application/rdf+xml"},
 		{".PSD", "0.psd", "image/vnd.adobe.photoshop"},
-		{".JP2/JPEG2000", "0.jp2", "image/jpeg"},
+		{".JP2/JPEG2000", "0.jp2", "image/jp2"},
 		{".BMP", "0.bmp", "image/bmp"},
 	}
 
@@ -291,3 +566,138 @@ func TestMsgp(t *testing.T) {
 	}
 
 }
+
+func TestRegister(t *testing.T) {
+	isScannerX := func(buf []byte) bool { return string(buf[:4]) == "SCNX" }
+	it := Register("ScannerX", "image/x-scannerx", ".scx", isScannerX)
+	if !it.IsRegistered() || ImageMOV.IsRegistered() {
+		t.Errorf("Incorrect IsRegistered of %d", it)
+	}
+	if it.String() != "image/x-scannerx" || it.Extension() != "scx" {
+		t.Errorf("Incorrect String or Extension wanted %s %s got %s %s", "image/x-scannerx", "scx", it.String(), it.Extension())
+	}
+	for _, str := range []string{"image/x-scannerx", ".scx", ".SCX"} {
+		if got := FromString(str); got != it {
+			t.Errorf("Incorrect FromString(%s) wanted %d got %d", str, it, got)
+		}
+	}
+
+	buf := append([]byte("SCNX"), make([]byte, searchHeaderLength)...)
+	if got, err := Scan(bytes.NewReader(buf)); got != it || err != nil {
+		t.Errorf("Incorrect Scan wanted %s got %s: %v", it, got, err)
+	}
+	// Built-in ImageTypes are unaffected
+	if got, _ := Buf(append([]byte("II*\x00"), make([]byte, searchHeaderLength)...)); got != ImageTiff {
+		t.Errorf("Incorrect Buf wanted %s got %s", ImageTiff, got)
+	}
+
+	// MarshalText and UnmarshalText
+	text, _ := it.MarshalText()
+	var got ImageType
+	if err := got.UnmarshalText(text); err != nil || got != it {
+		t.Errorf("Incorrect UnmarshalText wanted %s got %s", it, got)
+	}
+
+	// Duplicate content-types panic
+	for _, mime := range []string{"image/x-scannerx", "image/jpeg"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register of %s should panic", mime)
+				}
+			}()
+			Register("Duplicate", mime, "dup", isScannerX)
+		}()
+	}
+	// Duplicate extensions panic
+	for _, ext := range []string{"scx", ".SCX", "jpg", ".CR2"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register of %s should panic", ext)
+				}
+			}()
+			Register("Duplicate", "image/x-duplicate", ext, isScannerX)
+		}()
+	}
+	if FromString("image/x-duplicate") != ImageUnknown {
+		t.Error("Incorrect FromString wanted a Register that panics to not add an ImageType")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fileTests := []struct {
+		filename string
+		name     string
+		detected ImageType
+		ext      ImageType
+		mismatch Mismatch
+	}{
+		{"../testImages/JPEG.jpg", "image.jpg", ImageJPEG, ImageJPEG, MismatchNone},
+		{"../testImages/JPEG.jpg", "image.JPEG", ImageJPEG, ImageJPEG, MismatchNone},
+		{"../testImages/Heic.exif", "image.jpg", ImageHEIC, ImageJPEG, MismatchContainer},
+		{"../testImages/Heic.exif", "image.heif", ImageHEIC, ImageHEIF, MismatchAlias},
+		{"../testImages/Hero8.GPR", "image.tif", ImageGPR, ImageTiff, MismatchAlias},
+		{"../testImages/Hero8.GPR", "image.dng", ImageGPR, ImageDNG, MismatchAlias},
+		{"../testImages/NEF.exif", "image.NEF", ImageNEF, ImageNEF, MismatchNone},
+		{"../testImages/NEF.exif", "image.raw", ImageNEF, ImageRAW, MismatchAlias},
+		{"../testImages/CR2.exif", "image.png", ImageCR2, ImagePNG, MismatchContainer},
+		{"../testImages/JPEG.jpg", "image", ImageJPEG, ImageUnknown, MismatchUnknown},
+		{"../testImages/Unknown.exif", "image.jpg", ImageUnknown, ImageJPEG, MismatchUnknown},
+	}
+	for _, ft := range fileTests {
+		t.Run(ft.filename+"/"+ft.name, func(t *testing.T) {
+			f, err := os.Open(ft.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			v, err := Validate(ft.name, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Detected != ft.detected || v.Extension != ft.ext || v.Mismatch != ft.mismatch {
+				t.Errorf("Incorrect Validation wanted %s %s %s got %s %s %s", ft.detected, ft.ext, ft.mismatch, v.Detected, v.Extension, v.Mismatch)
+			}
+		})
+	}
+
+	jp2 := "\x00\x00\x00\x0cjP  \r\n\x87\n" + string(make([]byte, 32))
+	svg := "\xef\xbb\xbf<?xml version=\"1.0\"?>\n<!-- <svg> -->\n<!DOCTYPE svg [<!ENTITY a \"b\">]>\n<svg xmlns=\"http://www.w3.org/2000/svg\"><script>alert(1)</script></svg>"
+	bufTests := []struct {
+		name     string
+		content  string
+		markup   bool
+		detected ImageType
+		mismatch Mismatch
+	}{
+		{"image.png", svg, true, ImageSVG, MismatchDangerous},
+		{"image.svg", svg, true, ImageSVG, MismatchNone},
+		{"image.jpg", "  <SVG width=\"1\"></SVG>", true, ImageSVG, MismatchDangerous},
+		{"image.gif", "<!DOCTYPE html><html><body></body></html>", true, ImageUnknown, MismatchDangerous},
+		{"image.svg", "<html><script>alert(1)</script></html>", true, ImageUnknown, MismatchDangerous},
+		{"image.svg", "GIF89a" + string(make([]byte, 32)), false, ImageGIF, MismatchContainer},
+		{"image.png", "<?xml version=\"1.0\"?><x:xmpmeta></x:xmpmeta>", false, ImageUnknown, MismatchUnknown},
+		{"image.png", "", false, ImageUnknown, MismatchUnknown},
+		{"image.jpg", jp2, false, ImageJP2K, MismatchContainer},
+		{"image.j2k", jp2, false, ImageJP2K, MismatchNone},
+		{"image.jp2", "\xff\x4f\xff\x51" + string(make([]byte, 32)), false, ImageJP2K, MismatchNone},
+		// SVG after more than 64KB of comments is not read
+		{"image.png", "<!--" + strings.Repeat(" ", candidateScanLength) + "-->" + svg, false, ImageUnknown, MismatchUnknown},
+	}
+	for _, bt := range bufTests {
+		t.Run(bt.name, func(t *testing.T) {
+			v, err := Validate(bt.name, bytes.NewReader([]byte(bt.content)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Markup != bt.markup || v.Detected != bt.detected || v.Mismatch != bt.mismatch {
+				t.Errorf("Incorrect Validation wanted %t %s %s got %t %s %s", bt.markup, bt.detected, bt.mismatch, v.Markup, v.Detected, v.Mismatch)
+			}
+		})
+	}
+
+	if _, err := Validate("image.jpg", iotest.ErrReader(io.ErrClosedPipe)); err != io.ErrClosedPipe {
+		t.Errorf("Incorrect error wanted %v got %v", io.ErrClosedPipe, err)
+	}
+}
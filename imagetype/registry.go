@@ -0,0 +1,114 @@
+package imagetype
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// imageTypeCount is the number of built-in ImageTypes. Registered ImageTypes
+// follow the built-in ImageTypes.
+const imageTypeCount = int(ImageMOV) + 1
+
+// registeredType is an ImageType added with Register.
+type registeredType struct {
+	matcher   func(buf []byte) bool
+	name      string
+	mime      string
+	ext       string
+	imageType ImageType
+}
+
+var (
+	// registry is the []registeredType of the registered ImageTypes. It is
+	// replaced by Register and read without a lock.
+	registry   atomic.Value
+	registryMu sync.Mutex
+)
+
+// Register adds an ImageType with the content-type mime and the filename extension
+// ext, and returns it. Register is usually called from an init function.
+//
+// The matcher is called with the header of the image, at least 24 bytes, by Scan,
+// ScanBuf, ReadAt and Buf before the built-in magic numbers are checked, in the order
+// the ImageTypes were registered. It should not modify or retain the header.
+// FromString returns the ImageType of mime and ext, String returns mime and
+// Extension returns ext. The name identifies the ImageType in the panics of Register.
+//
+// Registered ImageTypes are numbered in the order they are registered, the value of
+// a registered ImageType is only valid within the process that registered it.
+//
+// Register panics if the matcher is nil, if the content-type or the extension, ignoring
+// case, is already of an ImageType or if there are more than 255 ImageTypes.
+func Register(name, mime, ext string, matcher func(buf []byte) bool) ImageType {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if matcher == nil {
+		panic("imagetype: Register matcher is nil for " + name)
+	}
+	if _, ok := imageTypeValues[mime]; ok || registeredFromString(mime) != ImageUnknown {
+		panic("imagetype: Register called twice for content-type " + mime + " of " + name)
+	}
+	ext = strings.TrimPrefix(ext, ".")
+	if _, ok := imageTypeExtensions["."+strings.ToLower(ext)]; ext != "" && (ok || registeredFromString("."+ext) != ImageUnknown) {
+		panic("imagetype: Register called twice for extension " + ext + " of " + name)
+	}
+	types := registeredTypes()
+	if imageTypeCount+len(types) > math.MaxUint8 {
+		panic("imagetype: Register too many ImageTypes for " + name)
+	}
+	rt := registeredType{
+		matcher:   matcher,
+		name:      name,
+		mime:      mime,
+		ext:       ext,
+		imageType: ImageType(imageTypeCount + len(types)),
+	}
+	// Copy on write, the registry is read without a lock
+	registry.Store(append(types[:len(types):len(types)], rt))
+	return rt.imageType
+}
+
+// IsRegistered returns true if the ImageType was added with Register.
+func (it ImageType) IsRegistered() bool {
+	_, ok := registeredImageType(it)
+	return ok
+}
+
+// registeredTypes returns the registered ImageTypes.
+func registeredTypes() []registeredType {
+	types, _ := registry.Load().([]registeredType)
+	return types
+}
+
+// registeredImageType returns the registeredType of the ImageType.
+func registeredImageType(it ImageType) (registeredType, bool) {
+	types := registeredTypes()
+	if i := int(it) - imageTypeCount; i >= 0 && i < len(types) {
+		return types[i], true
+	}
+	return registeredType{}, false
+}
+
+// registeredFromString returns the registered ImageType of the content-type or
+// filename extension str.
+func registeredFromString(str string) ImageType {
+	for _, rt := range registeredTypes() {
+		if rt.mime == str || rt.ext != "" && strings.EqualFold("."+rt.ext, str) {
+			return rt.imageType
+		}
+	}
+	return ImageUnknown
+}
+
+// matchRegistered returns the first registered ImageType that matches the header.
+func matchRegistered(buf []byte) ImageType {
+	for _, rt := range registeredTypes() {
+		if rt.matcher(buf) {
+			return rt.imageType
+		}
+	}
+	return ImageUnknown
+}
@@ -14,23 +14,40 @@ var (
 const (
 	// searchHeaderLength is the number of bytes to read while searching for an Image Header
 	searchHeaderLength = 24
+
+	// tiffProbeLength is the number of bytes read to find the Make tag of IFD0 of an image
+	// with a Tiff header, that identifies PEF, SRW and 3FR images.
+	tiffProbeLength = 1024
 )
 
 // Scan reads from the reader and returns an imageType based on
 // underlying rules. Returns ImageUnknown and ErrImageTypeNotFound if imageType was not
 // identified.
+//
+// PEF, SRW and 3FR images are identified by the Make tag of IFD0, up to 1KB are read
+// from an image with a Tiff header. They are ImageTiff if IFD0 is not within it.
 func Scan(r io.Reader) (imageType ImageType, err error) {
 	// Parse Header for an ImageType
 	br, ok := r.(*bufio.Reader)
-	if !ok || br.Size() < searchHeaderLength {
-		br = bufio.NewReaderSize(r, searchHeaderLength)
+	if ok && br.Size() >= searchHeaderLength {
+		return ScanBuf(br)
+	}
+	br = bufio.NewReaderSize(r, searchHeaderLength)
+	if imageType, err = ScanBuf(br); imageType != ImageTiff {
+		return imageType, err
 	}
-	return ScanBuf(br)
+	// The header is too short to peek at IFD0
+	buf := make([]byte, tiffProbeLength)
+	n, _ := io.ReadFull(br, buf)
+	return tiffImageType(buf[:n]), nil
 }
 
 // ScanBuf peeks at a bufio.Reader and returns an imageType based on
 // underlying rules. Returns ImageUnknown and ErrImageTypeNotFound if imageType was not
 // identified.
+//
+// PEF, SRW and 3FR images are identified like Scan, up to 1KB or the size of the
+// bufio.Reader are peeked at.
 func ScanBuf(br *bufio.Reader) (imageType ImageType, err error) {
 	var buf []byte
 
@@ -39,31 +56,49 @@ func ScanBuf(br *bufio.Reader) (imageType ImageType, err error) {
 		return ImageUnknown, err
 	}
 
-	return Buf(buf[:])
+	if imageType, err = Buf(buf[:]); imageType == ImageTiff {
+		// Peek at IFD0 of a Tiff header, the data may be shorter
+		buf, _ = br.Peek(min(br.Size(), tiffProbeLength))
+		imageType = tiffImageType(buf)
+	}
+	return imageType, err
 }
 
 // ReadAt reads from the reader at the given offset and returns an imageType based on
 // underlying rules. Returns ImageUnknown and an error if imageType was not
 // identified.
+//
+// PEF, SRW and 3FR images are identified like Scan.
 func ReadAt(r io.ReaderAt) (imageType ImageType, err error) {
 	buf := [searchHeaderLength]byte{}
 	if _, err = r.ReadAt(buf[:], 0); err != nil {
 		return ImageUnknown, err
 	}
 
-	return Buf(buf[:])
+	if imageType, err = Buf(buf[:]); imageType == ImageTiff {
+		// Read IFD0 of a Tiff header, the data may be shorter
+		probe := make([]byte, tiffProbeLength)
+		n, _ := r.ReadAt(probe, 0)
+		imageType = tiffImageType(probe[:n])
+	}
+	return imageType, err
 }
 
 // Buf parses a []byte for image magic numbers that identify the imagetype.
 // If []byte is less than searchHeaderLength returns ImageUnknown and ErrDataLength
 // If imageType was not identified returns ImageUnknown and ErrImageTypeNotFound
+//
+// PEF, SRW and 3FR images are identified by the Make tag of IFD0 if it is within
+// []byte, otherwise they are ImageTiff.
 func Buf(buf []byte) (imageType ImageType, err error) {
 	if len(buf) < searchHeaderLength {
 		return ImageUnknown, ErrDataLength
 	}
 
 	// Parse Header for an ImageType
-	imageType = parseBuffer(buf)
+	if imageType = parseBuffer(buf); imageType == ImageTiff {
+		imageType = tiffImageType(buf)
+	}
 
 	// Check if ImageType is Unknown
 	if imageType == ImageUnknown {
@@ -76,14 +111,24 @@ func Buf(buf []byte) (imageType ImageType, err error) {
 // that identify the imagetype. Returns an ImageType. Returns ImageUnknown
 // when imagetype was not identified.
 func parseBuffer(buf []byte) ImageType {
+	// Registered ImageTypes
+	if it := matchRegistered(buf); it != ImageUnknown {
+		return it
+	}
+
 	// JPEG Header
 	if isJPEG(buf) {
 		return ImageJPEG
 	}
 
-	// JPEG2000 Header
-	if isJPEG2000(buf) {
-		return ImageJPEG
+	// JPEG2000 Header and codestream
+	if isJPEG2000(buf) || isJ2K(buf) {
+		return ImageJP2K
+	}
+
+	// JPEG XL Header
+	if isJXL(buf) {
+		return ImageJXL
 	}
 
 	// Canon CRW Header
@@ -98,25 +143,42 @@ func parseBuffer(buf []byte) ImageType {
 
 	// ISOBMFF Header
 	if isFTYPBox(buf) {
-		// Canon CR3 Header
-		if isCR3(buf) {
-			return ImageCR3
-		}
-		// AVIF Header
-		if isAVIF(buf) {
-			return ImageAVIF
-		}
-		// Heif Header
-		if isHeif(buf) {
-			return ImageHEIF
+		// CR3, HEIC, HEIF, AVIF, their sequences and MP4/MOV videos by brand
+		if it := ftypImageType(buf); it != ImageUnknown {
+			return it
 		}
 	}
 
-	// Panasonic/Leica Raw Header
+	// Panasonic/Leica RW2 Header
 	if isRW2(buf) {
+		return ImageRW2
+	}
+
+	// Panasonic Raw Header
+	if isPanaRAW(buf) {
 		return ImagePanaRAW
 	}
 
+	// Olympus Raw Header
+	if isORF(buf) {
+		return ImageORF
+	}
+
+	// Fujifilm Raw Header
+	if isRAF(buf) {
+		return ImageRAF
+	}
+
+	// Sigma Raw Header
+	if isX3F(buf) {
+		return ImageX3F
+	}
+
+	// Phase One Raw Header
+	if isIIQ(buf) {
+		return ImageIIQ
+	}
+
 	// Tiff Header
 	if isTiff(buf) {
 		return ImageTiff
@@ -156,5 +218,25 @@ func parseBuffer(buf []byte) ImageType {
 		return ImagePPM
 	}
 
+	// QuickTime movie without an ftyp box
+	if isMOVAtom(buf) {
+		return ImageMOV
+	}
+
+	// OpenEXR Header
+	if isEXR(buf) {
+		return ImageEXR
+	}
+
+	// QOI Header
+	if isQOI(buf) {
+		return ImageQOI
+	}
+
+	// ICO Header
+	if isICO(buf) {
+		return ImageICO
+	}
+
 	return ImageUnknown
 }
@@ -0,0 +1,188 @@
+package imagetype
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+)
+
+// Mismatch classifies the difference between the ImageType of the content of a
+// file and the ImageType of its filename extension.
+type Mismatch uint8
+
+// Mismatch classifications of Validate
+const (
+	// MismatchNone is a filename extension of the ImageType of the content.
+	MismatchNone Mismatch = iota
+	// MismatchAlias is a filename extension of the same format family as the content,
+	// for example a DNG or NEF image named ".tif" or a HEIC image named ".heif".
+	MismatchAlias
+	// MismatchContainer is a filename extension of a different format, for example a
+	// HEIC image named ".jpg" or a video named ".heic".
+	MismatchContainer
+	// MismatchDangerous is content that is not a raster image and may be active when
+	// rendered, SVG or HTML, with a filename extension that is not of its type. For
+	// example an SVG image named ".png".
+	MismatchDangerous
+	// MismatchUnknown is content or a filename extension that was not identified.
+	MismatchUnknown
+)
+
+// String returns the name of the Mismatch.
+func (m Mismatch) String() string {
+	switch m {
+	case MismatchNone:
+		return "none"
+	case MismatchAlias:
+		return "alias"
+	case MismatchContainer:
+		return "container"
+	case MismatchDangerous:
+		return "dangerous"
+	}
+	return "unknown"
+}
+
+// Validation is the result of Validate.
+type Validation struct {
+	// Detected is the ImageType of the content, the candidate of ScanCandidates with
+	// the highest Confidence or ImageSVG. ImageUnknown if it was not identified.
+	Detected ImageType
+	// Extension is the ImageType of the filename extension (see FromString).
+	Extension ImageType
+	// Mismatch is the classification of the difference between Detected and Extension.
+	Mismatch Mismatch
+	// Markup is true if the content is SVG or HTML markup.
+	Markup bool
+}
+
+// Validate compares the ImageType of the content of r with the ImageType of the extension
+// of filename, for example to reject or rename files when they are uploaded. Up to 64KB are
+// read from r and the content is identified like ScanCandidates, SVG and HTML markup is
+// identified as well. SVG that is after more than 64KB of XML declaration, comments or
+// DOCTYPE is not read and is not MismatchDangerous.
+//
+// An error is only returned if r can not be read, content that was not identified is
+// MismatchUnknown.
+func Validate(filename string, r io.Reader) (Validation, error) {
+	buf := make([]byte, candidateScanLength)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return Validation{}, err
+	}
+	return validateBuf(filename, buf[:n]), nil
+}
+
+// validateBuf returns the Validation of the content buf of the file filename.
+func validateBuf(filename string, buf []byte) (v Validation) {
+	v.Extension = FromString(filepath.Ext(filename))
+	switch {
+	case isSVG(buf):
+		v.Detected, v.Markup = ImageSVG, true
+	case isHTML(buf):
+		v.Markup = true
+	default:
+		if c, err := BufCandidates(buf); err == nil {
+			v.Detected = c[0].ImageType
+		}
+	}
+	v.Mismatch = mismatch(v)
+	return v
+}
+
+// mismatch classifies the difference between the Detected and Extension ImageTypes.
+func mismatch(v Validation) Mismatch {
+	switch {
+	case v.Markup && (v.Detected != ImageSVG || v.Extension != ImageSVG):
+		return MismatchDangerous
+	case v.Detected == ImageUnknown || v.Extension == ImageUnknown:
+		return MismatchUnknown
+	case v.Detected == v.Extension:
+		return MismatchNone
+	case formatFamily(v.Detected) == formatFamily(v.Extension),
+		v.Extension == ImageRAW && isCameraRaw(v.Detected):
+		return MismatchAlias
+	}
+	return MismatchContainer
+}
+
+// formatFamily returns the ImageType of the family of formats of the ImageType.
+// The ImageTypes of a family share a container or a header.
+func formatFamily(it ImageType) ImageType {
+	switch it {
+	case ImageTiff, ImageDNG, ImageNEF, ImageARW, ImageCR2, ImagePEF, ImageSRW, Image3FR, ImageGPR:
+		// Tiff header
+		return ImageTiff
+	case ImagePanaRAW, ImageRW2:
+		return ImagePanaRAW
+	case ImageHEIF, ImageHEIC, ImageHEIFSequence:
+		return ImageHEIF
+	case ImageAVIF, ImageAVIFSequence:
+		return ImageAVIF
+	case ImageCR3, ImageCRM:
+		return ImageCR3
+	case ImageMP4, ImageMOV:
+		return ImageMP4
+	}
+	return it
+}
+
+// isCameraRaw returns true if the ImageType is a Camera Raw image.
+func isCameraRaw(it ImageType) bool {
+	switch it {
+	case ImageDNG, ImageNEF, ImageARW, ImageCR2, ImageCR3, ImageCRW, ImagePEF, ImageSRW, Image3FR, ImageGPR,
+		ImagePanaRAW, ImageRW2, ImageORF, ImageRAF, ImageX3F, ImageIIQ:
+		return true
+	}
+	return false
+}
+
+// isSVG returns true if buf is an SVG image. An XML declaration, comments and a
+// DOCTYPE may be before the svg element.
+func isSVG(buf []byte) bool {
+	buf = trimMarkup(buf)
+	for len(buf) > 0 && buf[0] == '<' {
+		switch {
+		case hasPrefixFold(buf, "<svg"):
+			return true
+		case hasPrefixFold(buf, "<?xml"), hasPrefixFold(buf, "<!doctype svg"), hasPrefixFold(buf, "<!--"):
+			// Skip the declaration, DOCTYPE with its internal subset or comment
+			end := []byte(">")
+			if hasPrefixFold(buf, "<!--") {
+				end = []byte("-->")
+			} else if i := bytes.IndexByte(buf, '['); i >= 0 && i < bytes.IndexByte(buf, '>') {
+				end = []byte("]>")
+			}
+			i := bytes.Index(buf, end)
+			if i < 0 {
+				return false
+			}
+			buf = trimMarkup(buf[i+len(end):])
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// isHTML returns true if buf starts with an HTML element, DOCTYPE or script.
+func isHTML(buf []byte) bool {
+	buf = trimMarkup(buf)
+	for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body", "<script", "<iframe"} {
+		if hasPrefixFold(buf, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimMarkup trims a UTF-8 byte order mark and white space from the start of buf.
+func trimMarkup(buf []byte) []byte {
+	buf = bytes.TrimPrefix(buf, []byte("\xef\xbb\xbf"))
+	return bytes.TrimLeft(buf, " \t\r\n")
+}
+
+// hasPrefixFold returns true if buf starts with the ASCII prefix, ignoring case.
+func hasPrefixFold(buf []byte, prefix string) bool {
+	return len(buf) >= len(prefix) && bytes.EqualFold(buf[:len(prefix)], []byte(prefix))
+}
@@ -0,0 +1,164 @@
+// Package logging builds log records for the log/slog loggers of the imagemeta packages.
+//
+// An Event is built with chained calls and sent to the slog.Handler of the Logger
+// with Msg or Send. NewEvent returns nil when the level is not enabled, all methods of a nil
+// Event are no-ops so that disabled log statements do not allocate.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// LevelTrace is the slog.Level of trace logging. The name of the calling function
+// is logged at this level.
+const LevelTrace = slog.LevelDebug - 4
+
+// Discard is a Logger that discards all log records.
+var Discard = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that is never enabled.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// Enabled returns true when l logs records of the given level.
+func Enabled(l *slog.Logger, level slog.Level) bool {
+	return l != nil && l.Enabled(context.Background(), level)
+}
+
+// Event is a log record of a Logger.
+type Event struct {
+	l     *slog.Logger
+	level slog.Level
+	attrs []slog.Attr
+}
+
+// NewEvent returns a new Event of l with the given level. Returns nil when the level is not enabled.
+func NewEvent(l *slog.Logger, level slog.Level) *Event {
+	if !Enabled(l, level) {
+		return nil
+	}
+	return &Event{l: l, level: level, attrs: make([]slog.Attr, 0, 8)}
+}
+
+func (e *Event) add(a slog.Attr) *Event {
+	if e != nil {
+		e.attrs = append(e.attrs, a)
+	}
+	return e
+}
+
+// Str adds the string val with key to the Event.
+func (e *Event) Str(key, val string) *Event {
+	return e.add(slog.String(key, val))
+}
+
+// Strs adds the strings vals with key to the Event.
+func (e *Event) Strs(key string, vals []string) *Event {
+	return e.add(slog.Any(key, vals))
+}
+
+// Stringer adds the String of val with key to the Event.
+func (e *Event) Stringer(key string, val fmt.Stringer) *Event {
+	if e == nil {
+		return e
+	}
+	return e.add(slog.String(key, val.String()))
+}
+
+// Int adds the int val with key to the Event.
+func (e *Event) Int(key string, val int) *Event {
+	return e.add(slog.Int(key, val))
+}
+
+// Int8 adds the int8 val with key to the Event.
+func (e *Event) Int8(key string, val int8) *Event {
+	return e.add(slog.Int(key, int(val)))
+}
+
+// Int32 adds the int32 val with key to the Event.
+func (e *Event) Int32(key string, val int32) *Event {
+	return e.add(slog.Int(key, int(val)))
+}
+
+// Int64 adds the int64 val with key to the Event.
+func (e *Event) Int64(key string, val int64) *Event {
+	return e.add(slog.Int64(key, val))
+}
+
+// Uint8 adds the uint8 val with key to the Event.
+func (e *Event) Uint8(key string, val uint8) *Event {
+	return e.add(slog.Uint64(key, uint64(val)))
+}
+
+// Uint16 adds the uint16 val with key to the Event.
+func (e *Event) Uint16(key string, val uint16) *Event {
+	return e.add(slog.Uint64(key, uint64(val)))
+}
+
+// Uint32 adds the uint32 val with key to the Event.
+func (e *Event) Uint32(key string, val uint32) *Event {
+	return e.add(slog.Uint64(key, uint64(val)))
+}
+
+// Uint64 adds the uint64 val with key to the Event.
+func (e *Event) Uint64(key string, val uint64) *Event {
+	return e.add(slog.Uint64(key, val))
+}
+
+// Err adds err with the key "error" to the Event.
+func (e *Event) Err(err error) *Event {
+	return e.AnErr("error", err)
+}
+
+// AnErr adds err with key to the Event. A nil err is not added.
+func (e *Event) AnErr(key string, err error) *Event {
+	if err == nil {
+		return e
+	}
+	return e.add(slog.Any(key, err))
+}
+
+// Object adds the group of val with key to the Event.
+func (e *Event) Object(key string, val slog.LogValuer) *Event {
+	return e.add(slog.Any(key, val))
+}
+
+// Msg sends the Event with msg to the Handler of the Logger.
+func (e *Event) Msg(msg string) {
+	if e == nil {
+		return
+	}
+	r := slog.NewRecord(time.Now(), e.level, msg, 0)
+	r.AddAttrs(e.attrs...)
+	_ = e.l.Handler().Handle(context.Background(), r)
+}
+
+// Msgf sends the Event with a formatted msg to the Handler of the Logger.
+func (e *Event) Msgf(format string, v ...interface{}) {
+	if e == nil {
+		return
+	}
+	e.Msg(fmt.Sprintf(format, v...))
+}
+
+// Send sends the Event without a message to the Handler of the Logger.
+func (e *Event) Send() {
+	e.Msg("")
+}
+
+// Array returns a slog.Value of a group of the values keyed by their index.
+func Array(vals ...slog.Value) slog.Value {
+	attrs := make([]slog.Attr, len(vals))
+	for i, v := range vals {
+		attrs[i] = slog.Attr{Key: strconv.Itoa(i), Value: v}
+	}
+	return slog.GroupValue(attrs...)
+}
@@ -0,0 +1,31 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestEvent(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// Disabled levels return a nil Event
+	if e := NewEvent(l, slog.LevelDebug); e != nil {
+		t.Errorf("Incorrect Event wanted nil got %v", e)
+	}
+	NewEvent(l, slog.LevelDebug).Str("key", "value").Uint32("offset", 8).Send()
+	NewEvent(Discard, slog.LevelError).Msg("discarded")
+	if buf.Len() != 0 {
+		t.Fatalf("Incorrect disabled Event wanted no output got %s", buf.String())
+	}
+
+	NewEvent(l, slog.LevelError).Str("marker", "APP1").Uint16("length", 16).Err(errors.New("EOF")).Msg("decode")
+	for _, s := range []string{"level=ERROR", "msg=decode", "marker=APP1", "length=16", "error=EOF"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("Incorrect Event wanted %q got %s", s, buf.String())
+		}
+	}
+}
@@ -2,18 +2,15 @@ package isobmff
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"os"
 	"testing"
 
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/tdelov/imagemeta/imagetype"
 )
 
-func init() {
-	Logger = log.Level(zerolog.PanicLevel)
-}
-
 func BenchmarkCR3(b *testing.B) {
 	dir := "../../test/img/"
 	f, err := os.Open(dir + "/" + "CanonR6_1.CR3")
@@ -57,3 +54,82 @@ func BenchmarkCR3(b *testing.B) {
 // BenchmarkCR3-12    	 1029416	      1160 ns/op	     124 B/op	       0 allocs/op
 // BenchmarkCR3-12    	 1456377	       783.9 ns/op	      88 B/op	       0 allocs/op
 // BenchmarkFTYP-12    	 9032180	       135.2 ns/op	      14 B/op	       0 allocs/op
+
+func TestReadFTYP(t *testing.T) {
+	ftypTests := []struct {
+		filename  string
+		imageType imagetype.ImageType
+	}{
+		{"../testImages/AVIF.avif", imagetype.ImageAVIF},
+		{"../testImages/AVIF2.avif", imagetype.ImageAVIF},
+		{"../testImages/Heic.exif", imagetype.ImageHEIC},
+	}
+	for _, ft := range ftypTests {
+		t.Run(ft.filename, func(t *testing.T) {
+			buf, err := os.ReadFile(ft.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := NewReader(bytes.NewReader(buf))
+			defer r.Close()
+			if err = r.ReadFTYP(); err != nil {
+				t.Fatal(err)
+			}
+			if it := r.FileType().ImageType; it != ft.imageType {
+				t.Errorf("Incorrect ImageType wanted %s got %s", ft.imageType, it)
+			}
+		})
+	}
+
+	// Compatible brands beyond the 24 byte header of imagetype.Scan
+	buf := []byte("\x00\x00\x00\x1cftypisom\x00\x00\x00\x00isomiso2avis")
+	r := NewReader(bytes.NewReader(buf))
+	defer r.Close()
+	if err := r.ReadFTYP(); err != nil {
+		t.Fatal(err)
+	}
+	if ftyp := r.FileType(); ftyp.ImageType != imagetype.ImageAVIFSequence || ftyp.MajorBrand != brandIsom || ftyp.Compatible[2] != brandAvis {
+		t.Errorf("Incorrect FileTypeBox %v %s", ftyp.MajorBrand, ftyp.ImageType)
+	}
+}
+
+// cancelAfterContext is a context that is canceled after its Err is called n times.
+type cancelAfterContext struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfterContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestReadMetadataCanceled(t *testing.T) {
+	buf, err := os.ReadFile("../testImages/Heic.exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The meta box is read with each of its inner boxes canceled
+	for n := 1; ; n++ {
+		ctx := &cancelAfterContext{Context: context.Background(), n: n}
+		r := NewReader(bytes.NewReader(buf))
+		r.SetContext(ctx)
+		if err = r.ReadFTYP(); err != nil {
+			t.Fatal(err)
+		}
+		err = r.ReadMetadata()
+		r.Close()
+		if ctx.n >= 0 {
+			// The meta box was read before the context was canceled
+			if err != nil {
+				t.Fatal(err)
+			}
+			break
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Incorrect error canceled after %d boxes wanted %v got %v", n, context.Canceled, err)
+		}
+	}
+}
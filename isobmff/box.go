@@ -1,9 +1,11 @@
 package isobmff
 
 import (
+	"io"
+	"log/slog"
+
 	"github.com/tdelov/imagemeta/meta"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog"
 )
 
 // box is an ISOBMFF box
@@ -46,15 +48,18 @@ func (b *box) Discard(n int) (int, error) {
 }
 
 // Read the bytes from underlying reader. Is limited by the
-// constrains of the box
+// constrains of the box. Returns io.EOF at the end of the box.
 func (b *box) Read(p []byte) (n int, err error) {
-	if b.remain >= len(p) {
-		//fmt.Println(b.remain)
-		n, err = b.reader.br.Read(p)
-		b.adjust(n)
-		return n, err
+	if b.remain <= 0 {
+		return 0, io.EOF
 	}
-	return 0, ErrRemainLengthInsufficient
+	if len(p) > b.remain {
+		p = p[:b.remain]
+	}
+	n, err = b.reader.br.Read(p)
+	b.reader.offset += n
+	b.adjust(n)
+	return n, err
 }
 
 func (b *box) adjust(n int) {
@@ -79,6 +84,9 @@ func (b *box) readInnerBox() (inner box, next bool, err error) {
 	if b.remain < 8 {
 		return inner, false, nil
 	}
+	if err = b.reader.contextErr(); err != nil {
+		return inner, false, err
+	}
 
 	buf, err := b.Peek(16)
 	if err != nil {
@@ -90,7 +98,7 @@ func (b *box) readInnerBox() (inner box, next bool, err error) {
 	// Read box size and box type
 	inner.size = int64(bmffEndian.Uint32(buf[:4]))
 	inner.remain = int(inner.size)
-	inner.boxType = boxTypeFromBuf(buf[4:8])
+	inner.boxType = b.reader.boxTypeFromBuf(buf[4:8])
 
 	switch inner.size {
 	case 1:
@@ -114,6 +122,29 @@ func (b *box) readInnerBox() (inner box, next bool, err error) {
 	return inner, true, err
 }
 
+// closeContext closes the box after its inner boxes were read. The errors of the inner
+// boxes are warnings, the error of the context of the Reader is returned so that
+// cancellation is not read as a truncated box.
+func (b *box) closeContext() error {
+	if err := b.reader.contextErr(); err != nil {
+		return err
+	}
+	return b.close()
+}
+
+// path returns the path of the box from the outermost box. e.g. "meta/iinf/infe"
+func (b *box) path() string {
+	if b.outer == nil {
+		return b.boxType.String()
+	}
+	return b.outer.path() + "/" + b.boxType.String()
+}
+
+// decodeError returns a DecodeError for err with the path of the box.
+func (b *box) decodeError(err error) error {
+	return meta.ContainerError(err, b.path(), int64(b.offset))
+}
+
 // readUint16 from box
 func (b *box) readUint16() (uint16, error) {
 	buf, err := b.Peek(2)
@@ -137,12 +168,13 @@ func (b *box) readUUID() (u meta.UUID, err error) {
 	return u, err
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (b box) MarshalZerologObject(e *zerolog.Event) {
-	e.Str("boxType", b.boxType.String()).Int("offset", b.offset).Int64("size", b.size)
+// LogValue is a slog.LogValuer interface for logging
+func (b box) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("boxType", b.boxType.String()), slog.Int("offset", b.offset), slog.Int64("size", b.size)}
 	if b.flags != 0 {
-		e.Object("flags", b.flags)
+		attrs = append(attrs, slog.Any("flags", b.flags))
 	}
+	return slog.GroupValue(attrs...)
 }
 
 // BoxType is an ISOBMFF box
@@ -157,7 +189,7 @@ func (t boxType) String() string {
 	return "nnnn"
 }
 
-func boxTypeFromBuf(buf []byte) boxType {
+func (r *Reader) boxTypeFromBuf(buf []byte) boxType {
 	str := string(buf[:4])
 	if str == "infe" { // inital check for performance reasons
 		return typeInfe
@@ -165,8 +197,8 @@ func boxTypeFromBuf(buf []byte) boxType {
 	if b, ok := mapStringBoxType[str]; ok {
 		return b
 	}
-	if logLevelError() {
-		logErrorMsg("BoxType", "error BoxType '%s' unknown", buf)
+	if r.logLevelError() {
+		r.logErrorMsg("BoxType", "error BoxType '%s' unknown", buf)
 	}
 	return typeUnknown
 }
@@ -205,9 +237,9 @@ func (f flags) version() uint8 {
 	return uint8(f >> 24)
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (f flags) MarshalZerologObject(e *zerolog.Event) {
-	e.Uint8("version", f.version()).Uint32("flags", f.flags())
+// LogValue is a slog.LogValuer interface for logging
+func (f flags) LogValue() slog.Value {
+	return slog.GroupValue(slog.Uint64("version", uint64(f.version())), slog.Uint64("flags", uint64(f.flags())))
 }
 
 // Common box types.
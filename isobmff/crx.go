@@ -2,11 +2,12 @@ package isobmff
 
 import (
 	"io"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2/ifds"
 	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/internal/logging"
 	"github.com/tdelov/imagemeta/meta"
-	"github.com/rs/zerolog"
 )
 
 // CrxMoovBox is a Canon Raw Moov Box
@@ -51,18 +52,16 @@ func readCrxMoovBox(b *box, exifReader ExifReader) (crx CrxMoovBox, err error) {
 		//case typeTHMB:
 		//case typeCCTP:
 		default:
-			if logLevelDebug() {
-				logDebug().Object("box", inner).Send()
+			if b.reader.logLevelDebug() {
+				b.reader.logDebug().Object("box", inner).Send()
 			}
 		}
-		if err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
-		}
+		b.reader.warn(&inner, err)
 		if err = inner.close(); err != nil {
 			return
 		}
 	}
-	return crx, b.close()
+	return crx, b.closeContext()
 }
 
 // CMT Box
@@ -73,9 +72,7 @@ func readCMTBox(b *box, exifReader func(r io.Reader, h meta.ExifHeader) error, i
 		return
 	}
 	if exifReader != nil {
-		if err = exifReader(b, header); err != nil && logLevelError() {
-			logError().Object("box", b).Object("exifReader", header).Send()
-		}
+		b.reader.warn(b, exifReader(b, header))
 	}
 	return header, b.close()
 }
@@ -99,8 +96,8 @@ func readCNCVBox(b *box) (cncv CNCVBox, err error) {
 		return CNCVBox{}, err
 	}
 	copy(cncv.version[:], buf[:30])
-	if logLevelInfo() {
-		logInfo().Object("box", b).Str("CNCV", string(cncv.version[:])).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfo().Object("box", b).Str("CNCV", string(cncv.version[:])).Send()
 	}
 	return cncv, b.close()
 }
@@ -115,6 +112,9 @@ func readCTBOBox(b *box) (ctbo CTBOBox, err error) {
 	if err != nil {
 		return ctbo, err
 	}
+	if len(buf) < 4 {
+		return ctbo, ErrBufLength
+	}
 	// Item Count
 	ctbo.count = crxEndian.Uint32(buf[0:4])
 
@@ -126,8 +126,8 @@ func readCTBOBox(b *box) (ctbo CTBOBox, err error) {
 			ctbo.items[idx].length = crxEndian.Uint64(buf[i+12 : i+20])
 		}
 	}
-	if logLevelInfo() {
-		logInfo().Object("box", b).Array("items", ctbo).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfo().Object("box", b).Object("items", ctbo).Send()
 	}
 	return ctbo, b.close()
 }
@@ -139,15 +139,17 @@ type CTBOBox struct {
 	count uint32
 }
 
-// MarshalZerologArray is a zerolog interface for logging
-func (ctbo CTBOBox) MarshalZerologArray(a *zerolog.Array) {
-	for i := 0; i < int(ctbo.count); i++ {
+// LogValue is a slog.LogValuer interface for logging
+func (ctbo CTBOBox) LogValue() slog.Value {
+	items := make([]slog.Value, 0, len(ctbo.items))
+	for i := 0; i < int(ctbo.count) && i < len(ctbo.items); i++ {
 		item := ctbo.items[i]
 		if item.length == 0 && item.offset == 0 {
 			break
 		}
-		a.Object(item)
+		items = append(items, item.LogValue())
 	}
+	return logging.Array(items...)
 }
 
 // Trak
@@ -167,13 +169,13 @@ func readCrxTrakBox(b *box) (t CR3Trak, err error) {
 	//	case typeCo64:
 	//
 	//	}
-	//	if logLevelInfo() {
-	//		logInfoBox(inner)
+	//	if b.reader.logLevelInfo() {
+	//		b.reader.logInfoBox(inner)
 	//	}
 	//	inner.close()
 	//}
-	if logLevelInfo() {
-		logInfo().Object("box", b).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfo().Object("box", b).Send()
 	}
 	return t, b.close()
 }
@@ -12,6 +12,7 @@ var (
 	ErrItemTypeLength           = errors.New("insufficient itemType Length")
 	errLargeBox                 = errors.New("unexpectedly large box")
 	ErrWrongBoxType             = errors.New("error wrong box type")
+	ErrIlocFieldSize            = errors.New("error iloc field size not 0, 4 or 8")
 
 	// ErrInfeVersionNotSupported is returned when an infe box with an unsupported was found.
 	ErrInfeVersionNotSupported = errors.New("infe box version not supported")
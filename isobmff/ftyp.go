@@ -1,6 +1,8 @@
 package isobmff
 
 import (
+	"github.com/tdelov/imagemeta/imagetype"
+
 	"github.com/pkg/errors"
 )
 
@@ -27,16 +29,20 @@ func parseFileTypeBox(b *box) (ftyp FileTypeBox, err error) {
 	if err != nil {
 		return ftyp, err
 	}
-	ftyp.MajorBrand = brandFromBuf(buf[:4])
+	if len(buf) < 8 {
+		return ftyp, ErrBufLength
+	}
+	ftyp.MajorBrand = b.reader.brandFromBuf(buf[:4])
 	copy(ftyp.MinorVersion[:4], buf[4:8])
+	ftyp.ImageType = imagetype.FromFTYP(buf)
 
 	// Read maximum 7 Compatible brands
-	for i, compatibleBrand := 8, 0; i < b.remain && compatibleBrand < maxBrandCount; compatibleBrand++ {
-		ftyp.Compatible[compatibleBrand] = brandFromBuf(buf[i : i+4])
+	for i, compatibleBrand := 8, 0; i+4 <= len(buf) && compatibleBrand < maxBrandCount; compatibleBrand++ {
+		ftyp.Compatible[compatibleBrand] = b.reader.brandFromBuf(buf[i : i+4])
 		i += 4
 	}
-	if logLevelInfo() {
-		logInfoBox(b).Str("MajorBrand", ftyp.MajorBrand.String()).Str("MinorVersion", string(ftyp.MinorVersion[:])).Strs("MinorBrands", minorBrandsToString(ftyp)).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfoBox(b).Str("MajorBrand", ftyp.MajorBrand.String()).Str("ImageType", ftyp.ImageType.String()).Str("MinorVersion", string(ftyp.MinorVersion[:])).Strs("MinorBrands", minorBrandsToString(ftyp)).Send()
 	}
 	return ftyp, b.close()
 }
@@ -46,6 +52,15 @@ type FileTypeBox struct {
 	Compatible   [maxBrandCount]Brand // all 4 bytes
 	MinorVersion [4]byte              // 4 bytes
 	MajorBrand   Brand                // 4 bytes
+
+	// ImageType is the ImageType of the major brand and of all of the
+	// compatible brands (see imagetype.FromFTYP).
+	ImageType imagetype.ImageType
+}
+
+// FileType returns the FileTypeBox read by ReadFTYP.
+func (r *Reader) FileType() FileTypeBox {
+	return r.ftyp
 }
 
 // Brand of ISOBMFF ftyp
@@ -58,14 +73,14 @@ func (b Brand) String() string {
 	}
 	return "nnnn"
 }
-func brandFromBuf(buf []byte) Brand {
+func (r *Reader) brandFromBuf(buf []byte) Brand {
 	if len(buf) == 4 {
 		if b, ok := mapStringBrand[string(buf)]; ok {
 			return b
 		}
 	}
-	if logLevelError() {
-		logErrorMsg("Brand", "error Brand '%s' unknown", buf)
+	if r.logLevelError() {
+		r.logErrorMsg("Brand", "error Brand '%s' unknown", buf)
 	}
 	return brandUnknown
 }
@@ -88,6 +103,7 @@ const (
 	brandUnknown Brand = iota // unknown ISOBMFF brand
 	brandAvci                 // 'avci'
 	brandAvif                 // 'avif': AVIF
+	brandAvis                 // 'avis': AVIF image sequence
 	brandCrx                  // 'crx ' : Canon CR3
 	brandHeic                 // 'heic': the usual HEIF images
 	brandHeim                 // 'heim': multiview
@@ -97,9 +113,14 @@ const (
 	brandHevm                 // 'hevm': multiview sequence
 	brandHevs                 // 'hevs': scalable sequence
 	brandHevx                 // 'hevx': image sequence
+	brandIso2                 // 'iso2': MP4
+	brandIso4                 // 'iso4': MP4
+	brandIso5                 // 'iso5': MP4
+	brandIso6                 // 'iso6': MP4
 	brandIso8                 // 'iso8': sequence
 	brandIsom                 // 'isom' : ?
 	brandM4A                  // 'M4A '
+	brandM4V                  // 'M4V ': MP4
 	brandMA1B                 // 'MA1B'
 	brandMeta                 // 'meta': meta
 	brandMiaf                 // 'miaf' :
@@ -114,12 +135,14 @@ const (
 	brandMp41                 // 'mp41'
 	brandMp42                 // 'mp42'
 	brandMsf1                 // 'msf1': sequence
+	brandQt                   // 'qt  ': QuickTime
 )
 
 var (
 	mapStringBrand = map[string]Brand{
 		"avci": brandAvci,
 		"avif": brandAvif,
+		"avis": brandAvis,
 		"crx ": brandCrx,
 		"heic": brandHeic,
 		"heim": brandHeim,
@@ -129,9 +152,14 @@ var (
 		"hevm": brandHevm,
 		"hevs": brandHevs,
 		"hevx": brandHevx,
+		"iso2": brandIso2,
+		"iso4": brandIso4,
+		"iso5": brandIso5,
+		"iso6": brandIso6,
 		"iso8": brandIso8,
 		"isom": brandIsom,
 		"M4A ": brandM4A,
+		"M4V ": brandM4V,
 		"MA1B": brandMA1B,
 		"meta": brandMeta,
 		"miaf": brandMiaf,
@@ -146,11 +174,13 @@ var (
 		"mp41": brandMp41,
 		"mp42": brandMp42,
 		"msf1": brandMsf1,
+		"qt  ": brandQt,
 	}
 
 	mapBrandString = map[Brand]string{
 		brandAvci: "avci",
 		brandAvif: "avif",
+		brandAvis: "avis",
 		brandCrx:  "crx ",
 		brandHeic: "heic",
 		brandHeim: "heim",
@@ -160,9 +190,14 @@ var (
 		brandHevm: "hevm",
 		brandHevs: "hevs",
 		brandHevx: "hevx",
+		brandIso2: "iso2",
+		brandIso4: "iso4",
+		brandIso5: "iso5",
+		brandIso6: "iso6",
 		brandIso8: "iso8",
 		brandIsom: "isom",
 		brandM4A:  "M4A ",
+		brandM4V:  "M4V ",
 		brandMA1B: "MA1B",
 		brandMeta: "meta",
 		brandMiaf: "miaf",
@@ -177,5 +212,6 @@ var (
 		brandMp41: "mp41",
 		brandMp42: "mp42",
 		brandMsf1: "msf1",
+		brandQt:   "qt  ",
 	}
 )
@@ -0,0 +1,43 @@
+package isobmff
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tdelov/imagemeta/meta"
+)
+
+func FuzzReadMetadata(f *testing.F) {
+	for _, pattern := range []string{"samples/*.sample", "../testImages/*.avif"} {
+		files, _ := filepath.Glob(pattern)
+		for _, file := range files {
+			buf, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf)
+		}
+	}
+	discard := func(r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := NewReader(bytes.NewReader(data))
+		defer r.Close()
+		r.ExifReader = func(r io.Reader, h meta.ExifHeader) error { return discard(r) }
+		r.XMPReader = discard
+		if err := r.ReadFTYP(); err != nil {
+			return
+		}
+		for i := 0; i < 4; i++ {
+			if err := r.ReadMetadata(); err != nil {
+				return
+			}
+		}
+		_ = r.Previews()
+	})
+}
@@ -1,10 +1,18 @@
 package isobmff
 
+import "github.com/tdelov/imagemeta/meta"
+
 type HeicMeta struct {
-	pitm itemID
-	idat idat
-	exif item
-	xml  item
+	props []itemProperty
+	dim   meta.Dimensions
+	pitm  itemID
+	idat  idat
+	exif  item
+	xml   item
+	thmb  []thumbnailItem
+	icc   bool
+	meta  bool
+	mdat  bool
 	// irot
 }
 
@@ -12,3 +20,9 @@ type item struct {
 	id itemID
 	ol offsetLength
 }
+
+// thumbnailItem is a thumbnail image item that is referenced by a "thmb" item reference.
+type thumbnailItem struct {
+	item
+	dim meta.Dimensions
+}
@@ -1,7 +1,7 @@
 package isobmff
 
 import (
-	"github.com/rs/zerolog"
+	"log/slog"
 )
 
 func readIdat(b *box) (i idat, err error) {
@@ -12,8 +12,8 @@ func readIdat(b *box) (i idat, err error) {
 	i = idat{
 		width:  bmffEndian.Uint16(buf[4:6]),
 		height: bmffEndian.Uint16(buf[6:8])}
-	if logLevelInfo() {
-		logInfoBox(b).Object("idat", i).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfoBox(b).Object("idat", i).Send()
 	}
 
 	return i, b.close()
@@ -26,7 +26,7 @@ type idat struct {
 	width, height uint16
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (i idat) MarshalZerologObject(e *zerolog.Event) {
-	e.Uint16("width", i.width).Uint16("height", i.height)
+// LogValue is a slog.LogValuer interface for logging
+func (i idat) LogValue() slog.Value {
+	return slog.GroupValue(slog.Uint64("width", uint64(i.width)), slog.Uint64("height", uint64(i.height)))
 }
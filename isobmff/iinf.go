@@ -13,11 +13,11 @@ func (r *Reader) readIinf(b *box) (err error) {
 	if err != nil {
 		return
 	}
-	if logLevelInfo() {
-		logInfo().Object("box", b).Uint16("count", count).Send()
+	if r.logLevelInfo() {
+		r.logInfo().Object("box", b).Uint16("count", count).Send()
 	}
-	if err = r.readInfe(b); err != nil && logLevelError() {
-		logError().Object("box", b).Err(err).Send()
+	if err = r.readInfe(b); err != nil && r.logLevelError() {
+		r.logError().Object("box", b).Err(err).Send()
 	}
 
 	return b.close()
@@ -34,8 +34,14 @@ func (r *Reader) readInfe(b *box) (err error) {
 		infeFastHeaderSize := 21
 
 		var contentType imagetype.ImageType
+		if i+12 > len(buf) {
+			return ErrBufLength
+		}
 		size := int(bmffEndian.Uint32(buf[i : i+4]))
-		boxType := boxTypeFromBuf(buf[i+4 : i+8])
+		if size < 12 || size > len(buf)-i {
+			return ErrBufLength
+		}
+		boxType := r.boxTypeFromBuf(buf[i+4 : i+8])
 		flags := flags(bmffEndian.Uint32(buf[i+8 : i+12]))
 
 		if boxType != typeInfe {
@@ -44,32 +50,37 @@ func (r *Reader) readInfe(b *box) (err error) {
 		}
 		// Only support Infe version 2
 		if flags.version() != 2 {
-			if logLevelError() {
-				logError().Object("box", b).Err(errors.Wrapf(ErrInfeVersionNotSupported, "found version %d infe box. Only 2 is supported now", flags.version())).Send()
+			if r.logLevelError() {
+				r.logError().Object("box", b).Err(errors.Wrapf(ErrInfeVersionNotSupported, "found version %d infe box. Only 2 is supported now", flags.version())).Send()
 			}
 			i += size
 			continue
 		}
 
+		if size < infeFastHeaderSize {
+			return ErrBufLength
+		}
 		itemID := itemID(bmffEndian.Uint16(buf[i+12 : i+14]))
 		itemType := itemTypeFromBuf(buf[i+16 : i+20])
 		// expect whitespace
 		if buf[i+20] != '\x00' {
-			if logLevelDebug() {
-				logDebug().Object("box", b).Str("itemType", string(buf[i+16:i+20])).Uint16("itemID", uint16(itemID)).Msg("does't end on whitespace")
+			if r.logLevelDebug() {
+				r.logDebug().Object("box", b).Str("itemType", string(buf[i+16:i+20])).Uint16("itemID", uint16(itemID)).Msg("does't end on whitespace")
 			}
 			infeFastHeaderSize--
 		}
 		switch itemType {
 		case itemTypeMime:
-			contentType = imagetype.FromString(string(buf[i+infeFastHeaderSize : i+size-1]))
+			if size > infeFastHeaderSize {
+				contentType = imagetype.FromString(string(buf[i+infeFastHeaderSize : i+size-1]))
+			}
 			r.heic.xml.id = itemID
 		case itemTypeExif:
 			r.heic.exif.id = itemID
 		}
-		if logLevelDebug() {
+		if r.logLevelDebug() {
 			protectionIndex := bmffEndian.Uint16(buf[i+14 : i+16])
-			ev := logDebug().Str("BoxType", boxType.String()).Object("flags", flags).Uint16("itemID", uint16(itemID)).Str("itemType", string(buf[i+16:i+20])).Int("offset", i+offset).Int("size", size).Uint16("idx", protectionIndex)
+			ev := r.logDebug().Str("BoxType", boxType.String()).Object("flags", flags).Uint16("itemID", uint16(itemID)).Str("itemType", string(buf[i+16:i+20])).Int("offset", i+offset).Int("size", size).Uint16("idx", protectionIndex)
 			if itemType == itemTypeMime {
 				ev.Str("contentType", contentType.String())
 			}
@@ -1,7 +1,9 @@
 package isobmff
 
 import (
-	"github.com/rs/zerolog"
+	"log/slog"
+
+	"github.com/tdelov/imagemeta/internal/logging"
 )
 
 // itemLocationBox is a "iloc" box
@@ -11,16 +13,13 @@ type itemLocationBox struct {
 	offsetSize, lengthSize, baseOffsetSize, indexSize uint8 // actually uint4
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (ilb itemLocationBox) MarshalZerologObject(e *zerolog.Event) {
-	e.Array("entries", ilb).Uint16("items", ilb.count).Uint8("offsetSize", ilb.offsetSize).Uint8("lengthSize", ilb.lengthSize).Uint8("baseOffsetSize", ilb.baseOffsetSize).Uint8("indexSize", ilb.indexSize)
-}
-
-// MarshalZerologArray is a zerolog interface for logging
-func (ilb itemLocationBox) MarshalZerologArray(a *zerolog.Array) {
+// LogValue is a slog.LogValuer interface for logging
+func (ilb itemLocationBox) LogValue() slog.Value {
+	entries := make([]slog.Value, len(ilb.items))
 	for i := 0; i < len(ilb.items); i++ {
-		a.Object(ilb.items[i])
+		entries[i] = ilb.items[i].LogValue()
 	}
+	return slog.GroupValue(slog.Any("entries", logging.Array(entries...)), slog.Uint64("items", uint64(ilb.count)), slog.Uint64("offsetSize", uint64(ilb.offsetSize)), slog.Uint64("lengthSize", uint64(ilb.lengthSize)), slog.Uint64("baseOffsetSize", uint64(ilb.baseOffsetSize)), slog.Uint64("indexSize", uint64(ilb.indexSize)))
 }
 
 // ilocEntry is not a box
@@ -34,9 +33,9 @@ type ilocEntry struct {
 	constructionMethod uint8  // cmeth actually uint4
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (ie ilocEntry) MarshalZerologObject(e *zerolog.Event) {
-	e.Uint16("itemID", uint16(ie.id)).Object("extent", ie.firstExtent).Uint16("count", ie.count).Uint16("dri", ie.dataReferenceIndex).Uint8("cmeth", ie.constructionMethod)
+// LogValue is a slog.LogValuer interface for logging
+func (ie ilocEntry) LogValue() slog.Value {
+	return slog.GroupValue(slog.Uint64("itemID", uint64(ie.id)), slog.Any("extent", ie.firstExtent), slog.Uint64("count", uint64(ie.count)), slog.Uint64("dri", uint64(ie.dataReferenceIndex)), slog.Uint64("cmeth", uint64(ie.constructionMethod)))
 }
 
 // offsetLength contains an offset and length
@@ -44,9 +43,9 @@ type offsetLength struct {
 	offset, length uint64
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (ol offsetLength) MarshalZerologObject(e *zerolog.Event) {
-	e.Uint64("length", ol.length).Uint64("offset", ol.offset)
+// LogValue is a slog.LogValuer interface for logging
+func (ol offsetLength) LogValue() slog.Value {
+	return slog.GroupValue(slog.Uint64("length", ol.length), slog.Uint64("offset", ol.offset))
 }
 
 func (r *Reader) readIloc(b *box) (err error) {
@@ -60,11 +59,22 @@ func (r *Reader) readIloc(b *box) (err error) {
 		return
 	}
 
+	// size of an entry without extents and of an extent
+	entrySize := 6 + int(ilb.baseOffsetSize)
+	if b.flags.version() > 0 {
+		entrySize += 2
+	}
+
+	// The count is untrusted, the box holds at most len(buf)/entrySize entries
 	if optionSpeed == 0 {
-		ilb.items = make([]ilocEntry, 0, ilb.count)
+		ilb.items = make([]ilocEntry, 0, min(int(ilb.count), len(buf)/entrySize))
 	}
+	extentSize := int(ilb.indexSize) + int(ilb.offsetSize) + int(ilb.lengthSize)
 
 	for i := 0; i < len(buf); {
+		if i+entrySize > len(buf) {
+			return ErrBufLength
+		}
 		var ent ilocEntry
 		ent.id = itemID(bmffEndian.Uint16(buf[i : i+2]))
 		i += 2
@@ -84,22 +94,26 @@ func (r *Reader) readIloc(b *box) (err error) {
 		}
 		ent.count = bmffEndian.Uint16(buf[i : i+2])
 		i += 2
+		if i+int(ent.count)*extentSize > len(buf) {
+			return ErrBufLength
+		}
 
 		for j := 0; j < int(ent.count); j++ {
 			var ol offsetLength
+			i += int(ilb.indexSize)
+			ol.offset = uintN(ilb.offsetSize, buf[i:i+int(ilb.offsetSize)])
+			i += int(ilb.offsetSize)
+			ol.length = uintN(ilb.lengthSize, buf[i:i+int(ilb.lengthSize)])
+			i += int(ilb.lengthSize)
 			if j == 0 {
-				ol.offset = uintN(ilb.offsetSize, buf[i:i+int(ilb.offsetSize)])
-				i += int(ilb.offsetSize)
-				ol.length = uintN(ilb.lengthSize, buf[i:i+int(ilb.lengthSize)])
-				i += int(ilb.lengthSize)
 				ent.firstExtent = ol
 			}
 		}
 		if optionSpeed == 0 {
 			ilb.items = append(ilb.items, ent)
 		}
-		if logLevelDebug() {
-			logDebug().Object("entry", ent).Send()
+		if r.logLevelDebug() {
+			r.logDebug().Object("entry", ent).Send()
 		}
 
 		switch ent.id {
@@ -108,6 +122,12 @@ func (r *Reader) readIloc(b *box) (err error) {
 		case r.heic.xml.id:
 			r.heic.xml.ol = ent.firstExtent
 		}
+		for j := range r.heic.thmb {
+			// Thumbnail items in the "idat" box are not supported
+			if r.heic.thmb[j].id == ent.id && ent.constructionMethod == 0 && ent.count == 1 {
+				r.heic.thmb[j].ol = offsetLength{ent.baseOffset + ent.firstExtent.offset, ent.firstExtent.length}
+			}
+		}
 		//if ent.ItemID == Exif...
 	}
 	return b.close()
@@ -127,8 +147,13 @@ func readIlocHeader(b *box) (ilb itemLocationBox, err error) {
 		ilb.indexSize = buf[1] & 15
 	}
 	ilb.count = bmffEndian.Uint16(buf[2:4])
-	if logLevelInfo() {
-		logInfoBox(b).Object("ItemLocation", ilb).Send()
+	for _, size := range []uint8{ilb.offsetSize, ilb.lengthSize, ilb.baseOffsetSize, ilb.indexSize} {
+		if size != 0 && size != 4 && size != 8 {
+			return ilb, ErrIlocFieldSize
+		}
+	}
+	if b.reader.logLevelInfo() {
+		b.reader.logInfoBox(b).Object("ItemLocation", ilb).Send()
 	}
 	_, err = b.Discard(8)
 	return ilb, err
@@ -136,15 +161,13 @@ func readIlocHeader(b *box) (ilb itemLocationBox, err error) {
 
 func uintN(size uint8, buf []byte) uint64 {
 	switch size {
-	case 1:
-		return uint64(buf[0])
-	case 2:
-		return uint64(bmffEndian.Uint16(buf[:2]))
+	case 0:
+		return 0
 	case 4:
 		return uint64(bmffEndian.Uint32(buf[:4]))
 	case 8:
 		return bmffEndian.Uint64(buf[:8])
-	default:
-		panic("error here")
 	}
+	// Field sizes are validated by readIlocHeader
+	return 0
 }
@@ -1,48 +1,181 @@
 package isobmff
 
-func readIprp(b *box) (err error) {
-	if logLevelInfo() {
-		logInfoBox(b).Send()
+import (
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/pkg/errors"
+)
+
+func (r *Reader) readIprp(b *box) (err error) {
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Send()
 	}
 	var inner box
 	var ok bool
 	for inner, ok, err = b.readInnerBox(); err == nil && ok; inner, ok, err = b.readInnerBox() {
 		switch inner.boxType {
 		case typeIpma:
-			_, err = readIpma(&inner)
+			err = r.readIpma(&inner)
 		case typeIpco:
-			err = readIpco(&inner)
+			err = r.readIpco(&inner)
 		default:
-			if logLevelInfo() {
-				logInfoBox(&inner).Send()
+			if r.logLevelInfo() {
+				r.logInfoBox(&inner).Send()
 			}
 		}
-		if err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
+		r.warn(&inner, err)
+		if err = inner.close(); err != nil && r.logLevelError() {
+			r.logError().Object("box", inner).Err(err).Send()
 		}
-		if err = inner.close(); err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
+	}
+	return b.closeContext()
+}
+
+// readIpco reads the item properties of an "ipco" box. Properties are referenced
+// by their 1-based index in the "ipma" box.
+func (r *Reader) readIpco(b *box) (err error) {
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Send()
+	}
+	r.heic.props = r.heic.props[:0]
+	var inner box
+	var ok bool
+	for inner, ok, err = b.readInnerBox(); err == nil && ok; inner, ok, err = b.readInnerBox() {
+		prop := itemProperty{boxType: inner.boxType}
+		switch inner.boxType {
+		case typeIspe:
+			prop.dim, err = readIspe(&inner)
+		case typeColr:
+			err = r.readColr(&inner)
+		default:
+			if r.logLevelDebug() {
+				r.logDebug().Object("box", inner).Send()
+			}
+		}
+		r.heic.props = append(r.heic.props, prop)
+		r.warn(&inner, err)
+		if err = inner.close(); err != nil {
+			return err
 		}
 	}
-	return b.close()
+	return b.closeContext()
 }
 
-func readIpco(b *box) (err error) {
-	if logLevelInfo() {
-		logInfoBox(b).Send()
-	}
-	//var inner box
-	//var ok bool
-	//for inner, ok, err = b.readInnerBox(); err == nil && ok; inner, ok, err = b.readInnerBox() {
-	//	if logLevelInfo() {
-	//		logInfoBox(inner)
-	//	}
-	//	inner.close()
-	//}
+// itemProperty is an item property from an "ipco" box.
+type itemProperty struct {
+	dim     meta.Dimensions
+	boxType boxType
+}
 
+// readIspe reads the image width and height from an "ispe" box.
+func readIspe(b *box) (dim meta.Dimensions, err error) {
+	buf, err := b.Peek(12)
+	if err != nil {
+		return dim, errors.Wrap(ErrBufLength, "readIspe")
+	}
+	b.readFlagsFromBuf(buf)
+	dim = meta.NewDimensions(bmffEndian.Uint32(buf[4:8]), bmffEndian.Uint32(buf[8:12]))
+	if b.reader.logLevelInfo() {
+		b.reader.logInfoBox(b).Uint32("width", dim.Width).Uint32("height", dim.Height).Send()
+	}
+	return dim, b.close()
+}
+
+// readColr reads a "colr" box. The ICC Profile of the first
+// colour type 'prof' or 'rICC' is read by the ICCReader.
+func (r *Reader) readColr(b *box) (err error) {
+	buf, err := b.Peek(4)
+	if err != nil {
+		return errors.Wrap(ErrBufLength, "readColr")
+	}
+	colourType := string(buf[:4])
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Str("colourType", colourType).Send()
+	}
+	if (colourType == "prof" || colourType == "rICC") && r.ICCReader != nil && !r.heic.icc {
+		if _, err = b.Discard(4); err != nil {
+			return err
+		}
+		r.heic.icc = true
+		if err = r.ICCReader(b); err != nil {
+			return err
+		}
+	}
 	return b.close()
 }
 
+// readIpma reads an "ipma" box and sets the dimensions of the primary item
+// and the thumbnail items from their associated "ispe" properties.
+func (r *Reader) readIpma(b *box) (err error) {
+	if err = b.readFlags(); err != nil {
+		return err
+	}
+	buf, err := b.Peek(b.remain)
+	if err != nil {
+		return errors.Wrap(ErrBufLength, "readIpma")
+	}
+	if len(buf) < 4 {
+		return errors.Wrap(ErrBufLength, "readIpma")
+	}
+	count := int(bmffEndian.Uint32(buf[:4]))
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Uint32("entries", uint32(count)).Send()
+	}
+	idSize, indexSize := 2, 1
+	if b.flags.version() >= 1 {
+		idSize = 4
+	}
+	if b.flags.flags()&1 == 1 {
+		indexSize = 2
+	}
+	for i, j := 0, 4; i < count && j+idSize+1 <= len(buf); i++ {
+		var id itemID
+		if idSize == 2 {
+			id = itemID(bmffEndian.Uint16(buf[j:]))
+		} else {
+			id = itemID(bmffEndian.Uint32(buf[j:]))
+		}
+		j += idSize
+		associations := int(buf[j])
+		j++
+		for k := 0; k < associations && j+indexSize <= len(buf); k++ {
+			var index int
+			if indexSize == 2 {
+				index = int(bmffEndian.Uint16(buf[j:]) & 0x7fff)
+			} else {
+				index = int(buf[j] & 0x7f)
+			}
+			j += indexSize
+			if index == 0 || index > len(r.heic.props) {
+				continue
+			}
+			prop := r.heic.props[index-1]
+			if prop.boxType != typeIspe {
+				continue
+			}
+			if id == r.heic.pitm && r.heic.dim == (meta.Dimensions{}) {
+				r.heic.dim = prop.dim
+			}
+			r.setThumbnailDimensions(id, prop.dim)
+		}
+	}
+	return b.close()
+}
+
+// setThumbnailDimensions sets the dimensions of the thumbnail item id.
+func (r *Reader) setThumbnailDimensions(id itemID, dim meta.Dimensions) {
+	for i := range r.heic.thmb {
+		if r.heic.thmb[i].id == id {
+			r.heic.thmb[i].dim = dim
+		}
+	}
+}
+
+// Dimensions returns the dimensions of the primary item of a HEIF or AVIF image.
+// Available after the "meta" box has been read.
+func (r *Reader) Dimensions() meta.Dimensions {
+	return r.heic.dim
+}
+
 // ItemPropertiesBox is an ISOBMFF "iprp" box
 type ItemPropertiesBox struct {
 	PropertyContainer ItemPropertyContainerBox
@@ -66,21 +199,3 @@ type IpmaItem struct {
 	//AssociationsCount uint32 // as declared
 	//Associations      []ItemProperty // as parsed
 }
-
-func readIpma(b *box) (ipma ItemPropertyAssociation, err error) {
-	buf, err := b.Peek(8)
-	if err != nil {
-		return
-	}
-	b.readFlagsFromBuf(buf[:4])
-	count := int(bmffEndian.Uint32(buf[4:8]))
-	if logLevelInfo() {
-		logInfoBox(b).Uint32("entries", uint32(count)).Send()
-	}
-	// Entries
-	// /ipma.Entries = make([]IpmaItem, count)
-	// /if buf, err = b.Peek(b.remain); err != nil {
-	// /	return
-	// /}
-	return ipma, b.close()
-}
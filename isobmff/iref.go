@@ -1,5 +1,7 @@
 package isobmff
 
+import "github.com/pkg/errors"
+
 // ItemTypeReferenceBox is an "iref" box.
 //
 // Item Reference box iref enables creating directional links from an item to one or several other items.
@@ -10,29 +12,51 @@ package isobmff
 // cdsc -> context description ref / exif
 type ItemTypeReferenceBox struct{}
 
-func readIref(b *box) (err error) {
+func (r *Reader) readIref(b *box) (err error) {
 	if err = b.readFlags(); err != nil {
 		return
 	}
-	if logLevelInfo() {
-		logInfoBox(b).Send()
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Send()
+	}
+	// Item IDs are 32 bits from version 1
+	idSize := 2
+	if b.flags.version() > 0 {
+		idSize = 4
 	}
 	var inner box
 	var ok bool
 	for inner, ok, err = b.readInnerBox(); err == nil && ok; inner, ok, err = b.readInnerBox() {
 		//switch inner.boxType {
 		//case typeDimg:
-		//case typeThmb:
 		//case typeCdsc:
 		//}
-		// TODO: implement reading iref boxes
-		if logLevelInfo() {
-			logInfoBox(&inner).Send()
+		if r.logLevelInfo() {
+			r.logInfoBox(&inner).Send()
 		}
-		if err = inner.close(); err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
+		if inner.boxType == typeThmb {
+			r.warn(&inner, r.readThmb(&inner, idSize))
+		}
+		if err = inner.close(); err != nil && r.logLevelError() {
+			r.logError().Object("box", inner).Err(err).Send()
 			break
 		}
 	}
-	return b.close()
+	return b.closeContext()
+}
+
+// readThmb reads a "thmb" item reference from a thumbnail item to its master image.
+func (r *Reader) readThmb(b *box, idSize int) error {
+	buf, err := b.Peek(b.remain)
+	if err != nil || len(buf) < idSize {
+		return errors.Wrap(ErrBufLength, "readThmb")
+	}
+	var id itemID
+	if idSize == 2 {
+		id = itemID(bmffEndian.Uint16(buf))
+	} else {
+		id = itemID(bmffEndian.Uint32(buf))
+	}
+	r.heic.thmb = append(r.heic.thmb, thumbnailItem{item: item{id: id}})
+	return nil
 }
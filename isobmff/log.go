@@ -1,79 +1,78 @@
 package isobmff
 
 import (
-	"os"
+	"log/slog"
 	"runtime"
 
+	"github.com/tdelov/imagemeta/internal/logging"
 	"github.com/pkg/errors"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 var (
-	// Logger is a zerolog logger
+	// Logger is the default logger of a Reader
 
-	Logger zerolog.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.PanicLevel)
+	Logger *slog.Logger = logging.Discard
 )
 
 // logLevelInfo
-func logLevelInfo() bool {
-	return Logger.GetLevel() <= zerolog.InfoLevel
+func (r *Reader) logLevelInfo() bool {
+	return logging.Enabled(r.Logger, slog.LevelInfo)
 }
 
 // logLevelDebug
-func logLevelDebug() bool {
-	return Logger.GetLevel() <= zerolog.DebugLevel
+func (r *Reader) logLevelDebug() bool {
+	return logging.Enabled(r.Logger, slog.LevelDebug)
 }
 
 // logLevelError
-func logLevelError() bool {
-	return Logger.GetLevel() <= zerolog.ErrorLevel
+func (r *Reader) logLevelError() bool {
+	return logging.Enabled(r.Logger, slog.LevelError)
 }
 
 // logLevelTrace
-func logLevelTrace() bool {
-	return Logger.GetLevel() == zerolog.TraceLevel
+func (r *Reader) logLevelTrace() bool {
+	return logging.Enabled(r.Logger, logging.LevelTrace)
 }
 
-func logErrorMsg(key string, format string, args ...interface{}) {
-	Logger.Error().AnErr(key, errors.Errorf(format, args...)).Send()
+func (r *Reader) logErrorMsg(key string, format string, args ...interface{}) {
+	logging.NewEvent(r.Logger, slog.LevelError).AnErr(key, errors.Errorf(format, args...)).Send()
 }
 
-func logInfo() *zerolog.Event {
-	ev := Logger.WithLevel(zerolog.InfoLevel)
-	logTraceFunction(ev)
+func (r *Reader) logInfo() *logging.Event {
+	ev := logging.NewEvent(r.Logger, slog.LevelInfo)
+	r.logTraceFunction(ev)
 	return ev
 }
 
-func logDebug() *zerolog.Event {
-	ev := Logger.WithLevel(zerolog.DebugLevel)
-	logTraceFunction(ev)
+func (r *Reader) logDebug() *logging.Event {
+	ev := logging.NewEvent(r.Logger, slog.LevelDebug)
+	r.logTraceFunction(ev)
 	return ev
 }
 
-func logError() *zerolog.Event {
-	ev := Logger.WithLevel(zerolog.ErrorLevel)
-	logTraceFunction(ev)
+func (r *Reader) logError() *logging.Event {
+	ev := logging.NewEvent(r.Logger, slog.LevelError)
+	r.logTraceFunction(ev)
 	return ev
 }
-func logInfoBox(b *box) *zerolog.Event {
-	ev := logInfo()
+func (r *Reader) logInfoBox(b *box) *logging.Event {
+	ev := r.logInfo()
 	if b != nil {
 		b.log(ev)
 	}
-	logTraceFunction(ev)
+	r.logTraceFunction(ev)
 	return ev
 }
 
-func (b *box) log(ev *zerolog.Event) {
+func (b *box) log(ev *logging.Event) {
 	ev.Str("BoxType", b.boxType.String()).Int("offset", b.offset).Int64("size", b.size)
 	if b.flags != 0 {
 		ev.Object("flags", b.flags)
 	}
 }
 
-func logTraceFunction(ev *zerolog.Event) {
-	if logLevelTrace() {
+func (r *Reader) logTraceFunction(ev *logging.Event) {
+	if r.logLevelTrace() {
 		pc, _, _, ok := runtime.Caller(2)
 		details := runtime.FuncForPC(pc)
 		if ok && details != nil {
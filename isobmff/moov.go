@@ -1,8 +1,6 @@
 package isobmff
 
 import (
-	"fmt"
-
 	"github.com/tdelov/imagemeta/exif2/ifds"
 	"github.com/tdelov/imagemeta/imagetype"
 	"github.com/tdelov/imagemeta/meta"
@@ -13,9 +11,6 @@ import (
 func (r *Reader) ReadMetadata() (err error) {
 	b, err := r.readBox()
 	if err != nil {
-		buf, err := r.br.Peek(128)
-		fmt.Println(buf, err, len(buf))
-		fmt.Println(string(buf))
 		return errors.Wrapf(err, "ReadMetadata")
 	}
 	switch b.boxType {
@@ -23,83 +18,121 @@ func (r *Reader) ReadMetadata() (err error) {
 		err = r.readMdat(&b)
 	case typeMeta:
 		err = r.readMeta(&b)
+		r.heic.meta = true
 		b.close()
 	case typeMoov:
 		err = r.readMoovBox(&b)
+		r.moov = true
 		b.close()
 	case typeUUID:
 		err = r.readUUIDBox(&b)
 	default:
-		if logLevelInfo() {
-			logInfo().Object("box", b).Send()
+		if r.logLevelInfo() {
+			r.logInfo().Object("box", b).Send()
 		}
+		err = b.close()
 	}
-	if err != nil && logLevelError() {
-		logError().Object("box", b).Err(err).Send()
+	if err != nil {
+		err = b.decodeError(err)
+		if r.logLevelError() {
+			r.logError().Object("box", b).Err(err).Send()
+		}
 	}
 	return err
 }
 
 func (r *Reader) readMdat(b *box) (err error) {
-	if logLevelInfo() {
-		logInfo().Object("box", b).Send()
+	if r.logLevelInfo() {
+		r.logInfo().Object("box", b).Send()
 	}
-	if r.heic.exif.ol.offset == 0 {
-		return b.close()
+	// Read the Exif and XMP items in the order they appear in the "mdat" box.
+	first, second := r.heic.exif, r.heic.xml
+	if second.ol.offset < first.ol.offset {
+		first, second = second, first
 	}
+	for _, it := range [2]item{first, second} {
+		if it.ol.offset == 0 || it.ol.offset < uint64(b.position()) {
+			continue
+		}
+		switch it.id {
+		case r.heic.exif.id:
+			err = r.readExifItem(b)
+		case r.heic.xml.id:
+			err = r.readXMPItem(b)
+		}
+		r.warn(b, err)
+	}
+	r.heic.mdat = true
+	return b.close()
+}
+
+// position returns the absolute offset of the reader within the box.
+func (b *box) position() int {
+	return b.offset + int(b.size) - b.remain
+}
+
+// readExifItem reads the Exif item from the "mdat" box with the ExifReader.
+func (r *Reader) readExifItem(b *box) (err error) {
 	inner, err := r.newExifBox(b)
 	if err != nil {
-		if logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
-		}
 		return
 	}
-	header, err := readExifHeader(&inner, ifds.IFD0, imagetype.ImageHEIF)
+	it := r.ftyp.ImageType
+	if it.IsUnknown() {
+		it = imagetype.ImageHEIF
+	}
+	header, err := readExifHeader(&inner, ifds.IFD0, it)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	if r.ExifReader != nil {
-		if err = r.ExifReader(&inner, header); err != nil {
-			if logLevelError() {
-				logError().Object("box", inner).Err(err).Send()
-			}
-		}
+		r.warn(&inner, r.ExifReader(&inner, header))
 	}
 
-	if logLevelInfo() {
-		logInfo().Object("box", inner).Int("remain", inner.remain).Send()
+	if r.logLevelInfo() {
+		r.logInfo().Object("box", inner).Int("remain", inner.remain).Send()
 	}
-
-	return b.close()
+	return inner.close()
 }
 
-func (r *Reader) newExifBox(b *box) (inner box, err error) {
-	if _, err = b.Discard(int(r.heic.exif.ol.offset) - b.offset - 16); err != nil {
+// readXMPItem reads the XMP item from the "mdat" box with the XMPReader.
+func (r *Reader) readXMPItem(b *box) (err error) {
+	if _, err = b.Discard(int(r.heic.xml.ol.offset) - b.position()); err != nil {
 		return
 	}
-	buf, err := b.Peek(16)
-	if err != nil {
-		return
+	inner := box{
+		reader: b.reader,
+		outer:  b,
+		offset: b.position(),
+		size:   int64(r.heic.xml.ol.length),
+		remain: int(r.heic.xml.ol.length),
 	}
-	var size int
-	for i := 0; i < len(buf); i += 4 {
-		if string(buf[i+4:i+4+4]) == "Exif" {
-			size = int(bmffEndian.Uint32(buf[i:i+4])) + i
-			break
-		}
+	if r.XMPReader != nil {
+		r.warn(&inner, r.XMPReader(&inner))
 	}
+	return inner.close()
+}
 
+// newExifBox returns the Exif item within the "mdat" box. The Exif item begins with
+// the offset to the TIFF header (exif_tiff_header_offset) which is discarded.
+func (r *Reader) newExifBox(b *box) (inner box, err error) {
+	if _, err = b.Discard(int(r.heic.exif.ol.offset) - b.position()); err != nil {
+		return
+	}
 	inner = box{
 		reader:  b.reader,
 		outer:   b,
 		boxType: typeExif,
-		offset:  int(b.size) - b.remain + b.offset,
+		offset:  b.position(),
 		size:    int64(r.heic.exif.ol.length),
 		remain:  int(r.heic.exif.ol.length),
 	}
-
-	_, err = inner.Discard(size + 4)
+	buf, err := inner.Peek(4)
+	if err != nil {
+		return
+	}
+	_, err = inner.Discard(4 + int(bmffEndian.Uint32(buf)))
 	return inner, err
 }
 
@@ -110,10 +143,10 @@ func readExifHeader(b *box, firstIfd ifds.IfdType, it imagetype.ImageType) (head
 		return
 	}
 	endian := utils.BinaryOrder(buf[:4])
-	header = meta.NewExifHeader(endian, endian.Uint32(buf[4:8]), 0, uint32(b.remain), imagetype.ImageCR3)
+	header = meta.NewExifHeader(endian, endian.Uint32(buf[4:8]), uint32(b.position()), uint32(b.remain), it)
 	header.FirstIfd = firstIfd
-	if logLevelInfo() {
-		logInfo().Object("box", b).Object("header", header).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfo().Object("box", b).Object("header", header).Send()
 	}
 	_, err = b.Discard(8)
 	return header, err
@@ -126,8 +159,8 @@ func (r *Reader) readMeta(b *box) (err error) {
 	if err = b.readFlags(); err != nil {
 		return err
 	}
-	if logLevelInfo() {
-		logInfo().Object("box", b).Send()
+	if r.logLevelInfo() {
+		r.logInfo().Object("box", b).Send()
 	}
 	var inner box
 	var ok bool
@@ -142,28 +175,26 @@ func (r *Reader) readMeta(b *box) (err error) {
 		case typeIinf:
 			err = r.readIinf(&inner)
 		case typeIref:
-			err = readIref(&inner)
+			err = r.readIref(&inner)
 		case typeIprp:
-			err = readIprp(&inner)
+			err = r.readIprp(&inner)
 		case typeIdat:
 			r.heic.idat, err = readIdat(&inner)
 		case typeIloc:
 			err = r.readIloc(&inner)
 		default:
-			if logLevelInfo() {
-				logInfo().Object("box", inner).Send()
+			if r.logLevelInfo() {
+				r.logInfo().Object("box", inner).Send()
 			}
 		}
-		if err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
-		}
+		r.warn(&inner, err)
 
 		if err = inner.close(); err != nil {
-			logError().Object("box", inner).Err(err).Send()
+			r.logError().Object("box", inner).Err(err).Send()
 			break
 		}
 	}
-	return b.close()
+	return b.closeContext()
 }
 
 // ReadMOOV reads an 'moov' box from a BMFF file.
@@ -171,8 +202,8 @@ func (r *Reader) readMoovBox(b *box) (err error) {
 	if !b.isType(typeMoov) {
 		return errors.Wrapf(ErrWrongBoxType, "Box %s", b.boxType)
 	}
-	if logLevelInfo() {
-		logInfo().Object("box", b).Send()
+	if r.logLevelInfo() {
+		r.logInfo().Object("box", b).Send()
 	}
 	var inner box
 	var ok bool
@@ -184,17 +215,15 @@ func (r *Reader) readMoovBox(b *box) (err error) {
 			_, err = readCrxTrakBox(&inner)
 		//case typeMvhd:
 		default:
-			if logLevelInfo() {
-				logInfo().Object("box", inner).Send()
+			if r.logLevelInfo() {
+				r.logInfo().Object("box", inner).Send()
 			}
 		}
-		if err != nil && logLevelError() {
-			logError().Object("box", inner).Err(err).Send()
-		}
+		r.warn(&inner, err)
 		if err = inner.close(); err != nil {
-			logError().Object("box", inner).Err(err).Send()
+			r.logError().Object("box", inner).Err(err).Send()
 			break
 		}
 	}
-	return b.close()
+	return b.closeContext()
 }
@@ -10,10 +10,13 @@ func readPitm(b *box) (id itemID, err error) {
 	if err != nil {
 		return -1, err
 	}
+	if len(buf) < 6 {
+		return -1, ErrBufLength
+	}
 	b.readFlagsFromBuf(buf)
 	id = itemID(bmffEndian.Uint16(buf[4:]))
-	if logLevelInfo() {
-		logInfoBox(b).Uint16("ptim", uint16(id)).Send()
+	if b.reader.logLevelInfo() {
+		b.reader.logInfoBox(b).Uint16("ptim", uint16(id)).Send()
 	}
 	return id, b.close()
 }
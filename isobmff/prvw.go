@@ -21,13 +21,10 @@ func (r *Reader) readPreview(b *box) (err error) {
 	if err != nil {
 		return errors.Wrapf(err, "parsePreviewBox")
 	}
+	r.prvwOffset = r.offset
 
 	if r.PreviewImageReader != nil {
-		if err = r.PreviewImageReader(&inner, meta.PreviewHeader(r.prvw)); err != nil {
-			if logLevelError() {
-				logError().Object("box", inner).Err(err).Send()
-			}
-		}
+		r.warn(&inner, r.PreviewImageReader(&inner, meta.PreviewHeader(r.prvw)))
 	}
 
 	return inner.close()
@@ -49,7 +46,7 @@ func (r *Reader) createPRVWBox(b *box) (inner box, err error) {
 	inner.offset = int(b.size) - b.remain + b.offset
 	inner.size = int64(bmffEndian.Uint32(buf[:4]))
 	inner.remain = int(inner.size)
-	inner.boxType = boxTypeFromBuf(buf[4:8])
+	inner.boxType = r.boxTypeFromBuf(buf[4:8])
 
 	return inner, nil
 }
@@ -75,3 +72,27 @@ func parsePreviewBox(b *box) (prvw PRVWBox, err error) {
 
 	return prvw, nil
 }
+
+// Preview is the location of a preview or thumbnail image of an ISOBMFF image.
+type Preview struct {
+	// Offset and Length of the image data from the beginning of the file.
+	Offset, Length uint64
+	Dimensions     meta.Dimensions
+	// BoxType is "PRVW" for the preview image of a CR3 image and "thmb"
+	// for a thumbnail item of a HEIF or AVIF image.
+	BoxType string
+}
+
+// Previews returns the preview image of a CR3 image and the thumbnail items of a HEIF or
+// AVIF image that have been read. Thumbnail items without a location are not returned.
+func (r *Reader) Previews() (previews []Preview) {
+	if r.prvw.Size > 0 {
+		previews = append(previews, Preview{Offset: uint64(r.prvwOffset), Length: uint64(r.prvw.Size), Dimensions: meta.NewDimensions(uint32(r.prvw.Width), uint32(r.prvw.Height)), BoxType: typePRVW.String()})
+	}
+	for _, t := range r.heic.thmb {
+		if t.ol.length > 0 {
+			previews = append(previews, Preview{Offset: t.ol.offset, Length: t.ol.length, Dimensions: t.dim, BoxType: typeThmb.String()})
+		}
+	}
+	return previews
+}
@@ -2,8 +2,10 @@ package isobmff
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"io"
+	"log/slog"
 	"sync"
 
 	"github.com/tdelov/imagemeta/meta"
@@ -39,10 +41,19 @@ type Reader struct {
 
 	ExifReader         func(r io.Reader, h meta.ExifHeader) error
 	XMPReader          func(r io.Reader) error
+	ICCReader          func(r io.Reader) error
 	PreviewImageReader func(r io.Reader, h meta.PreviewHeader) error
 
-	offset int
-	rPool  bool
+	// Logger is the logger of the Reader. Defaults to the package Logger.
+	Logger *slog.Logger
+
+	ctx        context.Context
+	warnings   []error
+	offset     int
+	prvwOffset int // offset of the CR3 preview image
+	rPool      bool
+	moov       bool
+	xpacket    bool
 }
 
 // NewReader returns a new bmff.Reader
@@ -51,9 +62,9 @@ func NewReader(r io.Reader) Reader {
 	if !ok || br.Size() < minBufReaderSize {
 		br = readerPool.Get().(*bufio.Reader)
 		br.Reset(r)
-		return Reader{br: br, rPool: true}
+		return Reader{br: br, rPool: true, Logger: Logger}
 	}
-	return Reader{br: br}
+	return Reader{br: br, Logger: Logger}
 }
 
 func (r *Reader) peek(n int) ([]byte, error) {
@@ -71,6 +82,32 @@ func (r *Reader) reset(newReader io.Reader) {
 	*r = NewReader(newReader)
 }
 
+// ReadAll reads the top-level boxes with ReadMetadata until the metadata of the
+// file has been read or the end of the file has been reached. For CR3 files these are the
+// "moov" box and the XMP "uuid" box, for HEIF and AVIF files these are the "meta" box
+// and the "mdat" box that contains the Exif and XMP items.
+//
+// ReadFTYP should be called before ReadAll.
+func (r *Reader) ReadAll() error {
+	for !r.metadataRead() {
+		if err := r.ReadMetadata(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataRead returns true when all of the metadata boxes have been read.
+func (r *Reader) metadataRead() bool {
+	if r.ftyp.MajorBrand == brandCrx {
+		return r.moov && r.xpacket
+	}
+	return r.heic.meta && (r.heic.mdat || (r.heic.exif.ol.offset == 0 && r.heic.xml.ol.offset == 0))
+}
+
 // Close the Reader. Returns the underlying bufio.Reader to the reader pool.
 func (r *Reader) Close() {
 	if r.rPool {
@@ -78,22 +115,65 @@ func (r *Reader) Close() {
 	}
 }
 
+// SetContext sets the context of the Reader. The context is checked before
+// each box is read and ctx.Err() is returned when the context is done.
+func (r *Reader) SetContext(ctx context.Context) {
+	r.ctx = ctx
+}
+
+// contextErr returns the error of the Reader's context.
+func (r *Reader) contextErr() error {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Err()
+}
+
+// Warnings returns the non-fatal errors that occurred while reading boxes
+// and items. Errors are a *meta.DecodeError with the box path of the error.
+func (r *Reader) Warnings() []error {
+	return r.warnings
+}
+
+// warn logs err as an error of box b and adds it to the warnings of the Reader.
+func (r *Reader) warn(b *box, err error) {
+	if err == nil || r.contextErr() != nil {
+		return
+	}
+	err = b.decodeError(err)
+	if r.logLevelError() {
+		r.logError().Object("box", b).Err(err).Send()
+	}
+	r.warnings = append(r.warnings, err)
+}
+
 // readBox reads an ISOBMFF box
 func (r *Reader) readBox() (b box, err error) {
+	if err = r.contextErr(); err != nil {
+		return b, err
+	}
 	// Read box size and box type
 	buf, err := r.peek(16)
 	if err != nil {
-		return b, errors.Wrap(ErrBufLength, "readBox")
+		if err == io.EOF && len(buf) == 0 {
+			return b, io.EOF
+		}
+		if len(buf) < 8 {
+			return b, errors.Wrap(ErrBufLength, "readBox")
+		}
 	}
 	b.reader = r
 	b.size = int64(bmffEndian.Uint32(buf[:4]))
 	b.remain = int(b.size)
-	b.boxType = boxTypeFromBuf(buf[4:8])
+	b.boxType = r.boxTypeFromBuf(buf[4:8])
 	b.offset = r.offset
 
 	switch b.size {
 	case 1:
 		// 1 means it's actually a 64-bit size, after the type.
+		if len(buf) < 16 {
+			return b, errors.Wrap(ErrBufLength, "readBox")
+		}
 		b.size = int64(bmffEndian.Uint64(buf[8:16]))
 		if b.size < 0 {
 			// Go uses int64 for sizes typically, but BMFF uses uint64.
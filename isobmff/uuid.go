@@ -24,11 +24,12 @@ func (r *Reader) readUUIDBox(b *box) error {
 	if err != nil {
 		return err
 	}
-	if logLevelInfo() {
-		logInfoBox(b).Str("uuid", uuid.String()).Send()
+	if r.logLevelInfo() {
+		r.logInfoBox(b).Str("uuid", uuid.String()).Send()
 	}
 	switch uuid {
 	case cr3XPacketUUID:
+		r.xpacket = true
 		if r.XMPReader != nil {
 			if err = r.XMPReader(b); err != nil {
 				b.close()
@@ -44,8 +45,8 @@ func (r *Reader) readUUIDBox(b *box) error {
 			return err
 		}
 	default:
-		if logLevelDebug() {
-			logDebug().Object("box", b).Send()
+		if r.logLevelDebug() {
+			r.logDebug().Object("box", b).Send()
 		}
 	}
 	return b.close()
@@ -0,0 +1,43 @@
+package jpeg
+
+import (
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// ExifHeaderBytes returns the ExifHeader of the first APP1 Exif segment of the JPEG image in
+// buf without copying it. The TiffHeaderOffset is the offset of the Tiff header in buf. The
+// markers are read until the SOF marker of the primary image.
+//
+// Returns the error ErrNoJPEGMarker if buf does not start with a JPEG SOI marker and
+// meta.ErrNoExif if an Exif segment was not found.
+func ExifHeaderBytes(buf []byte) (meta.ExifHeader, error) {
+	if len(buf) < 2 || !isSOIMarker(buf) {
+		return meta.ExifHeader{}, ErrNoJPEGMarker
+	}
+	for i := 2; i+4 <= len(buf); {
+		if !isMarkerFirstByte(buf[i:]) {
+			i++
+			continue
+		}
+		marker := markerType(buf[i+1])
+		switch {
+		case marker == markerFirstByte:
+			// Fill byte
+			i++
+			continue
+		case marker == markerSOS || marker == markerEOI || marker>>4 == 12 && marker != markerDHT:
+			return meta.ExifHeader{}, meta.ErrNoExif
+		}
+		size := int(jpegEndian.Uint16(buf[i+2 : i+4]))
+		if marker == markerAPP1 && size >= 16 && i+18 <= len(buf) && isExifPrefix(buf[i:]) {
+			// Tiff header follows the length and the Exif prefix
+			byteOrder := utils.BinaryOrder(buf[i+10:])
+			firstIfdOffset := byteOrder.Uint32(buf[i+14 : i+18])
+			return meta.NewExifHeader(byteOrder, firstIfdOffset, uint32(i+10), uint32(size-exifPrefixLength), imagetype.ImageJPEG), nil
+		}
+		i += 2 + size
+	}
+	return meta.ExifHeader{}, meta.ErrNoExif
+}
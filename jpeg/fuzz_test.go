@@ -0,0 +1,53 @@
+package jpeg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tdelov/imagemeta/meta"
+)
+
+func FuzzScanJPEG(f *testing.F) {
+	for _, pattern := range []string{"../assets/*.jpg", "../testImages/*.jpg"} {
+		files, _ := filepath.Glob(pattern)
+		for _, file := range files {
+			buf, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf)
+		}
+	}
+	discard := func(r io.Reader) error {
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		exifReader := func(r io.Reader, h meta.ExifHeader) error { return discard(r) }
+		_ = ScanJPEG(bytes.NewReader(data), exifReader, discard)
+		s := Scanner{ExifReader: exifReader, XMPReader: discard, ICCReader: discard}
+		_, _ = s.Scan(bytes.NewReader(data))
+		_, _, _ = ScanFrame(bytes.NewReader(data))
+	})
+}
+
+// panicReader panics with a value that is not an error.
+type panicReader struct{}
+
+func (panicReader) Read(p []byte) (int, error) { panic("read") }
+
+func TestScanPanic(t *testing.T) {
+	if err := ScanJPEG(panicReader{}, nil, nil); err == nil || err.Error() != "jpeg: panic: read" {
+		t.Errorf("Incorrect error wanted %q got %v", "jpeg: panic: read", err)
+	}
+}
+
+func TestScanMarkersBeforeSOI(t *testing.T) {
+	buf := bytes.Repeat([]byte{0xff}, 256)
+	if err := ScanJPEG(bytes.NewReader(buf), nil, nil); err != ErrNoJPEGMarker {
+		t.Errorf("Incorrect error wanted %v got %v", ErrNoJPEGMarker, err)
+	}
+}
@@ -7,10 +7,12 @@ package jpeg
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"sync"
 
 	"github.com/tdelov/imagemeta/imagetype"
@@ -32,6 +34,10 @@ const (
 type jpegReader struct {
 	ExifReader func(r io.Reader, h meta.ExifHeader) error
 	XMPReader  func(r io.Reader) error
+	ICCReader  func(r io.Reader) error
+
+	logger *slog.Logger
+	ctx    context.Context
 
 	// Reader
 	br  *bufio.Reader
@@ -49,6 +55,10 @@ type jpegReader struct {
 	// Reader
 	pos       uint8
 	discarded uint32
+
+	// readSOF continues the scan past DQT markers until
+	// the SOF marker of the primary image has been read.
+	readSOF bool
 }
 
 var bufferPool = sync.Pool{
@@ -60,9 +70,63 @@ var bufferPool = sync.Pool{
 //
 // Returns the error ErrNoJPEGMarker if a JPEG SOF was not found.
 func ScanJPEG(r io.Reader, exifReader func(r io.Reader, header meta.ExifHeader) error, xmpReader func(r io.Reader) error) (err error) {
+	jr := &jpegReader{ExifReader: exifReader, XMPReader: xmpReader, logger: Logger, ctx: context.Background()}
+	return jr.scan(r)
+}
+
+// Scanner scans a JPEG Image for its metadata. ExifReader, XMPReader and ICCReader
+// are run at their respective markers during the scan when they are not nil.
+//
+// ICCReader is run once for every APP2 ICC Profile chunk in the order they
+// appear in the image.
+type Scanner struct {
+	ExifReader func(r io.Reader, header meta.ExifHeader) error
+	XMPReader  func(r io.Reader) error
+	ICCReader  func(r io.Reader) error
+
+	// Logger is the logger used during the scan. The package Logger is used when nil.
+	Logger *slog.Logger
+}
+
+// Scan scans a reader for JPEG Image markers until the SOF marker of the primary image.
+// Returns the dimensions of the primary image or an error.
+//
+// Returns the error ErrNoJPEGMarker if a JPEG SOF was not found.
+func (s Scanner) Scan(r io.Reader) (meta.Dimensions, error) {
+	return s.ScanContext(context.Background(), r)
+}
+
+// ScanContext is Scan with a context. The context is checked before each JPEG marker
+// and ctx.Err() is returned when the context is done.
+func (s Scanner) ScanContext(ctx context.Context, r io.Reader) (meta.Dimensions, error) {
+	jr := &jpegReader{ExifReader: s.ExifReader, XMPReader: s.XMPReader, ICCReader: s.ICCReader, logger: Logger, ctx: ctx, readSOF: true}
+	if s.Logger != nil {
+		jr.logger = s.Logger
+	}
+	err := jr.scan(r)
+	return meta.NewDimensions(uint32(jr.width), uint32(jr.height)), err
+}
+
+// ScanFrame scans a reader for JPEG markers until the SOF marker of the primary image.
+// Returns the dimensions of the primary image and true when it is coded with a lossless
+// process, as the raw image data of Camera Raw images is.
+//
+// Returns the error ErrNoJPEGMarker if a JPEG SOF was not found.
+func ScanFrame(r io.Reader) (dim meta.Dimensions, lossless bool, err error) {
+	jr := &jpegReader{logger: Logger, ctx: context.Background(), readSOF: true}
+	err = jr.scan(r)
+	// SOF3, SOF7, SOF11 and SOF15 are lossless
+	return meta.NewDimensions(uint32(jr.width), uint32(jr.height)), jr.sofMarker&3 == 3, err
+}
+
+func (jr *jpegReader) scan(r io.Reader) (err error) {
 	defer func() {
 		if state := recover(); state != nil {
-			err = state.(error)
+			// Panics that are not errors are wrapped
+			var ok bool
+			if err, ok = state.(error); !ok {
+				err = fmt.Errorf("jpeg: panic: %v", state)
+			}
 		}
 	}()
 
@@ -73,8 +137,7 @@ func ScanJPEG(r io.Reader, exifReader func(r io.Reader, header meta.ExifHeader)
 		br = bufferPool.Get().(*bufio.Reader)
 		br.Reset(r)
 	}
-
-	jr := &jpegReader{br: br, ExifReader: exifReader, XMPReader: xmpReader}
+	jr.br = br
 
 	defer func() {
 		if localBuffer {
@@ -85,30 +148,31 @@ func ScanJPEG(r io.Reader, exifReader func(r io.Reader, header meta.ExifHeader)
 	for jr.nextMarker() {
 		switch jr.marker >> 4 {
 		case 12: // SOF Markers
+			if jr.marker == markerDHT {
+				if jr.logInfo() {
+					jr.logMarker("")
+				}
+				// Ignore DHT Markers
+				jr.ignoreMarker()
+				continue
+			}
 			jr.readSOFMarker()
+			// Stop parsing after the SOF Marker of the primary image
+			if jr.readSOF && jr.pos == 1 {
+				return jr.decodeError(jr.err)
+			}
 		case 14: // APP Markers
 			jr.readAPPMarker()
 		default:
 			switch jr.marker {
-			case markerDHT:
-				if logInfo() {
-					jr.logMarker("")
-				}
-				// Artificial End Of Image for DHT Marker.
-				// This is done to improve performance.
-				if jr.pos == 1 {
-					return nil
-				}
-				// Ignore DHT Markers
-				jr.ignoreMarker()
 			case markerSOI:
-				if logInfo() {
+				if jr.logInfo() {
 					jr.logMarker("")
 				}
 				jr.pos++
 				jr.err = jr.discard(2)
 			case markerEOI:
-				if logInfo() {
+				if jr.logInfo() {
 					jr.logMarker("")
 				}
 				jr.pos--
@@ -118,27 +182,41 @@ func ScanJPEG(r io.Reader, exifReader func(r io.Reader, header meta.ExifHeader)
 				}
 				jr.err = jr.discard(2)
 			case markerDQT:
-				// Stop parsing at DQT Markers
-				if logInfo() {
+				if jr.logInfo() {
 					jr.logMarker("")
 				}
-				jr.ignoreMarker() // Ignore DQT Markers and close parsing
-				return nil
+				jr.ignoreMarker() // Ignore DQT Markers
+				// Stop parsing at DQT Markers unless the SOF Marker is required
+				if !jr.readSOF {
+					return nil
+				}
 			case markerDRI:
 				jr.err = jr.discard(6)
 			default: // unknown marker
-				if logInfo() {
+				if jr.logInfo() {
 					jr.logMarker("")
 				}
 				jr.ignoreMarker()
 			}
 		}
+		if jr.err != nil {
+			return jr.decodeError(jr.err)
+		}
 	}
 	return jr.err
 }
 
+// decodeError returns a DecodeError for err at the current marker.
+func (jr *jpegReader) decodeError(err error) error {
+	err = meta.ContainerError(err, jr.marker.String(), int64(jr.offset))
+	return meta.ImageTypeError(err, imagetype.ImageJPEG)
+}
+
 func (jr *jpegReader) nextMarker() bool {
 	for jr.err == nil {
+		if jr.err = jr.ctx.Err(); jr.err != nil {
+			return false
+		}
 		if jr.buf, jr.err = jr.peek(64); jr.err != nil {
 			jr.err = ErrNoJPEGMarker
 			return false
@@ -165,6 +243,8 @@ func (jr *jpegReader) nextMarker() bool {
 			jr.marker = markerType(jr.buf[1])
 			return true
 		}
+		// Markers before the SOI marker are skipped
+		jr.err = jr.discard(1)
 	}
 	return false
 }
@@ -188,7 +268,7 @@ func (jr *jpegReader) discard(i int) (err error) {
 func (jr *jpegReader) readAPP0() {
 	// Is JFIF Marker
 	if isJFIFPrefix(jr.buf) || isJFIFPrefixExt(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP0 JFIF")
 		}
 	}
@@ -199,7 +279,7 @@ func (jr *jpegReader) readAPP0() {
 func (jr *jpegReader) readAPP1() {
 	// APP1 Exif Marker
 	if isExifPrefix(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP1 Exif")
 		}
 		jr.err = jr.readExif()
@@ -208,7 +288,7 @@ func (jr *jpegReader) readAPP1() {
 
 	// APP1 XMP Marker
 	if isXMPPrefix(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP1 XMP")
 		}
 		jr.err = jr.readXMP()
@@ -217,7 +297,7 @@ func (jr *jpegReader) readAPP1() {
 
 	// APP1 XMP Extension marker (NOT SUPPORTED)
 	if isXMPPrefixExt(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP1 XMP Extension")
 		}
 		// Ignore XMP Extension
@@ -228,9 +308,13 @@ func (jr *jpegReader) readAPP1() {
 // readAPP2
 func (jr *jpegReader) readAPP2() {
 	if isICCProfilePrefix(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP2 ICC Profile")
 		}
+		if jr.ICCReader != nil {
+			jr.err = jr.readICC()
+			return
+		}
 		// Ignore ICC Profile Marker
 	}
 	jr.ignoreMarker()
@@ -239,7 +323,7 @@ func (jr *jpegReader) readAPP2() {
 // readAPP13
 func (jr *jpegReader) readAPP13() {
 	if isPhotoshopPrefix(jr.buf) {
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("APP13 Photoshop")
 		}
 		// Ignore Photoshop Profile Marker
@@ -309,6 +393,24 @@ func (jr *jpegReader) readXMP() (err error) {
 	return jr.discard(remain)
 }
 
+// readICC reads an ICC Profile chunk with the attached ICCReader.
+// The chunk sequence number and chunk count are discarded.
+func (jr *jpegReader) readICC() (err error) {
+	// Read the length of the ICC Profile chunk
+	remain := int(jr.size) - 2 - iccPrefixLength
+
+	// Discard App Marker bytes, header length bytes and ICC Profile header bytes
+	if err = jr.discard(4 + iccPrefixLength); err != nil {
+		return err
+	}
+	r := io.LimitReader(jr.br, int64(remain))
+	if err = jr.ICCReader(r); err != nil {
+		return err
+	}
+	// Discard remaining bytes
+	return jr.discard(int(r.(*io.LimitedReader).N))
+}
+
 // readSOFMarker reads a JPEG Start of file with the uint16
 // width, height, and components of the JPEG image.
 func (jr *jpegReader) readSOFMarker() {
@@ -316,7 +418,7 @@ func (jr *jpegReader) readSOFMarker() {
 	width := jpegEndian.Uint16(jr.buf[7:9])
 	comp := uint8(jr.buf[9])
 	if jr.pos == 1 {
-		jr.sofHeader = sofHeader{height, width, comp}
+		jr.sofHeader = sofHeader{height, width, comp, jr.marker}
 	}
 	jr.err = jr.discard(int(jr.size) + 2)
 }
@@ -326,6 +428,7 @@ type sofHeader struct {
 	height     uint16
 	width      uint16
 	components uint8
+	sofMarker  markerType
 }
 
 // readAPPMarker reads an APP JPEG Marker
@@ -340,7 +443,7 @@ func (jr *jpegReader) readAPPMarker() {
 	case markerAPP13:
 		jr.readAPP13()
 	default:
-		if logInfo() {
+		if jr.logInfo() {
 			jr.logMarker("")
 		}
 		jr.ignoreMarker()
@@ -377,6 +480,7 @@ const (
 	markerSOI       markerType = 0xD8
 	markerEOI       markerType = 0xD9
 	markerImageData markerType = 0xD9
+	markerSOS       markerType = 0xDA
 	markerDQT       markerType = 0xDB
 	markerDRI       markerType = 0xDD
 
@@ -408,6 +512,7 @@ const (
 	photoshopPrefix  = "Photoshop "
 	exifPrefixLength = 8
 	xmpPrefixLength  = 29
+	iccPrefixLength  = 14 // "ICC_PROFILE\000" + chunk sequence number + chunk count
 )
 
 var (
@@ -430,6 +535,7 @@ var (
 		markerDHT:   "DHT",
 		markerSOI:   "SOI",
 		markerEOI:   "EOI",
+		markerSOS:   "SOS",
 		markerDQT:   "DQT",
 		markerDRI:   "DRI",
 		markerAPP0:  "APP0",
@@ -1,26 +1,25 @@
 package jpeg
 
 import (
-	"os"
+	"log/slog"
 
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/tdelov/imagemeta/internal/logging"
 )
 
 var (
 	// Logger is the logger
-	Logger zerolog.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.PanicLevel).With().Str("package", "jpeg").Logger()
+	Logger *slog.Logger = logging.Discard
 )
 
-func logInfo() bool {
-	return Logger.GetLevel() <= zerolog.InfoLevel
+func (jr *jpegReader) logInfo() bool {
+	return logging.Enabled(jr.logger, slog.LevelInfo)
 }
 
 func (jr *jpegReader) logMarker(str string) {
-	if logInfo() {
+	if jr.logInfo() {
 		if len(str) == 0 {
 			str = jr.marker.String()
 		}
-		Logger.Info().Str("marker", str).Int("length", int(jr.size)).Uint32("offset", uint32(jr.discarded)).Send()
+		logging.NewEvent(jr.logger, slog.LevelInfo).Str("package", "jpeg").Str("marker", str).Int("length", int(jr.size)).Uint32("offset", uint32(jr.discarded)).Send()
 	}
 }
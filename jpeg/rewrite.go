@@ -0,0 +1,277 @@
+// Copyright (c) 2018-2023 Evan Oberholster. All rights reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package jpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Errors
+var (
+	ErrSegmentLength = errors.New("error segment payload is too long")
+	ErrSegment       = errors.New("error unsupported segment")
+)
+
+// Segment is a metadata segment of a JPEG image that can be rewritten.
+type Segment uint8
+
+// Segments
+const (
+	// SegmentExif is the APP1 Exif segment
+	SegmentExif Segment = iota
+	// SegmentXMP is the APP1 XMP segment
+	SegmentXMP
+	// SegmentICC is the APP2 ICC Profile segment. ICC Profiles longer than a
+	// single segment are written as a sequence of chunks.
+	SegmentICC
+	// SegmentPhotoshop is the APP13 Photoshop segment
+	SegmentPhotoshop
+
+	segmentCount = 4
+)
+
+const (
+	// segmentMaxLength is the maximum length of a JPEG marker segment
+	// including the 2 length bytes.
+	segmentMaxLength = 0xFFFF
+	iccMaxChunks     = 0xFF
+
+	photoshopHeader = "Photoshop 3.0\000"
+)
+
+// String is a Stringer interface for Segment
+func (s Segment) String() string {
+	switch s {
+	case SegmentExif:
+		return "APP1 Exif"
+	case SegmentXMP:
+		return "APP1 XMP"
+	case SegmentICC:
+		return "APP2 ICC Profile"
+	case SegmentPhotoshop:
+		return "APP13 Photoshop"
+	}
+	return "Unknown segment"
+}
+
+// marker returns the JPEG marker and the header written before the payload of s.
+func (s Segment) marker() (markerType, string) {
+	switch s {
+	case SegmentExif:
+		return markerAPP1, exifPrefix
+	case SegmentXMP:
+		return markerAPP1, xmpPrefix
+	case SegmentICC:
+		return markerAPP2, iccPrefix + "\000"
+	default:
+		return markerAPP13, photoshopHeader
+	}
+}
+
+// rewrite is the change of a Segment.
+type rewrite struct {
+	payload []byte
+	set     bool
+	remove  bool
+	written bool
+}
+
+// Rewriter rewrites the metadata segments of a JPEG image without recompressing it.
+//
+// Segments that are set replace the first segment of the same kind in the image, or are
+// inserted after the APP0 JFIF segments and the metadata segments of an earlier canonical
+// order (Exif, XMP, ICC Profile, Photoshop) when the image does not have one. Segments that are
+// removed are dropped from the image. All other markers and the entropy-coded image data
+// are copied unchanged.
+type Rewriter struct {
+	segments [segmentCount]rewrite
+
+	// Logger is the logger used during the rewrite. The package Logger is used when nil.
+	Logger *slog.Logger
+}
+
+// Set replaces or inserts the Segment s with payload. The payload does not include the
+// segment header:
+//   - SegmentExif is the Tiff header and IFDs, an "Exif\x00\x00" prefix is removed.
+//   - SegmentXMP is the XMP packet.
+//   - SegmentICC is the complete ICC Profile.
+//   - SegmentPhotoshop is the Photoshop Image Resource Blocks.
+//
+// The payload replaces the first segment of kind s in the image, later segments of kind s
+// are dropped. These are duplicate segments, the APP1 XMP Extension segments of the
+// replaced XMP and the other chunks of the replaced ICC Profile.
+//
+// Returns ErrSegmentLength if the payload does not fit in the segment and ErrSegment if
+// s is not a supported Segment.
+func (rw *Rewriter) Set(s Segment, payload []byte) error {
+	if s >= segmentCount {
+		return ErrSegment
+	}
+	if s == SegmentExif && len(payload) >= len(exifPrefix) && string(payload[:len(exifPrefix)]) == exifPrefix {
+		payload = payload[len(exifPrefix):]
+	}
+	_, header := s.marker()
+	maxLength := segmentMaxLength - 2 - len(header)
+	if s == SegmentICC {
+		maxLength = iccMaxChunks * (maxLength - 2)
+	}
+	if len(payload) > maxLength {
+		return ErrSegmentLength
+	}
+	rw.segments[s] = rewrite{payload: payload, set: true}
+	return nil
+}
+
+// Remove removes all segments of kind s from the image. Removing SegmentXMP also
+// removes the APP1 XMP Extension segments.
+func (rw *Rewriter) Remove(s Segment) error {
+	if s >= segmentCount {
+		return ErrSegment
+	}
+	rw.segments[s] = rewrite{remove: true}
+	return nil
+}
+
+// Rewrite reads a JPEG image from r and writes it with the rewritten segments to w.
+// The marker segments before the SOS marker of the primary image are held in memory,
+// the image data after it is copied unchanged.
+//
+// Returns the error ErrNoJPEGMarker if a JPEG SOS was not found.
+func (rw Rewriter) Rewrite(w io.Writer, r io.Reader) (err error) {
+	jr := &jpegReader{logger: Logger, ctx: context.Background()}
+	if rw.Logger != nil {
+		jr.logger = rw.Logger
+	}
+
+	br, ok := r.(*bufio.Reader)
+	if !ok || br.Size() < bufferSize {
+		br = bufferPool.Get().(*bufio.Reader)
+		br.Reset(r)
+		defer bufferPool.Put(br)
+	}
+	jr.br = br
+
+	// SOI Marker
+	header := []byte{byte(markerFirstByte), byte(markerSOI)}
+	for jr.nextMarker() {
+		if jr.marker == markerSOS {
+			break
+		}
+		s, ok := jr.segment()
+		switch {
+		case ok:
+			// Segments of an earlier canonical order are inserted before s
+			header = rw.appendInserted(header, s)
+		case !(jr.marker == markerAPP0 && (isJFIFPrefix(jr.buf) || isJFIFPrefixExt(jr.buf))):
+			header = rw.appendInserted(header, segmentCount)
+		}
+		if !ok || !(rw.segments[s].set || rw.segments[s].remove) {
+			if header, jr.err = jr.appendMarker(header); jr.err != nil {
+				return jr.decodeError(jr.err)
+			}
+			continue
+		}
+		if jr.logInfo() {
+			jr.logMarker(s.String())
+		}
+		if c := &rw.segments[s]; c.set && !c.written {
+			header = appendSegment(header, s, c.payload)
+			c.written = true
+		}
+		if jr.ignoreMarker(); jr.err != nil {
+			return jr.decodeError(jr.err)
+		}
+	}
+	if jr.err != nil {
+		return jr.decodeError(jr.err)
+	}
+	header = rw.appendInserted(header, segmentCount)
+	if _, err = w.Write(header); err != nil {
+		return err
+	}
+	// Copy the SOS marker and the entropy-coded image data
+	_, err = io.Copy(w, jr.br)
+	return err
+}
+
+// appendInserted appends the set segments before the Segment next that were not
+// written yet, in the canonical order of the Segments. Segments that are found
+// later in the image are inserted here instead.
+func (rw *Rewriter) appendInserted(buf []byte, next Segment) []byte {
+	for s := Segment(0); s < next; s++ {
+		if c := &rw.segments[s]; c.set && !c.written {
+			buf = appendSegment(buf, s, c.payload)
+			c.written = true
+		}
+	}
+	return buf
+}
+
+// segment returns the Segment of the current marker and true when it is a Segment.
+// APP1 XMP Extension markers are returned as SegmentXMP.
+func (jr *jpegReader) segment() (Segment, bool) {
+	switch jr.marker {
+	case markerAPP1:
+		if isExifPrefix(jr.buf) {
+			return SegmentExif, true
+		}
+		if isXMPPrefix(jr.buf) || isXMPPrefixExt(jr.buf) {
+			return SegmentXMP, true
+		}
+	case markerAPP2:
+		if isICCProfilePrefix(jr.buf) {
+			return SegmentICC, true
+		}
+	case markerAPP13:
+		if isPhotoshopPrefix(jr.buf) {
+			return SegmentPhotoshop, true
+		}
+	}
+	return 0, false
+}
+
+// appendMarker appends the current marker to buf and advances the reader.
+func (jr *jpegReader) appendMarker(buf []byte) ([]byte, error) {
+	n := len(buf)
+	buf = append(buf, make([]byte, int(jr.size)+2)...)
+	if _, err := io.ReadFull(jr.br, buf[n:]); err != nil {
+		return buf[:n], err
+	}
+	jr.discarded += uint32(jr.size) + 2
+	return buf, nil
+}
+
+// appendSegment appends the JPEG marker segments of s with payload to buf.
+func appendSegment(buf []byte, s Segment, payload []byte) []byte {
+	marker, header := s.marker()
+	if s != SegmentICC {
+		buf = append(buf, byte(markerFirstByte), byte(marker))
+		buf = jpegEndian.AppendUint16(buf, uint16(2+len(header)+len(payload)))
+		buf = append(buf, header...)
+		return append(buf, payload...)
+	}
+	// ICC Profile chunks are numbered from 1
+	chunkLength := segmentMaxLength - 2 - len(header) - 2
+	count := (len(payload) + chunkLength - 1) / chunkLength
+	if count == 0 {
+		count = 1
+	}
+	for i := 0; i < count; i++ {
+		chunk := payload[i*chunkLength:]
+		if len(chunk) > chunkLength {
+			chunk = chunk[:chunkLength]
+		}
+		buf = append(buf, byte(markerFirstByte), byte(marker))
+		buf = jpegEndian.AppendUint16(buf, uint16(2+len(header)+2+len(chunk)))
+		buf = append(buf, header...)
+		buf = append(buf, byte(i+1), byte(count))
+		buf = append(buf, chunk...)
+	}
+	return buf
+}
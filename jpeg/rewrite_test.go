@@ -0,0 +1,149 @@
+// Copyright (c) 2018-2023 Evan Oberholster. All rights reserved.
+// Use of this source code is governed by a license that can be
+// found in the LICENSE file.
+
+package jpeg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+func TestRewriter(t *testing.T) {
+	src, err := os.ReadFile("../assets/a1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"></x:xmpmeta>`)
+	icc := bytes.Repeat([]byte{0x01, 0x02, 0x03, 0x04, 0x05}, 14000)
+
+	var rw Rewriter
+	_ = rw.Set(SegmentXMP, xmp)
+	_ = rw.Set(SegmentICC, icc)
+	_ = rw.Remove(SegmentExif)
+	_ = rw.Remove(SegmentPhotoshop)
+	if err := rw.Set(SegmentPhotoshop, make([]byte, segmentMaxLength)); err != ErrSegmentLength {
+		t.Errorf("Incorrect error wanted %s got %v", ErrSegmentLength, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := rw.Rewrite(buf, bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+
+	// The image data is copied unchanged
+	sos := []byte{0xff, byte(markerSOS)}
+	if !bytes.Equal(src[bytes.Index(src, sos):], out[bytes.Index(out, sos):]) {
+		t.Error("Incorrect image data after SOS")
+	}
+	if bytes.Contains(out, []byte(photoshopHeader)) {
+		t.Error("Incorrect APP13 Photoshop segment was not removed")
+	}
+
+	var gotXMP, gotICC []byte
+	var exifCount int
+	s := Scanner{
+		ExifReader: func(r io.Reader, header meta.ExifHeader) error {
+			exifCount++
+			return nil
+		},
+		XMPReader: func(r io.Reader) (err error) {
+			gotXMP, err = io.ReadAll(r)
+			return err
+		},
+		ICCReader: func(r io.Reader) error {
+			chunk, err := io.ReadAll(r)
+			gotICC = append(gotICC, chunk...)
+			return err
+		},
+	}
+	dims, err := s.Scan(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dims.Width != 389 || dims.Height != 259 {
+		t.Errorf("Incorrect dimensions wanted 389x259 got %s", dims)
+	}
+	if exifCount != 0 {
+		t.Errorf("Incorrect APP1 Exif segment was not removed")
+	}
+	if !bytes.Equal(gotXMP, xmp) {
+		t.Errorf("Incorrect XMP wanted %q got %q", xmp, gotXMP)
+	}
+	if !bytes.Equal(gotICC, icc) {
+		t.Errorf("Incorrect ICC Profile wanted %d bytes got %d bytes", len(icc), len(gotICC))
+	}
+
+	// Insert an Exif segment after the APP0 JFIF segment
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0}
+	rw = Rewriter{}
+	_ = rw.Set(SegmentExif, append([]byte(exifPrefix), tiff...))
+	buf.Reset()
+	if err := rw.Rewrite(buf, bytes.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+	err = ScanJPEG(bytes.NewReader(buf.Bytes()), func(r io.Reader, header meta.ExifHeader) error {
+		exifCount++
+		metaExifHeaderEqual(t, meta.NewExifHeader(utils.BigEndian, 8, 30, uint32(len(tiff)), header.ImageType), header)
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exifCount != 1 {
+		t.Errorf("Incorrect APP1 Exif segment was not inserted")
+	}
+
+	// Insert an XMP segment after the APP1 Exif segment of a JFIF+Exif image
+	rw = Rewriter{}
+	_ = rw.Remove(SegmentXMP)
+	_ = rw.Remove(SegmentICC)
+	jfifExif := new(bytes.Buffer)
+	if err := rw.Rewrite(jfifExif, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	rw = Rewriter{}
+	_ = rw.Set(SegmentXMP, xmp)
+	_ = rw.Set(SegmentICC, icc[:100])
+	buf.Reset()
+	if err := rw.Rewrite(buf, bytes.NewReader(jfifExif.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"JFIF", exifPrefix, xmpPrefix, iccPrefix}
+	if got := segmentPrefixes(buf.Bytes(), len(want)); !equalStrings(got, want) {
+		t.Errorf("Incorrect segment order wanted %q got %q", want, got)
+	}
+}
+
+// segmentPrefixes returns the prefixes of the first n marker segments after SOI.
+func segmentPrefixes(buf []byte, n int) (prefixes []string) {
+	buf = buf[2:]
+	for i := 0; i < n && len(buf) > 4; i++ {
+		length := int(jpegEndian.Uint16(buf[2:]))
+		payload := buf[4 : 2+length]
+		for _, prefix := range []string{"JFIF", exifPrefix, xmpPrefix, iccPrefix} {
+			if bytes.HasPrefix(payload, []byte(prefix)) {
+				prefixes = append(prefixes, prefix)
+			}
+		}
+		buf = buf[2+length:]
+	}
+	return prefixes
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
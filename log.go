@@ -1,24 +1,31 @@
 package imagemeta
 
 import (
-	"io"
-	"os"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/internal/logging"
 	"github.com/tdelov/imagemeta/isobmff"
 	"github.com/tdelov/imagemeta/jpeg"
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/tdelov/imagemeta/preview"
 )
 
 var (
 	// Logger is the logger
-	logger zerolog.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.PanicLevel)
+	logger *slog.Logger = logging.Discard
 )
 
-func SetLogger(w io.Writer, level zerolog.Level) {
-	logger = log.Output(w).Level(level)
+// SetLogger sets the slog.Handler of the imagemeta, jpeg, exif2, isobmff and preview packages.
+// A nil Handler discards all log records, this is the default. A zerolog.Logger can be used
+// with the github.com/tdelov/imagemeta/zerologhandler module.
+// It is not safe to call concurrently with decoding, use WithLogger for a per-call logger.
+func SetLogger(h slog.Handler) {
+	logger = logging.Discard
+	if h != nil {
+		logger = slog.New(h)
+	}
 	jpeg.Logger = logger
 	exif2.Logger = logger
 	isobmff.Logger = logger
+	preview.Logger = logger
 }
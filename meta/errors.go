@@ -0,0 +1,124 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+)
+
+// DecodeError is an error with the location in the image where decoding failed.
+// The underlying error is available with errors.Is and errors.As.
+type DecodeError struct {
+	Err       error               // Underlying error
+	Path      string              // Container path. e.g. "APP1/IFD0/ExifIFD" or "meta/iloc"
+	Offset    int64               // Absolute byte offset in the image, -1 when unknown
+	TagID     tag.ID              // Tag ID when the error is for a tag value
+	ImageType imagetype.ImageType // ImageType of the image
+}
+
+// NewDecodeError returns a new DecodeError for err. Returns nil if err is nil.
+func NewDecodeError(err error, path string, offset int64, tagID tag.ID) error {
+	if err == nil {
+		return nil
+	}
+	return &DecodeError{Err: err, Path: path, Offset: offset, TagID: tagID}
+}
+
+// Error implements the error interface
+func (e *DecodeError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("decode error")
+	if e.ImageType != imagetype.ImageUnknown {
+		sb.WriteString(" ")
+		sb.WriteString(e.ImageType.String())
+	}
+	if e.Path != "" {
+		sb.WriteString(" at ")
+		sb.WriteString(e.Path)
+	}
+	if e.TagID != 0 {
+		sb.WriteString(" tag ")
+		sb.WriteString(e.TagID.String())
+	}
+	if e.Offset >= 0 {
+		sb.WriteString(fmt.Sprintf(" offset %d", e.Offset))
+	}
+	if e.Err != nil {
+		sb.WriteString(": ")
+		sb.WriteString(e.Err.Error())
+	}
+	return sb.String()
+}
+
+// Unwrap returns the underlying error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ContainerError adds the path of a container to a copy of a DecodeError within err,
+// the DecodeError of err is not modified as it may be shared. The messages and errors
+// that wrap the DecodeError are kept. When err does not contain a DecodeError a new
+// DecodeError is returned with the container path and offset. Returns nil if err is nil.
+func ContainerError(err error, path string, offset int64) error {
+	if err == nil {
+		return nil
+	}
+	var de *DecodeError
+	if errors.As(err, &de) {
+		cp := *de
+		if cp.Path == "" {
+			cp.Path = path
+		} else if path != "" {
+			cp.Path = path + "/" + cp.Path
+		}
+		return copyError(err, de, &cp)
+	}
+	return &DecodeError{Err: err, Path: path, Offset: offset}
+}
+
+// ImageTypeError sets the ImageType of a copy of a DecodeError within err when it is
+// unknown, the DecodeError of err is not modified as it may be shared.
+// Returns err unchanged when it does not contain a DecodeError.
+func ImageTypeError(err error, it imagetype.ImageType) error {
+	var de *DecodeError
+	if errors.As(err, &de) && de.ImageType == imagetype.ImageUnknown {
+		cp := *de
+		cp.ImageType = it
+		return copyError(err, de, &cp)
+	}
+	return err
+}
+
+// copyError returns err with its DecodeError de replaced by the copy cp.
+func copyError(err error, de, cp *DecodeError) error {
+	if err == error(de) {
+		return cp
+	}
+	return &wrapError{
+		msg: strings.Replace(err.Error(), de.Error(), cp.Error(), 1),
+		de:  cp,
+		err: err,
+	}
+}
+
+// wrapError keeps the chain of an error that wraps a DecodeError when the
+// DecodeError is replaced by a copy. errors.As finds the copy before the
+// original DecodeError and errors.Is still finds every error of the chain.
+type wrapError struct {
+	msg string
+	de  *DecodeError
+	err error
+}
+
+// Error implements the error interface
+func (e *wrapError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the copy of the DecodeError and the original error
+func (e *wrapError) Unwrap() []error {
+	return []error{e.de, e.err}
+}
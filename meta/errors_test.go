@@ -0,0 +1,68 @@
+package meta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestDecodeError(t *testing.T) {
+	err := NewDecodeError(io.ErrUnexpectedEOF, "Ifd/ExifIfd", 1234, tag.ID(0x829a))
+	err = ContainerError(err, "APP1", 20)
+	err = ImageTypeError(pkgerrors.Wrap(err, "decode"), imagetype.ImageJPEG)
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Incorrect errors.Is wanted %s got %s", io.ErrUnexpectedEOF, err)
+	}
+	var de *DecodeError
+	if !errors.As(err, &de) {
+		t.Fatalf("Incorrect errors.As wanted DecodeError got %T", err)
+	}
+	if de.Path != "APP1/Ifd/ExifIfd" || de.Offset != 1234 || de.TagID != 0x829a || de.ImageType != imagetype.ImageJPEG {
+		t.Errorf("Incorrect DecodeError got %+v", de)
+	}
+	want := "decode error image/jpeg at APP1/Ifd/ExifIfd tag 0x829a offset 1234: unexpected EOF"
+	if de.Error() != want {
+		t.Errorf("Incorrect Error wanted %q got %q", want, de.Error())
+	}
+
+	err = ContainerError(io.EOF, "meta/iloc", 42)
+	if !errors.As(err, &de) || de.Path != "meta/iloc" || de.Offset != 42 || !errors.Is(err, io.EOF) {
+		t.Errorf("Incorrect ContainerError got %v", err)
+	}
+	// The DecodeError of a shared error is not modified
+	shared := NewDecodeError(io.EOF, "iloc", 42, 0)
+	wrapped := ContainerError(pkgerrors.Wrap(shared, "readMeta"), "meta", 8)
+	if !errors.As(wrapped, &de) || de.Path != "meta/iloc" || wrapped.Error() != "readMeta: decode error at meta/iloc offset 42: EOF" {
+		t.Errorf("Incorrect ContainerError got %v", wrapped)
+	}
+	if ContainerError(shared, "meta", 8); shared.(*DecodeError).Path != "iloc" {
+		t.Errorf("Incorrect shared DecodeError path wanted %q got %q", "iloc", shared.(*DecodeError).Path)
+	}
+	if ImageTypeError(shared, imagetype.ImageJPEG); shared.(*DecodeError).ImageType != imagetype.ImageUnknown {
+		t.Errorf("Incorrect shared DecodeError image type wanted %s got %s", imagetype.ImageUnknown, shared.(*DecodeError).ImageType)
+	}
+
+	// The errors wrapped alongside the DecodeError are kept
+	errSentinel := errors.New("sentinel")
+	err = ImageTypeError(ContainerError(fmt.Errorf("%w: %w", errSentinel, shared), "meta", 8), imagetype.ImageHEIF)
+	if !errors.Is(err, errSentinel) || !errors.Is(err, io.EOF) {
+		t.Errorf("Incorrect errors.Is wanted %s and %s got %s", errSentinel, io.EOF, err)
+	}
+	want = "sentinel: decode error " + imagetype.ImageHEIF.String() + " at meta/iloc offset 42: EOF"
+	if !errors.As(err, &de) || de.Path != "meta/iloc" || de.ImageType != imagetype.ImageHEIF || err.Error() != want {
+		t.Errorf("Incorrect wrapped DecodeError wanted %q got %q", want, err)
+	}
+	if err := ImageTypeError(io.EOF, imagetype.ImageJPEG); err != io.EOF {
+		t.Errorf("Incorrect ImageTypeError wanted %s got %v", io.EOF, err)
+	}
+
+	if NewDecodeError(nil, "", 0, 0) != nil || ContainerError(nil, "", 0) != nil {
+		t.Error("Incorrect DecodeError wanted nil for a nil error")
+	}
+}
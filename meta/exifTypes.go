@@ -124,6 +124,26 @@ func (et ExposureTime) MarshalText() (text []byte, err error) {
 	return strconv.AppendFloat(nil, a, 'f', 2, 32), nil
 }
 
+// UnmarshalText implements the TextUnmarshaler interface that is
+// used by encoding/json. ex: "1/250" or "2.00"
+func (et *ExposureTime) UnmarshalText(text []byte) (err error) {
+	if len(text) == 0 {
+		*et = 0
+		return nil
+	}
+	if len(text) > 2 && text[0] == '1' && text[1] == '/' {
+		d, err := strconv.ParseFloat(string(text[2:]), 32)
+		if err != nil {
+			return err
+		}
+		*et = ExposureTime(1 / float32(d))
+		return nil
+	}
+	f, err := strconv.ParseFloat(string(text), 32)
+	*et = ExposureTime(f)
+	return err
+}
+
 func (et ExposureTime) String() string {
 	buf, _ := et.MarshalText()
 	return string(buf)
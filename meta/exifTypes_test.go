@@ -149,13 +149,14 @@ func TestShutterSpeed(t *testing.T) {
 			t.Errorf("Incorrect ShutterSpeed.String wanted %s got %s ", bm.str, b)
 		}
 
-		//b1 := ExposureTime(0.0)
-		//if err = b1.UnmarshalText([]byte(bm.str)); err != nil {
-		//	t.Error(err)
-		//////}
+		b1 := ExposureTime(0.0)
+		if err = b1.UnmarshalText([]byte(bm.str)); err != nil {
+			t.Error(err)
+		}
 
-		//m, _ := b1.MarshalText()
-		//assert.Equal(t, bm.ss, b1, "UnmarshalText #%s, wanted: %s got: %s", bm.name, bm.str, m)
+		if b1.String() != bm.str {
+			t.Errorf("Incorrect ShutterSpeed.UnmarshalText #%s wanted %s got %s ", bm.name, bm.str, b1)
+		}
 
 	}
 }
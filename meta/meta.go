@@ -5,11 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 
 	"github.com/tdelov/imagemeta/exif2/ifds"
 	"github.com/tdelov/imagemeta/imagetype"
 	"github.com/tdelov/imagemeta/meta/utils"
-	"github.com/rs/zerolog"
 )
 
 // Common Errors
@@ -102,9 +102,9 @@ func NewExifHeader(byteOrder utils.ByteOrder, firstIfdOffset, tiffHeaderOffset u
 	}
 }
 
-// MarshalZerologObject is a zerolog interface for logging
-func (h ExifHeader) MarshalZerologObject(e *zerolog.Event) {
-	e.Str("FirstIfd", h.FirstIfd.String()).Uint32("FirstIfdOffset", h.FirstIfdOffset).Uint32("TiffHeaderOffset", h.TiffHeaderOffset).Uint32("ExifLength", h.ExifLength).Str("Endian", h.ByteOrder.String()).Str("ImageType", h.ImageType.String())
+// LogValue is a slog.LogValuer interface for logging
+func (h ExifHeader) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("FirstIfd", h.FirstIfd.String()), slog.Uint64("FirstIfdOffset", uint64(h.FirstIfdOffset)), slog.Uint64("TiffHeaderOffset", uint64(h.TiffHeaderOffset)), slog.Uint64("ExifLength", uint64(h.ExifLength)), slog.String("Endian", h.ByteOrder.String()), slog.String("ImageType", h.ImageType.String()))
 }
 
 // XmpHeader is an XMP header of an image file.
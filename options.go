@@ -0,0 +1,69 @@
+package imagemeta
+
+import (
+	"log/slog"
+
+	"github.com/tdelov/imagemeta/exif2"
+)
+
+// Option is a functional option for DecodeWithOptions.
+type Option func(*options)
+
+// options are the decoding options
+type options struct {
+	logger         *slog.Logger
+	maxBytes       uint32
+	skipMakerNotes bool
+	skipGPS        bool
+}
+
+// MaxMetadataBytes sets the maximum number of bytes of Exif metadata read.
+// The default is 4 MB for TIFF based images, n replaces it and may be larger, and the
+// length of the Exif segment otherwise.
+// Tag values beyond the limit are not read and imagetype.ErrDataLength is returned
+// when an IFD is beyond the limit.
+func MaxMetadataBytes(n uint32) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// SkipMakerNotes skips the parsing of MakerNotes. Camera Raw images that are only
+// identified by their MakerNotes (NEF) are returned as TIFF images.
+func SkipMakerNotes() Option {
+	return func(o *options) {
+		o.skipMakerNotes = true
+	}
+}
+
+// SkipGPS skips the parsing of the GPSInfo IFD.
+func SkipGPS() Option {
+	return func(o *options) {
+		o.skipGPS = true
+	}
+}
+
+// WithLogger sets the slog.Handler of the logger used for a single call.
+// The package loggers set with SetLogger are used by default.
+func WithLogger(h slog.Handler) Option {
+	return func(o *options) {
+		if h != nil {
+			o.logger = slog.New(h)
+		}
+	}
+}
+
+func newOptions(opts []Option) (o options) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// exifLogger returns the logger for the Exif decoder.
+func (o options) exifLogger() *slog.Logger {
+	if o.logger != nil {
+		return o.logger
+	}
+	return exif2.Logger
+}
@@ -0,0 +1,28 @@
+package png
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func FuzzScanPngHeader(f *testing.F) {
+	var buf bytes.Buffer
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.White)
+	if err := png.Encode(&buf, img); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	// An eXIf chunk before the image data
+	var exif bytes.Buffer
+	exif.Write(buf.Bytes()[:33])
+	writeChunk(&exif, "eXIf", []byte("II\x2a\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+	exif.Write(buf.Bytes()[33:])
+	f.Add(exif.Bytes())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ScanPngHeader(bytes.NewReader(data))
+	})
+}
@@ -2,7 +2,11 @@
 package png
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
 
 	"github.com/tdelov/imagemeta/imagetype"
@@ -10,13 +14,26 @@ import (
 	"github.com/tdelov/imagemeta/meta/utils"
 )
 
-func ScanPngHeader(r io.ReadSeeker) (header meta.ExifHeader, err error) {
+// Errors
+var (
+	ErrNotPNG      = errors.New("error not a PNG image")
+	ErrChunkLength = errors.New("error PNG chunk length insufficient")
+)
+
+const (
 	// 5.2 PNG signature
-	const signature = "\x89PNG\r\n\x1a\n"
+	signature = "\x89PNG\r\n\x1a\n"
 
 	// 5.3 Chunk layout
-	const crcSize = 4
+	crcSize         = 4
+	chunkHeaderSize = 8
+
+	// xmpKeyword is the iTXt keyword for XMP metadata
+	xmpKeyword = "XML:com.adobe.xmp"
+)
 
+// ScanPngHeader searches for the eXIf chunk and returns the ExifHeader of the PNG image.
+func ScanPngHeader(r io.ReadSeeker) (header meta.ExifHeader, err error) {
 	// 8 is the size of both the signature and the chunk
 	// id (4 bytes) + chunk length (4 bytes).
 	// This is just a coincidence.
@@ -65,3 +82,169 @@ func ScanPngHeader(r io.ReadSeeker) (header meta.ExifHeader, err error) {
 
 	return header, meta.ErrNoExif
 }
+
+// Scanner scans a PNG Image for its metadata. ExifReader, XMPReader and ICCReader
+// are run at their respective chunks during the scan when they are not nil.
+//
+// XMPReader is run for the iTXt chunk with the keyword "XML:com.adobe.xmp" and
+// ICCReader is run with the decompressed profile of the iCCP chunk.
+type Scanner struct {
+	ExifReader func(r io.Reader, header meta.ExifHeader) error
+	XMPReader  func(r io.Reader) error
+	ICCReader  func(r io.Reader) error
+}
+
+// Scan scans a reader for PNG chunks until the IEND chunk.
+// Returns the dimensions of the image from the IHDR chunk or an error.
+func (s Scanner) Scan(r io.ReadSeeker) (dim meta.Dimensions, err error) {
+	return s.ScanContext(context.Background(), r)
+}
+
+// ScanContext is Scan with a context. The context is checked before each PNG chunk
+// and ctx.Err() is returned when the context is done.
+func (s Scanner) ScanContext(ctx context.Context, r io.ReadSeeker) (dim meta.Dimensions, err error) {
+	buf := make([]byte, chunkHeaderSize)
+	if _, err = io.ReadFull(r, buf[:len(signature)]); err != nil {
+		return dim, err
+	}
+	if string(buf[:len(signature)]) != signature {
+		return dim, ErrNotPNG
+	}
+	offset := int64(len(signature))
+	for {
+		if err = ctx.Err(); err != nil {
+			return dim, err
+		}
+		// 5.3 Chunk layout
+		if _, err = io.ReadFull(r, buf[:8]); err != nil {
+			if err == io.EOF {
+				return dim, nil
+			}
+			return dim, err
+		}
+		length := binary.BigEndian.Uint32(buf[0:4])
+		chunkType := string(buf[4:8])
+		offset += 8
+
+		switch chunkType {
+		case "IHDR":
+			if length < 8 {
+				return dim, ErrChunkLength
+			}
+			if _, err = io.ReadFull(r, buf[:8]); err != nil {
+				return dim, err
+			}
+			dim = meta.NewDimensions(binary.BigEndian.Uint32(buf[0:4]), binary.BigEndian.Uint32(buf[4:8]))
+		case "eXIf":
+			if s.ExifReader != nil {
+				err = s.readExif(r, offset, length)
+			}
+		case "iTXt":
+			if s.XMPReader != nil {
+				err = s.readXMP(r, length)
+			}
+		case "iCCP":
+			if s.ICCReader != nil {
+				err = s.readICC(r, length)
+			}
+		case "IEND":
+			return dim, nil
+		}
+		if err != nil {
+			err = meta.ContainerError(err, chunkType, offset-chunkHeaderSize)
+			return dim, meta.ImageTypeError(err, imagetype.ImagePNG)
+		}
+
+		// Seek to the next chunk after the chunk length + CRC.
+		offset += int64(length) + crcSize
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return dim, err
+		}
+	}
+}
+
+// readExif reads the Tiff header of the eXIf chunk and runs the ExifReader.
+func (s Scanner) readExif(r io.Reader, offset int64, length uint32) error {
+	buf := make([]byte, 8)
+	if length < 8 {
+		return ErrChunkLength
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	byteOrder := utils.BinaryOrder(buf)
+	if byteOrder == utils.UnknownEndian {
+		return meta.ErrNoExif
+	}
+	header := meta.NewExifHeader(byteOrder, byteOrder.Uint32(buf[4:8]), uint32(offset), length, imagetype.ImagePNG)
+
+	// Exif Readers expect to read from the beginning of the Tiff header.
+	return s.ExifReader(io.MultiReader(bytes.NewReader(buf), io.LimitReader(r, int64(length)-8)), header)
+}
+
+// readXMP reads an iTXt chunk and runs the XMPReader when the chunk keyword is
+// "XML:com.adobe.xmp". Compressed text is decompressed.
+func (s Scanner) readXMP(r io.ReadSeeker, length uint32) error {
+	// keyword, null separator, compression flag and compression method
+	const headerSize = len(xmpKeyword) + 3
+	if length < uint32(headerSize) {
+		return nil
+	}
+	buf := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	if string(buf[:len(xmpKeyword)+1]) != xmpKeyword+"\x00" {
+		return nil
+	}
+	compressed := buf[len(xmpKeyword)+1] == 1
+	lr := &io.LimitedReader{R: r, N: int64(length) - int64(headerSize)}
+
+	// Skip the null terminated language tag and translated keyword
+	if err := skipNullTerminated(lr, 2); err != nil {
+		return err
+	}
+	if compressed {
+		zr, err := zlib.NewReader(lr)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return s.XMPReader(zr)
+	}
+	return s.XMPReader(lr)
+}
+
+// readICC reads the iCCP chunk and runs the ICCReader with the decompressed ICC Profile.
+func (s Scanner) readICC(r io.Reader, length uint32) error {
+	lr := &io.LimitedReader{R: r, N: int64(length)}
+
+	// Skip the null terminated profile name
+	if err := skipNullTerminated(lr, 1); err != nil {
+		return err
+	}
+	// Compression method is always 0 (zlib)
+	if _, err := io.ReadFull(lr, make([]byte, 1)); err != nil {
+		return err
+	}
+	zr, err := zlib.NewReader(lr)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return s.ICCReader(zr)
+}
+
+// skipNullTerminated reads from r until n null bytes have been read.
+func skipNullTerminated(r io.Reader, n int) error {
+	var b [1]byte
+	for n > 0 {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return err
+		}
+		if b[0] == 0 {
+			n--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+)
+
+func writeChunk(w *bytes.Buffer, chunkType string, data []byte) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(data)))
+	w.Write(buf[:])
+	w.WriteString(chunkType)
+	w.Write(data)
+	binary.BigEndian.PutUint32(buf[:], crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+	w.Write(buf[:])
+}
+
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func testPNG() []byte {
+	var w bytes.Buffer
+	w.WriteString(signature)
+
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:4], 640)
+	binary.BigEndian.PutUint32(ihdr[4:8], 480)
+	writeChunk(&w, "IHDR", ihdr)
+	writeChunk(&w, "iCCP", append([]byte("ICC Profile\x00\x00"), compress([]byte("profile"))...))
+	writeChunk(&w, "tEXt", []byte("Comment\x00test"))
+	writeChunk(&w, "iTXt", append([]byte(xmpKeyword+"\x00\x01\x00en\x00\x00"), compress([]byte("<x:xmpmeta/>"))...))
+	writeChunk(&w, "eXIf", []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x00"))
+	writeChunk(&w, "IDAT", []byte{})
+	writeChunk(&w, "IEND", []byte{})
+	return w.Bytes()
+}
+
+func TestScanner(t *testing.T) {
+	var exif, xmp, icc []byte
+	var header meta.ExifHeader
+	s := Scanner{
+		ExifReader: func(r io.Reader, h meta.ExifHeader) (err error) {
+			header = h
+			exif, err = io.ReadAll(r)
+			return err
+		},
+		XMPReader: func(r io.Reader) (err error) {
+			xmp, err = io.ReadAll(r)
+			return err
+		},
+		ICCReader: func(r io.Reader) (err error) {
+			icc, err = io.ReadAll(r)
+			return err
+		},
+	}
+	dim, err := s.Scan(bytes.NewReader(testPNG()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dim != meta.NewDimensions(640, 480) {
+		t.Errorf("Incorrect Dimensions wanted %s got %s", meta.NewDimensions(640, 480), dim)
+	}
+	if string(icc) != "profile" {
+		t.Errorf("Incorrect ICC Profile wanted %q got %q", "profile", icc)
+	}
+	if string(xmp) != "<x:xmpmeta/>" {
+		t.Errorf("Incorrect XMP wanted %q got %q", "<x:xmpmeta/>", xmp)
+	}
+	if len(exif) != 10 || header.ImageType != imagetype.ImagePNG || header.FirstIfdOffset != 8 {
+		t.Errorf("Incorrect Exif got %d bytes with header %s", len(exif), header)
+	}
+
+	if _, err = (Scanner{}).Scan(bytes.NewReader([]byte("GIF89a\x00\x00"))); err != ErrNotPNG {
+		t.Errorf("Incorrect error wanted %s got %v", ErrNotPNG, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = s.ScanContext(ctx, bytes.NewReader(testPNG())); err != context.Canceled {
+		t.Errorf("Incorrect error wanted %s got %v", context.Canceled, err)
+	}
+}
+
+func TestRewriter(t *testing.T) {
+	src := testPNG()
+	var rw Rewriter
+	_ = rw.Set(ChunkXMP, []byte("<x:xmpmeta/>"))
+	_ = rw.Set(ChunkICC, []byte("new profile"))
+	_ = rw.Remove(ChunkExif)
+	rw.RemoveText = true
+	if err := rw.Set(Chunk(10), nil); err != ErrChunk {
+		t.Errorf("Incorrect error wanted %s got %v", ErrChunk, err)
+	}
+	var buf bytes.Buffer
+	if err := rw.Rewrite(&buf, bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if bytes.Contains(out, []byte("tEXt")) || bytes.Contains(out, []byte("eXIf")) {
+		t.Error("Incorrect chunks were not removed")
+	}
+	if !bytes.HasSuffix(out, src[bytes.Index(src, []byte("IDAT"))-4:]) {
+		t.Error("Incorrect IDAT and IEND chunks")
+	}
+
+	var exif bool
+	var xmp, icc []byte
+	s := Scanner{
+		ExifReader: func(r io.Reader, h meta.ExifHeader) error {
+			exif = true
+			return nil
+		},
+		XMPReader: func(r io.Reader) (err error) {
+			xmp, err = io.ReadAll(r)
+			return err
+		},
+		ICCReader: func(r io.Reader) (err error) {
+			icc, err = io.ReadAll(r)
+			return err
+		},
+	}
+	if _, err := s.Scan(bytes.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+	if exif || string(xmp) != "<x:xmpmeta/>" || string(icc) != "new profile" {
+		t.Errorf("Incorrect rewritten chunks got Exif %t XMP %q ICC %q", exif, xmp, icc)
+	}
+}
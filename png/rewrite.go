@@ -0,0 +1,181 @@
+package png
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// Errors
+var (
+	ErrChunk = errors.New("error unsupported chunk")
+)
+
+// Chunk is a metadata chunk of a PNG image that can be rewritten.
+type Chunk uint8
+
+// Chunks
+const (
+	// ChunkExif is the eXIf chunk
+	ChunkExif Chunk = iota
+	// ChunkXMP is the iTXt chunk with the keyword "XML:com.adobe.xmp"
+	ChunkXMP
+	// ChunkICC is the iCCP chunk
+	ChunkICC
+
+	chunkCount = 3
+)
+
+const (
+	// iccProfileName is the profile name of iCCP chunks written by a Rewriter
+	iccProfileName = "ICC Profile"
+)
+
+// rewrite is the change of a Chunk.
+type rewrite struct {
+	payload []byte
+	set     bool
+	remove  bool
+}
+
+// Rewriter rewrites the metadata chunks of a PNG image without recompressing it.
+//
+// Chunks that are set are written after the IHDR chunk and replace all chunks of the same kind
+// in the image. Chunks that are removed are dropped from the image. All other chunks are copied unchanged.
+type Rewriter struct {
+	chunks [chunkCount]rewrite
+
+	// RemoveText removes the tEXt, zTXt and iTXt text chunks other than ChunkXMP.
+	RemoveText bool
+}
+
+// Set replaces or inserts the Chunk c with payload:
+//   - ChunkExif is the Tiff header and IFDs.
+//   - ChunkXMP is the XMP packet, it is written uncompressed.
+//   - ChunkICC is the ICC Profile, it is compressed.
+//
+// Returns ErrChunk if c is not a supported Chunk.
+func (rw *Rewriter) Set(c Chunk, payload []byte) error {
+	if c >= chunkCount {
+		return ErrChunk
+	}
+	rw.chunks[c] = rewrite{payload: payload, set: true}
+	return nil
+}
+
+// Remove removes all chunks of kind c from the image.
+func (rw *Rewriter) Remove(c Chunk) error {
+	if c >= chunkCount {
+		return ErrChunk
+	}
+	rw.chunks[c] = rewrite{remove: true}
+	return nil
+}
+
+// Rewrite reads a PNG image from r and writes it with the rewritten chunks to w.
+// Chunks are copied until the IEND chunk.
+func (rw Rewriter) Rewrite(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	buf := make([]byte, chunkHeaderSize)
+	if _, err := io.ReadFull(br, buf); err != nil {
+		return err
+	}
+	if string(buf) != signature {
+		return ErrNotPNG
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	for {
+		// 5.3 Chunk layout
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		length := binary.BigEndian.Uint32(buf[0:4])
+		chunkType := string(buf[4:8])
+
+		if rw.isRemoved(br, chunkType, length) {
+			if _, err := br.Discard(int(length) + crcSize); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(w, br, int64(length)+crcSize); err != nil {
+			return err
+		}
+		switch chunkType {
+		case "IHDR":
+			if err := rw.writeChunks(w); err != nil {
+				return err
+			}
+		case "IEND":
+			return nil
+		}
+	}
+}
+
+// isRemoved returns true when the chunk is replaced or removed by the Rewriter.
+func (rw Rewriter) isRemoved(br *bufio.Reader, chunkType string, length uint32) bool {
+	c := &rw.chunks
+	switch chunkType {
+	case "eXIf":
+		return c[ChunkExif].set || c[ChunkExif].remove
+	case "iCCP":
+		return c[ChunkICC].set || c[ChunkICC].remove
+	case "iTXt":
+		if buf, _ := br.Peek(len(xmpKeyword) + 1); length > uint32(len(xmpKeyword)) && string(buf) == xmpKeyword+"\x00" {
+			return c[ChunkXMP].set || c[ChunkXMP].remove
+		}
+		return rw.RemoveText
+	case "tEXt", "zTXt":
+		return rw.RemoveText
+	}
+	return false
+}
+
+// writeChunks writes the chunks that are set.
+func (rw Rewriter) writeChunks(w io.Writer) (err error) {
+	for c := Chunk(0); c < chunkCount && err == nil; c++ {
+		if !rw.chunks[c].set {
+			continue
+		}
+		payload := rw.chunks[c].payload
+		switch c {
+		case ChunkExif:
+			err = encodeChunk(w, "eXIf", payload)
+		case ChunkXMP:
+			// Uncompressed with an empty language tag and translated keyword
+			data := append([]byte(xmpKeyword+"\x00\x00\x00\x00\x00"), payload...)
+			err = encodeChunk(w, "iTXt", data)
+		case ChunkICC:
+			var b bytes.Buffer
+			b.WriteString(iccProfileName + "\x00\x00")
+			zw := zlib.NewWriter(&b)
+			if _, err = zw.Write(payload); err == nil {
+				err = zw.Close()
+			}
+			if err == nil {
+				err = encodeChunk(w, "iCCP", b.Bytes())
+			}
+		}
+	}
+	return err
+}
+
+// encodeChunk writes a chunk with its length and CRC to w.
+func encodeChunk(w io.Writer, chunkType string, data []byte) error {
+	buf := make([]byte, 0, chunkHeaderSize+len(data)+crcSize)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(data)))
+	buf = append(buf, chunkType...)
+	buf = append(buf, data...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf[4:]))
+	_, err := w.Write(buf)
+	return err
+}
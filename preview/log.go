@@ -1,17 +1,20 @@
 package preview
 
 import (
-	"os"
+	"log/slog"
 
-	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
+	"github.com/tdelov/imagemeta/internal/logging"
 )
 
 var (
 	// Logger is the logger
-	Logger zerolog.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout}).Level(zerolog.PanicLevel).With().Str("package", "preview").Logger()
+	Logger *slog.Logger = logging.Discard
 )
 
-func (pr *previewReader) logError(err error) *zerolog.Event {
-	return pr.logger.Err(err)
+func (pr *previewReader) logError(err error) *logging.Event {
+	return logging.NewEvent(pr.logger, slog.LevelError).Str("package", "preview").Err(err)
+}
+
+func (pw *previewWriter) logError(err error) *logging.Event {
+	return logging.NewEvent(pw.logger, slog.LevelError).Str("package", "preview").Err(err)
 }
@@ -0,0 +1,67 @@
+package preview
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/tdelov/imagemeta/meta"
+)
+
+// copyBufferSize is the size of the pooled buffers of Copy
+const copyBufferSize = 32 * 1024
+
+// bufferPool for copy buffers
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new([copyBufferSize]byte) },
+}
+
+// writerOnly hides the io.ReaderFrom of an io.Writer so that io.CopyBuffer
+// uses the pooled buffer instead of the ReadFrom buffer of w.
+type writerOnly struct {
+	io.Writer
+}
+
+// Copy copies n bytes of a preview image from r to w with a pooled buffer, the
+// io.ReaderFrom of w (*os.File, *bytes.Buffer, *bufio.Writer) is not used.
+// Returns the number of bytes copied and io.ErrUnexpectedEOF if r has fewer than n bytes.
+func Copy(w io.Writer, r io.Reader, n int64) (written int64, err error) {
+	buf := bufferPool.Get().(*[copyBufferSize]byte)
+	defer bufferPool.Put(buf)
+	written, err = io.CopyBuffer(writerOnly{w}, io.LimitReader(r, n), buf[:])
+	if err == nil && written < n {
+		err = io.ErrUnexpectedEOF
+	}
+	return written, err
+}
+
+type previewWriter struct {
+	logger *slog.Logger
+	w      io.Writer
+
+	// Header is the header of the preview image that was written.
+	Header meta.PreviewHeader
+	// Written is the number of bytes of the preview image that were written.
+	Written int64
+}
+
+// NewPreviewWriter returns a previewWriter that copies the preview image to w
+// without allocating the whole image.
+func NewPreviewWriter(w io.Writer, l *slog.Logger) previewWriter {
+	return previewWriter{
+		logger: l,
+		w:      w,
+	}
+}
+
+// WritePreview copies the preview image with header h from r to the io.Writer of the previewWriter.
+func (pw *previewWriter) WritePreview(r io.Reader, h meta.PreviewHeader) (err error) {
+	pw.Header = h
+	if pw.Written, err = Copy(pw.w, r, int64(h.Size)); err != nil {
+		pw.logError(err).
+			Int64("written", pw.Written).
+			Uint32("size", h.Size).
+			Msgf("error write preview image")
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package preview
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readerFromWriter is an io.Writer with an io.ReaderFrom that must not be used by Copy.
+type readerFromWriter struct {
+	bytes.Buffer
+}
+
+func (w *readerFromWriter) ReadFrom(r io.Reader) (int64, error) {
+	panic("Copy used ReadFrom")
+}
+
+func TestCopy(t *testing.T) {
+	w := &readerFromWriter{}
+	n, err := Copy(w, strings.NewReader("preview image"), 7)
+	if err != nil || n != 7 || w.String() != "preview" {
+		t.Errorf("Incorrect Copy wanted %q got %q %d %v", "preview", w.String(), n, err)
+	}
+
+	if _, err = Copy(io.Discard, strings.NewReader("preview"), 10); err != io.ErrUnexpectedEOF {
+		t.Errorf("Incorrect error wanted %v got %v", io.ErrUnexpectedEOF, err)
+	}
+}
@@ -0,0 +1,305 @@
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/isobmff"
+	"github.com/tdelov/imagemeta/jpeg"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/preview"
+	"github.com/tdelov/imagemeta/tiff"
+	"github.com/pkg/errors"
+)
+
+// panasonicJpgFromRaw is the JpgFromRaw tag of IFD0 of a Panasonic RW2 image.
+const panasonicJpgFromRaw tag.ID = 0x002e
+
+// PreviewImage is the location of an embedded preview image.
+type PreviewImage struct {
+	// Source is the IFD or box of the preview image. One of "IFD0", "IFD1",
+	// "SubIFD0" to "SubIFD5", "JpgFromRaw", "PRVW" or "thmb".
+	Source string
+	// ImageType is ImageJPEG for JPEG preview images. The thumbnail items of HEIF and
+	// AVIF images are coded items of the image type of the image.
+	ImageType  imagetype.ImageType
+	Dimensions meta.Dimensions
+	// Offset and Length of the preview image from the beginning of the file.
+	Offset, Length int64
+}
+
+// pixels returns the number of pixels of the preview image.
+func (p PreviewImage) pixels() uint64 {
+	return uint64(p.Dimensions.Width) * uint64(p.Dimensions.Height)
+}
+
+// Previews returns the embedded preview images of an image. These are the JPEG images of
+// IFD0, IFD1 and the SubIFDs of JPEG, TIFF, CR2, NEF, ARW and DNG images, the JpgFromRaw of RW2
+// images, the PRVW preview image of CR3 images and the "thmb" items of HEIF and AVIF images.
+//
+// The dimensions of JPEG preview images are read from their SOF marker, JPEG images that are
+// lossless (the raw image data) or are not within r are not returned.
+func Previews(r io.ReadSeeker) (previews []PreviewImage, err error) {
+	rr := readerPool.Get().(*bufio.Reader)
+	rr.Reset(r)
+	defer readerPool.Put(rr)
+
+	it, err := imagetype.ScanBuf(rr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = meta.ImageTypeError(err, it) }()
+
+	ra := newReaderAt(r)
+
+	ir := exif2.NewIfdReader(exif2.Logger)
+	defer ir.Close()
+	var pc previewCollector
+	ir.SetCustomTagParser(pc.parseTag)
+
+	switch decodeType(it) {
+	case imagetype.ImageJPEG:
+		// IFD1 is read with random access from the Exif segment as it may be before IFD0
+		exifReader := func(er io.Reader, h meta.ExifHeader) error {
+			buf, err := io.ReadAll(io.LimitReader(er, int64(h.ExifLength)))
+			if err != nil {
+				return err
+			}
+			pc.tiffHeaderOffset = int64(h.TiffHeaderOffset)
+			h.TiffHeaderOffset = 0
+			return ir.DecodeReaderAt(bytes.NewReader(buf), h)
+		}
+		s := jpeg.Scanner{ExifReader: exifReader}
+		if _, err = s.Scan(rr); err != nil {
+			return nil, err
+		}
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR:
+		header, err := tiff.ScanTiffHeader(rr, it)
+		if err != nil {
+			return nil, err
+		}
+		pc.tiffHeaderOffset = int64(header.TiffHeaderOffset)
+		if err = ir.DecodeReaderAt(ra, header); err != nil {
+			return nil, err
+		}
+	case imagetype.ImageCR3, imagetype.ImageHEIF, imagetype.ImageHEIC, imagetype.ImageHEIFSequence, imagetype.ImageAVIF, imagetype.ImageAVIFSequence:
+		bmr := isobmff.NewReader(rr)
+		defer bmr.Close()
+		if err = bmr.ReadFTYP(); err != nil {
+			return nil, errors.Wrapf(err, "ReadFtypBox")
+		}
+		// The meta box of HEIF and AVIF images or the moov, uuid xpacket and uuid preview boxes of CR3 images
+		boxes := 1
+		if decodeType(it) == imagetype.ImageCR3 {
+			boxes = 3
+		}
+		for i := 0; i < boxes; i++ {
+			if err = bmr.ReadMetadata(); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, err
+			}
+		}
+		size, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range bmr.Previews() {
+			if p.Offset == 0 || p.Offset+p.Length > uint64(size) {
+				continue
+			}
+			preview := PreviewImage{Source: p.BoxType, ImageType: it, Dimensions: p.Dimensions, Offset: int64(p.Offset), Length: int64(p.Length)}
+			if p.BoxType == "PRVW" {
+				preview.ImageType = imagetype.ImageJPEG
+			}
+			previews = append(previews, preview)
+		}
+		return previews, nil
+	default:
+		return nil, ErrMetadataNotSupported
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pc.previews() {
+		if p.Offset <= 0 || p.Offset+p.Length > size || containsPreview(previews, p.Offset) {
+			continue
+		}
+		dim, lossless, err := jpeg.ScanFrame(io.NewSectionReader(ra, p.Offset, p.Length))
+		if err != nil || lossless {
+			continue
+		}
+		p.Dimensions = dim
+		previews = append(previews, p)
+	}
+	return previews, nil
+}
+
+// LargestPreview returns the preview image with the most pixels, or the longest of
+// preview images with the same number of pixels.
+//
+// Returns ErrNoPreview when there are no preview images.
+func LargestPreview(previews []PreviewImage) (PreviewImage, error) {
+	if len(previews) == 0 {
+		return PreviewImage{}, ErrNoPreview
+	}
+	largest := previews[0]
+	for _, p := range previews[1:] {
+		if p.pixels() > largest.pixels() || p.pixels() == largest.pixels() && p.Length > largest.Length {
+			largest = p
+		}
+	}
+	return largest, nil
+}
+
+// SmallestPreview returns the preview image with the fewest pixels, or the shortest of
+// preview images with the same number of pixels.
+//
+// Returns ErrNoPreview when there are no preview images.
+func SmallestPreview(previews []PreviewImage) (PreviewImage, error) {
+	if len(previews) == 0 {
+		return PreviewImage{}, ErrNoPreview
+	}
+	smallest := previews[0]
+	for _, p := range previews[1:] {
+		if p.pixels() < smallest.pixels() || p.pixels() == smallest.pixels() && p.Length < smallest.Length {
+			smallest = p
+		}
+	}
+	return smallest, nil
+}
+
+// PreviewSectionReader returns an io.SectionReader of the preview image p in r.
+func PreviewSectionReader(r io.ReaderAt, p PreviewImage) *io.SectionReader {
+	return io.NewSectionReader(r, p.Offset, p.Length)
+}
+
+// WritePreview copies the preview image p from r to w with a pooled buffer, without
+// allocating the whole image. Returns the number of bytes written or an error.
+func WritePreview(w io.Writer, r io.ReadSeeker, p PreviewImage) (int64, error) {
+	if _, err := r.Seek(p.Offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return preview.Copy(w, r, p.Length)
+}
+
+func containsPreview(previews []PreviewImage, offset int64) bool {
+	for _, p := range previews {
+		if p.Offset == offset {
+			return true
+		}
+	}
+	return false
+}
+
+// previewIfd are the tags of an IFD that locate a JPEG preview image.
+type previewIfd struct {
+	source                   string
+	jpegOffset, jpegLength   uint32
+	stripOffset, stripLength uint32
+	compression              uint16
+}
+
+// previewCollector is a custom tag parser that collects the
+// locations of the JPEG preview images of the IFDs.
+type previewCollector struct {
+	ifds             []previewIfd
+	tiffHeaderOffset int64
+}
+
+// ifd returns the previewIfd of source.
+func (pc *previewCollector) ifd(source string) *previewIfd {
+	for i := range pc.ifds {
+		if pc.ifds[i].source == source {
+			return &pc.ifds[i]
+		}
+	}
+	pc.ifds = append(pc.ifds, previewIfd{source: source})
+	return &pc.ifds[len(pc.ifds)-1]
+}
+
+func (pc *previewCollector) parseTag(p exif2.TagParser, t exif2.Tag) error {
+	var source string
+	switch t.Ifd {
+	case ifds.IFD0:
+		source = fmt.Sprintf("IFD%d", t.IfdIndex)
+	case ifds.SubIfd0, ifds.SubIfd1, ifds.SubIfd2, ifds.SubIfd3, ifds.SubIfd4, ifds.SubIfd5:
+		source = fmt.Sprintf("SubIFD%d", t.Ifd-ifds.SubIfd0)
+	default:
+		return nil
+	}
+	switch t.ID {
+	case ifds.JPEGInterchangeFormat:
+		pc.ifd(source).jpegOffset = p.ParseUint32(t)
+	case ifds.JPEGInterchangeFormatLength:
+		pc.ifd(source).jpegLength = p.ParseUint32(t)
+	case ifds.StripOffsets:
+		// Only previews in a single strip
+		if t.UnitCount == 1 {
+			pc.ifd(source).stripOffset = p.ParseUint32(t)
+		}
+	case ifds.StripByteCounts:
+		if t.UnitCount == 1 {
+			pc.ifd(source).stripLength = p.ParseUint32(t)
+		}
+	case ifds.Compression:
+		pc.ifd(source).compression = p.ParseUint16(t)
+	case panasonicJpgFromRaw:
+		if t.Ifd == ifds.IFD0 && t.IfdIndex == 0 && !t.IsEmbedded() {
+			ifd := pc.ifd("JpgFromRaw")
+			ifd.jpegOffset, ifd.jpegLength = t.ValueOffset, t.Size()
+		}
+	}
+	return nil
+}
+
+// previews returns the preview images of the collected IFDs.
+func (pc *previewCollector) previews() (previews []PreviewImage) {
+	for _, ifd := range pc.ifds {
+		if ifd.jpegOffset != 0 && ifd.jpegLength != 0 {
+			previews = append(previews, pc.preview(ifd.source, ifd.jpegOffset, ifd.jpegLength))
+		}
+		// Strips with old-style (6) or new-style (7) JPEG compression
+		if (ifd.compression == 6 || ifd.compression == 7) && ifd.stripOffset != 0 && ifd.stripLength != 0 {
+			previews = append(previews, pc.preview(ifd.source, ifd.stripOffset, ifd.stripLength))
+		}
+	}
+	return previews
+}
+
+func (pc *previewCollector) preview(source string, offset, length uint32) PreviewImage {
+	return PreviewImage{Source: source, ImageType: imagetype.ImageJPEG, Offset: pc.tiffHeaderOffset + int64(offset), Length: int64(length)}
+}
+
+// readerAt is an io.ReaderAt of an io.ReadSeeker. It is not safe for concurrent use.
+type readerAt struct {
+	r io.ReadSeeker
+}
+
+// newReaderAt returns r as an io.ReaderAt.
+func newReaderAt(r io.ReadSeeker) io.ReaderAt {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return ra
+	}
+	return readerAt{r}
+}
+
+func (ra readerAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if _, err = ra.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err = io.ReadFull(ra.r, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
@@ -0,0 +1,340 @@
+package imagemeta
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Errors of the RangeReader
+var (
+	ErrRangeNotSupported = errors.New("error http range requests not supported by the server")
+	ErrRangeModified     = errors.New("error remote file modified between range requests")
+	ErrContentRange      = errors.New("error invalid Content-Range of range response")
+	ErrRangeSize         = errors.New("error size of remote file unknown")
+	ErrRangeOffset       = errors.New("error negative offset")
+)
+
+// Defaults of a RangeReader
+const (
+	defaultRangeBlockSize = 64 * 1024
+	defaultRangeReadAhead = 1
+	defaultRangeMaxBlocks = 256
+)
+
+// RangeStats are the number of HTTP requests and the number of bytes
+// fetched by a RangeReader.
+type RangeStats struct {
+	Requests int
+	Bytes    int64
+}
+
+// RangeReader is an io.ReaderAt and io.ReadSeeker of a remote file that is read with
+// HTTP Range requests. The file is fetched in blocks of BlockSize that are cached, a
+// read of blocks that are not cached fetches them and ReadAhead blocks after them
+// with a single request.
+//
+// Decode, DecodeWithOptions and DecodeContext read the IFDs and tag values of a RangeReader
+// at their offsets (see DecodeReaderAt), only the blocks that hold metadata are fetched.
+//
+// ReadAt is safe for concurrent use, Read and Seek are not.
+type RangeReader struct {
+	// Client is the http.Client of the requests, http.DefaultClient when nil.
+	Client *http.Client
+	// Header is added to each request, for example an Authorization header.
+	Header http.Header
+	// BlockSize is the size of the cached blocks. The default is 64 KB.
+	BlockSize int64
+	// ReadAhead is the number of blocks that are fetched after the blocks of a
+	// read. The default is 1.
+	ReadAhead int
+	// MaxBlocks is the maximum number of cached blocks, the oldest blocks are
+	// evicted first. The default is 256.
+	MaxBlocks int
+
+	url string
+	ctx context.Context
+	pos int64
+
+	mu     sync.Mutex
+	size   int64 // -1 until known
+	etag   string
+	blocks map[int64][]byte
+	order  []int64 // cached blocks, oldest first
+	stats  RangeStats
+}
+
+// NewRangeReader returns a RangeReader of the file at url. The fields of the
+// RangeReader can be changed before the first read. No request is made until
+// the first read.
+func NewRangeReader(url string) *RangeReader {
+	return &RangeReader{
+		BlockSize: defaultRangeBlockSize,
+		ReadAhead: defaultRangeReadAhead,
+		MaxBlocks: defaultRangeMaxBlocks,
+		url:       url,
+		ctx:       context.Background(),
+		size:      -1,
+		blocks:    make(map[int64][]byte),
+	}
+}
+
+// SetContext sets the context of the requests of Read, ReadAt, Seek and Size. A
+// request that is canceled returns ctx.Err(). DecodeContext makes the requests of
+// the decode with its own context.
+func (rr *RangeReader) SetContext(ctx context.Context) {
+	rr.ctx = ctx
+}
+
+// withContext returns an io.ReaderAt of the RangeReader that makes its requests with
+// ctx. The blocks are shared with the RangeReader.
+func (rr *RangeReader) withContext(ctx context.Context) io.ReaderAt {
+	return rangeReaderContext{rr: rr, ctx: ctx}
+}
+
+// rangeReaderContext is an io.ReaderAt of a RangeReader with the context of a call.
+type rangeReaderContext struct {
+	rr  *RangeReader
+	ctx context.Context
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (rc rangeReaderContext) ReadAt(p []byte, off int64) (int, error) {
+	return rc.rr.readAt(rc.ctx, p, off)
+}
+
+// Stats returns the number of requests and bytes fetched.
+func (rr *RangeReader) Stats() RangeStats {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.stats
+}
+
+// Size returns the size of the remote file. The first block is fetched
+// when the size is not known.
+func (rr *RangeReader) Size() (int64, error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.size < 0 {
+		if _, ok := rr.blocks[0]; !ok {
+			if _, err := rr.fetch(rr.ctx, 0, 0); err != nil && err != io.EOF {
+				return 0, err
+			}
+		}
+	}
+	if rr.size < 0 {
+		return 0, ErrRangeSize
+	}
+	return rr.size, nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (rr *RangeReader) ReadAt(p []byte, off int64) (n int, err error) {
+	return rr.readAt(rr.ctx, p, off)
+}
+
+// readAt reads len(p) bytes at off, the blocks that are not cached are fetched with ctx.
+func (rr *RangeReader) readAt(ctx context.Context, p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, ErrRangeOffset
+	}
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	if rr.BlockSize <= 0 {
+		rr.BlockSize = defaultRangeBlockSize
+	}
+	for n < len(p) {
+		pos := off + int64(n)
+		if rr.size >= 0 && pos >= rr.size {
+			return n, io.EOF
+		}
+		i := pos / rr.BlockSize
+		block, ok := rr.blocks[i]
+		if !ok {
+			last := (off + int64(len(p)) - 1) / rr.BlockSize
+			if block, err = rr.fetch(ctx, i, last); err != nil {
+				return n, err
+			}
+		}
+		start := pos - i*rr.BlockSize
+		if start >= int64(len(block)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], block[start:])
+	}
+	return n, nil
+}
+
+// Read implements the io.Reader interface.
+func (rr *RangeReader) Read(p []byte) (n int, err error) {
+	n, err = rr.ReadAt(p, rr.pos)
+	rr.pos += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements the io.Seeker interface. Seeking relative to the end
+// fetches the first block when the size is not known.
+func (rr *RangeReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += rr.pos
+	case io.SeekEnd:
+		size, err := rr.Size()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	default:
+		return 0, errors.Errorf("error invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, ErrRangeOffset
+	}
+	rr.pos = offset
+	return offset, nil
+}
+
+// fetch fetches the blocks from first to last and ReadAhead blocks after them that
+// are not cached with a single request and returns the first block. Is called with
+// rr.mu held.
+func (rr *RangeReader) fetch(ctx context.Context, first, last int64) ([]byte, error) {
+	last += int64(rr.ReadAhead)
+	for i := first + 1; i <= last; i++ {
+		if _, ok := rr.blocks[i]; ok {
+			last = i - 1
+			break
+		}
+	}
+	if rr.size >= 0 && last > (rr.size-1)/rr.BlockSize {
+		last = (rr.size - 1) / rr.BlockSize
+	}
+	start, end := first*rr.BlockSize, (last+1)*rr.BlockSize-1
+
+	buf, err := rr.get(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	var block []byte
+	for i := first; len(buf) > 0; i++ {
+		b := buf[:min(int64(len(buf)), rr.BlockSize)]
+		buf = buf[len(b):]
+		if i == first {
+			block = b
+		}
+		rr.cache(i, b)
+	}
+	if block == nil {
+		return nil, io.EOF
+	}
+	return block, nil
+}
+
+// cache caches block i and evicts the oldest blocks beyond MaxBlocks.
+func (rr *RangeReader) cache(i int64, block []byte) {
+	for rr.MaxBlocks > 0 && len(rr.order) >= rr.MaxBlocks {
+		delete(rr.blocks, rr.order[0])
+		rr.order = rr.order[1:]
+	}
+	rr.blocks[i] = block
+	rr.order = append(rr.order, i)
+}
+
+// get requests the bytes from start to end of the remote file. The response is
+// shorter at the end of the file.
+func (rr *RangeReader) get(ctx context.Context, start, end int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rr.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range rr.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	client := rr.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	rr.stats.Requests++
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The start is beyond the end of the file
+		if _, total, err := parseContentRange(resp.Header.Get("Content-Range")); err == nil && total >= 0 {
+			rr.size = total
+		}
+		return nil, io.EOF
+	case http.StatusOK:
+		return nil, ErrRangeNotSupported
+	default:
+		return nil, errors.Errorf("error http range request: %s", resp.Status)
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		if rr.etag != "" && rr.etag != etag {
+			return nil, ErrRangeModified
+		}
+		rr.etag = etag
+	}
+	first, total, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return nil, err
+	}
+	if first != start {
+		return nil, errors.Wrapf(ErrContentRange, "wanted start %d got %d", start, first)
+	}
+	if total >= 0 {
+		rr.size = total
+	}
+	buf, err := io.ReadAll(io.LimitReader(resp.Body, end-start+1))
+	rr.stats.Bytes += int64(len(buf))
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// parseContentRange returns the start and the total size of a Content-Range header
+// "bytes start-end/total" or "bytes */total". The total is -1 when it is unknown.
+func parseContentRange(s string) (start, total int64, err error) {
+	s, ok := strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return 0, 0, ErrContentRange
+	}
+	r, t, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, ErrContentRange
+	}
+	total = -1
+	if t != "*" {
+		if total, err = strconv.ParseInt(t, 10, 64); err != nil {
+			return 0, 0, ErrContentRange
+		}
+	}
+	if r == "*" {
+		return 0, total, nil
+	}
+	r, _, ok = strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, ErrContentRange
+	}
+	if start, err = strconv.ParseInt(r, 10, 64); err != nil {
+		return 0, 0, ErrContentRange
+	}
+	return start, total, nil
+}
@@ -0,0 +1,175 @@
+package imagemeta
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRangeServer returns a httptest.Server that serves buf with range requests.
+func newRangeServer(t *testing.T, buf []byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRangeReader(t *testing.T) {
+	buf, err := os.ReadFile("testImages/NEF.exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newRangeServer(t, buf)
+
+	rr := NewRangeReader(srv.URL)
+	rr.BlockSize = 1024
+	size, err := rr.Seek(0, io.SeekEnd)
+	if err != nil || size != int64(len(buf)) {
+		t.Fatalf("Incorrect size wanted %d got %d: %v", len(buf), size, err)
+	}
+
+	// Reads across blocks, of cached blocks and at the end of the file
+	reads := []struct {
+		off int64
+		n   int
+	}{{0, 10}, {1000, 3000}, {500, 600}, {int64(len(buf)) - 100, 100}, {int64(len(buf)) - 50, 100}, {int64(len(buf)), 10}}
+	for _, r := range reads {
+		p := make([]byte, r.n)
+		n, err := rr.ReadAt(p, r.off)
+		wanted := buf[min(r.off, int64(len(buf))):min(r.off+int64(r.n), int64(len(buf)))]
+		if !bytes.Equal(p[:n], wanted) {
+			t.Errorf("Incorrect ReadAt(%d, %d) wanted %d bytes got %d", r.n, r.off, len(wanted), n)
+		}
+		if n < r.n && err != io.EOF {
+			t.Errorf("Incorrect ReadAt(%d, %d) error wanted %v got %v", r.n, r.off, io.EOF, err)
+		}
+	}
+	// Blocks 0-1 of Seek, blocks 2-4 and the last block
+	wanted := RangeStats{Requests: 3, Bytes: 5*1024 + int64(len(buf)%1024)}
+	if stats := rr.Stats(); stats != wanted {
+		t.Errorf("Incorrect stats wanted %+v got %+v", wanted, stats)
+	}
+
+	// Read and Seek
+	if _, err = rr.Seek(2000, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	p, err := io.ReadAll(io.LimitReader(rr, 5000))
+	if err != nil || !bytes.Equal(p, buf[2000:7000]) {
+		t.Errorf("Incorrect Read wanted %d bytes got %d: %v", 5000, len(p), err)
+	}
+}
+
+func TestRangeReaderDecode(t *testing.T) {
+	for _, name := range []string{"NEF.exif", "JPEG.jpg", "AVIF2.avif"} {
+		t.Run(name, func(t *testing.T) {
+			buf, err := os.ReadFile("testImages/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wanted, err := DecodeReaderAt(bytes.NewReader(buf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rr := NewRangeReader(newRangeServer(t, buf).URL)
+			got, err := Decode(rr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, wanted) {
+				t.Errorf("Incorrect Exif of RangeReader")
+			}
+			stats := rr.Stats()
+			if stats.Bytes >= int64(len(buf)) && len(buf) > 2*defaultRangeBlockSize {
+				t.Errorf("Incorrect stats wanted less than %d bytes got %+v", len(buf), stats)
+			}
+			t.Logf("%s: %d bytes %d requests of %d bytes", name, stats.Bytes, stats.Requests, len(buf))
+		})
+	}
+}
+
+func TestRangeReaderErrors(t *testing.T) {
+	// Server without range requests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("imagemeta"))
+	}))
+	defer srv.Close()
+	if _, err := NewRangeReader(srv.URL).ReadAt(make([]byte, 4), 0); err != ErrRangeNotSupported {
+		t.Errorf("Incorrect error wanted %v got %v", ErrRangeNotSupported, err)
+	}
+
+	// Modified file
+	version := `"v1"`
+	buf := make([]byte, 4096)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", version)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf))
+	}))
+	defer srv.Close()
+	rr := NewRangeReader(srv.URL)
+	rr.BlockSize, rr.ReadAhead = 1024, 0
+	if _, err := rr.ReadAt(make([]byte, 4), 0); err != nil {
+		t.Fatal(err)
+	}
+	version = `"v2"`
+	if _, err := rr.ReadAt(make([]byte, 4), 2048); err != ErrRangeModified {
+		t.Errorf("Incorrect error wanted %v got %v", ErrRangeModified, err)
+	}
+
+	if _, err := rr.ReadAt(make([]byte, 4), -1); err != ErrRangeOffset {
+		t.Errorf("Incorrect error wanted %v got %v", ErrRangeOffset, err)
+	}
+}
+
+func TestRangeReaderDecodeContext(t *testing.T) {
+	buf, err := os.ReadFile("testImages/NEF.exif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The first request is served, the following requests stall until they are canceled
+	var requests atomic.Int32
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			select {
+			case <-r.Context().Done():
+			case <-stop:
+			}
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(buf))
+	}))
+	defer srv.Close()
+	defer close(stop)
+
+	rr := NewRangeReader(srv.URL)
+	rr.BlockSize, rr.ReadAhead = 1024, 0
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := DecodeContext(ctx, rr)
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Incorrect error wanted %v got %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Incorrect DecodeContext, the range request was not canceled")
+	}
+	if n := requests.Load(); n < 2 {
+		t.Errorf("Incorrect requests wanted a stalled request got %d requests", n)
+	}
+}
@@ -0,0 +1,45 @@
+package imagemeta
+
+import (
+	"sync"
+
+	"github.com/tdelov/imagemeta/imagetype"
+
+	"github.com/pkg/errors"
+)
+
+// ErrDecoderNotRegistered is returned by RegisterDecoder for an ImageType that
+// was not added with imagetype.Register.
+var ErrDecoderNotRegistered = errors.New("error imagetype is not registered")
+
+// decoders maps a registered ImageType to the built-in ImageType that its
+// metadata is decoded as.
+var decoders sync.Map
+
+// RegisterDecoder decodes the metadata of the ImageType it, that was added with
+// imagetype.Register, like the metadata of the built-in ImageType as. For example a
+// scanner format with Tiff IFDs is decoded as imagetype.ImageTiff. The ImageType of
+// the decoded Exif is it.
+//
+// Decode, DecodeContext, DecodeReaderAt, DecodeBytes, DecodeMetadata, Previews and
+// Thumbnail use the registered decoders. RegisterDecoder is usually called from an
+// init function after imagetype.Register.
+func RegisterDecoder(it, as imagetype.ImageType) error {
+	if !it.IsRegistered() || as.IsRegistered() || as.IsUnknown() {
+		return errors.Wrapf(ErrDecoderNotRegistered, "RegisterDecoder %s as %s", it, as)
+	}
+	decoders.Store(it, as)
+	return nil
+}
+
+// decodeType returns the built-in ImageType that the metadata of the ImageType is
+// decoded as.
+func decodeType(it imagetype.ImageType) imagetype.ImageType {
+	if !it.IsRegistered() {
+		return it
+	}
+	if as, ok := decoders.Load(it); ok {
+		return as.(imagetype.ImageType)
+	}
+	return it
+}
@@ -0,0 +1,282 @@
+package imagemeta
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/ifds/exififd"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/jpeg"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/png"
+	"github.com/tdelov/imagemeta/webp"
+	"github.com/tdelov/imagemeta/xmp"
+)
+
+// StripTag is an Exif tag of a StripPolicy.
+type StripTag struct {
+	Ifd ifds.IfdType
+	ID  tag.ID
+}
+
+// StripPolicy is the metadata that is kept by Strip.
+type StripPolicy struct {
+	// Exif keeps the Exif tags that are allowed by Tags.
+	Exif bool
+	// Tags is the allowlist of Exif tags of IFD0, ExifIFD, GPSIFD, the Interoperability IFD
+	// and IFD1 (ifds.NullIFD) that are kept. When nil all tags are kept except the GPSIFD
+	// and the PrivateTags.
+	Tags []StripTag
+	// Thumbnail keeps the Exif thumbnail image and the tags of IFD1 that are allowed by Tags.
+	Thumbnail bool
+	// XMP keeps the XMP metadata without the location and device identifying properties.
+	XMP bool
+	// ICC keeps the ICC color profile.
+	ICC bool
+}
+
+// Strip Policies
+var (
+	// StripAll removes all metadata.
+	StripAll = StripPolicy{}
+
+	// StripPrivate removes the location and device identifying metadata and keeps
+	// the remaining metadata.
+	StripPrivate = StripPolicy{Exif: true, Thumbnail: true, XMP: true, ICC: true}
+
+	// StripKeepOrientation keeps the Orientation, the ColorSpace and the ICC color profile.
+	StripKeepOrientation = StripPolicy{Exif: true, Tags: []StripTag{{ifds.IFD0, ifds.Orientation}, {ifds.ExifIFD, exififd.ColorSpace}}, ICC: true}
+
+	// PrivateTags are the device identifying Exif tags that are removed when
+	// StripPolicy.Tags is nil. The tags of IFD0 are also removed from IFD1.
+	PrivateTags = []StripTag{
+		{ifds.IFD0, ifds.CameraSerialNumber},
+		{ifds.IFD0, ifds.ApplicationNotes},
+		{ifds.IFD0, ifds.IPTCNAA},
+		{ifds.ExifIFD, exififd.MakerNote},
+		{ifds.ExifIFD, exififd.ImageUniqueID},
+		{ifds.ExifIFD, exififd.CameraOwnerName},
+		{ifds.ExifIFD, exififd.BodySerialNumber},
+		{ifds.ExifIFD, exififd.LensSerialNumber},
+	}
+
+	// privateXMP are the location and device identifying XMP properties by namespace.
+	// Properties ending with "*" are matched by prefix.
+	privateXMP = map[string][]string{
+		"http://ns.adobe.com/exif/1.0/":               {"GPS*", "ImageUniqueID"},
+		"http://cipa.jp/exif/1.0/":                    {"BodySerialNumber", "LensSerialNumber", "CameraOwnerName", "ImageUniqueID"},
+		"http://ns.adobe.com/exif/1.0/aux/":           {"SerialNumber", "LensSerialNumber", "OwnerName"},
+		"http://ns.adobe.com/photoshop/1.0/":          {"City", "State", "Country"},
+		"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/": {"Location", "CountryCode"},
+		"http://iptc.org/std/Iptc4xmpExt/2008-02-29/": {"LocationCreated", "LocationShown"},
+	}
+)
+
+// keepTag returns true when the Exif tag is kept by the policy.
+func (p StripPolicy) keepTag(ifdType ifds.IfdType, id tag.ID) bool {
+	if p.Tags == nil {
+		if ifdType == ifds.NullIFD {
+			// IFD1 has the tags of IFD0
+			ifdType = ifds.IFD0
+		}
+		return ifdType != ifds.GPSIFD && !containsStripTag(PrivateTags, ifdType, id)
+	}
+	return containsStripTag(p.Tags, ifdType, id)
+}
+
+func containsStripTag(tags []StripTag, ifdType ifds.IfdType, id tag.ID) bool {
+	for _, t := range tags {
+		if t.Ifd == ifdType && t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrivateXMP returns true when the XMP property is location or device identifying.
+func isPrivateXMP(namespace, name string) bool {
+	for _, p := range privateXMP[namespace] {
+		if p == name || strings.HasSuffix(p, "*") && strings.HasPrefix(name, p[:len(p)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripExif returns the Exif Tiff block with the tags that are kept by the policy.
+// Returns nil when no tags are kept or the Exif is not valid.
+func (p StripPolicy) stripExif(buf []byte) ([]byte, error) {
+	if !p.Exif || buf == nil {
+		return nil, nil
+	}
+	enc, err := exif2.NewEncoderTiff(buf)
+	if err != nil {
+		// Exif that can not be filtered is removed
+		return nil, nil
+	}
+	enc.Filter(p.keepTag)
+	if !p.Thumbnail {
+		enc.RemoveThumbnail()
+	}
+	if enc.Len() == 0 {
+		return nil, nil
+	}
+	return enc.EncodeTiff()
+}
+
+// stripXMP returns the XMP packet without the private properties.
+// Returns nil when XMP is not kept or is not valid.
+func (p StripPolicy) stripXMP(buf []byte) []byte {
+	if !p.XMP || buf == nil {
+		return nil
+	}
+	buf, err := xmp.RemoveProperties(buf, isPrivateXMP)
+	if err != nil {
+		// XMP that can not be filtered is removed
+		return nil
+	}
+	return buf
+}
+
+// strippedMetadata is the metadata of an image that is read before it is stripped.
+type strippedMetadata struct {
+	exif, xmp []byte
+}
+
+func (m *strippedMetadata) readExif(r io.Reader, h meta.ExifHeader) (err error) {
+	m.exif, err = io.ReadAll(io.LimitReader(r, int64(h.ExifLength)))
+	return err
+}
+
+func (m *strippedMetadata) readXMP(r io.Reader) (err error) {
+	m.xmp, err = io.ReadAll(r)
+	return err
+}
+
+// Strip copies the image from r to w without the metadata that is removed by the policy.
+// The image data is copied unchanged. Supports JPEG, PNG and WebP images.
+//
+// The GPSIFD and the PrivateTags are removed from Exif unless the policy has an allowlist of Tags,
+// Exif tags are rewritten and the offsets inside of kept MakerNotes are not updated.
+// The location and device identifying properties are removed from XMP. The APP13 Photoshop
+// segments of JPEG images and the text chunks of PNG images are always removed. The metadata
+// of the secondary images of JPEG MPF images is not changed.
+//
+// Returns ErrMetadataNotSupported for other image types.
+func Strip(r io.ReadSeeker, w io.Writer, policy StripPolicy) (err error) {
+	rr := readerPool.Get().(*bufio.Reader)
+	rr.Reset(r)
+	defer readerPool.Put(rr)
+
+	it, err := imagetype.ScanBuf(rr)
+	if err != nil {
+		return err
+	}
+	defer func() { err = meta.ImageTypeError(err, it) }()
+
+	var m strippedMetadata
+	switch it {
+	case imagetype.ImageJPEG:
+		s := jpeg.Scanner{ExifReader: m.readExif, XMPReader: m.readXMP}
+		if _, err = s.Scan(rr); err != nil {
+			return err
+		}
+		changes, err := policy.changes(m)
+		if err != nil {
+			return err
+		}
+		var rw jpeg.Rewriter
+		_ = rw.Remove(jpeg.SegmentPhotoshop)
+		segments := []jpeg.Segment{jpeg.SegmentExif, jpeg.SegmentXMP, jpeg.SegmentICC}
+		for i, c := range changes {
+			if c.remove {
+				err = rw.Remove(segments[i])
+			} else if c.payload != nil {
+				err = rw.Set(segments[i], c.payload)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return rw.Rewrite(w, r)
+	case imagetype.ImagePNG:
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		s := png.Scanner{ExifReader: m.readExif, XMPReader: m.readXMP}
+		if _, err = s.Scan(r); err != nil {
+			return err
+		}
+		changes, err := policy.changes(m)
+		if err != nil {
+			return err
+		}
+		rw := png.Rewriter{RemoveText: true}
+		chunks := []png.Chunk{png.ChunkExif, png.ChunkXMP, png.ChunkICC}
+		for i, c := range changes {
+			if c.remove {
+				err = rw.Remove(chunks[i])
+			} else if c.payload != nil {
+				err = rw.Set(chunks[i], c.payload)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		return rw.Rewrite(w, r)
+	case imagetype.ImageWebP:
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		s := webp.Scanner{ExifReader: m.readExif, XMPReader: m.readXMP}
+		if _, err = s.Scan(r); err != nil {
+			return err
+		}
+		changes, err := policy.changes(m)
+		if err != nil {
+			return err
+		}
+		var rw webp.Rewriter
+		chunks := []webp.Chunk{webp.ChunkExif, webp.ChunkXMP, webp.ChunkICC}
+		for i, c := range changes {
+			if c.remove {
+				err = rw.Remove(chunks[i])
+			} else if c.payload != nil {
+				err = rw.Set(chunks[i], c.payload)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return rw.Rewrite(w, r)
+	}
+	return ErrMetadataNotSupported
+}
+
+// stripChange is the change of the Exif, XMP or ICC Profile of a stripped image.
+type stripChange struct {
+	payload []byte
+	remove  bool
+}
+
+// changes returns the changes of the Exif, XMP and ICC Profile of the image with the metadata m.
+func (p StripPolicy) changes(m strippedMetadata) (c [3]stripChange, err error) {
+	if c[0].payload, err = p.stripExif(m.exif); err != nil {
+		return c, err
+	}
+	c[0].remove = c[0].payload == nil
+	c[1].payload = p.stripXMP(m.xmp)
+	c[1].remove = c[1].payload == nil
+	c[2].remove = !p.ICC
+	return c, nil
+}
@@ -0,0 +1,166 @@
+package imagemeta
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	goimagepng "image/png"
+	"os"
+	"testing"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/exif2/ifds"
+	"github.com/tdelov/imagemeta/exif2/tag"
+	"github.com/tdelov/imagemeta/jpeg"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+	"github.com/tdelov/imagemeta/png"
+)
+
+// testStripExif returns the Exif of a1.jpg with GPS coordinates.
+func testStripExif(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	src, err := os.ReadFile("assets/a1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Decode(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.GPS.SetLatitude(51.5)
+	e.GPS.SetLongitude(-0.12)
+	e.LensSerial = "0000c15998"
+	buf, err := exif2.Encode(e, utils.BigEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src, buf
+}
+
+func TestStripJPEG(t *testing.T) {
+	src, exif := testStripExif(t)
+	var rw jpeg.Rewriter
+	_ = rw.Set(jpeg.SegmentExif, exif)
+	var buf bytes.Buffer
+	if err := rw.Rewrite(&buf, bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	src = append([]byte(nil), buf.Bytes()...)
+	sos := bytes.Index(src, []byte{0xff, 0xda})
+
+	tests := []struct {
+		name        string
+		policy      StripPolicy
+		make        string
+		orientation meta.Orientation
+		xmp         bool
+	}{
+		{"StripPrivate", StripPrivate, "Canon", meta.OrientationHorizontal, true},
+		{"StripKeepOrientation", StripKeepOrientation, "", meta.OrientationHorizontal, false},
+		{"StripAll", StripAll, "", meta.Orientation(0), false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buf.Reset()
+			if err := Strip(bytes.NewReader(src), &buf, test.policy); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.Bytes()
+			if !bytes.Equal(src[sos:], out[bytes.Index(out, []byte{0xff, 0xda}):]) {
+				t.Error("Incorrect image data after SOS")
+			}
+			m, err := DecodeAll(bytes.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := m.Exif
+			if e.GPS.Latitude() != 0 || e.GPS.Longitude() != 0 || e.CameraSerial != "" || e.LensSerial != "" {
+				t.Errorf("Incorrect private Exif got %f %f %q %q", e.GPS.Latitude(), e.GPS.Longitude(), e.CameraSerial, e.LensSerial)
+			}
+			if e.Make != test.make || e.Orientation != test.orientation {
+				t.Errorf("Incorrect Exif wanted %q %s got %q %s", test.make, test.orientation, e.Make, e.Orientation)
+			}
+			if m.XMP.Aux.SerialNumber != "" || (m.XMP.Aux.Lens != "") != test.xmp {
+				t.Errorf("Incorrect XMP got serial number %q and lens %q", m.XMP.Aux.SerialNumber, m.XMP.Aux.Lens)
+			}
+		})
+	}
+}
+
+func TestStripIfd1(t *testing.T) {
+	_, exif := testStripExif(t)
+	enc, err := exif2.NewEncoderTiff(exif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.SetIfd1(exif2.NewShortEntry(ifds.Compression, 6))
+	enc.SetIfd1(exif2.NewASCIIEntry(ifds.CameraSerialNumber, "12345"))
+	enc.SetThumbnail([]byte{0xff, 0xd8, 0xff, 0xd9})
+	if exif, err = enc.EncodeTiff(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The PrivateTags of IFD0 are removed from IFD1
+	buf, err := StripPrivate.stripExif(exif)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc, err = exif2.NewEncoderTiff(buf); err != nil {
+		t.Fatal(err)
+	}
+	if enc.Filter(func(ifdType ifds.IfdType, id tag.ID) bool { return ifdType == ifds.NullIFD }); enc.Len() != 1 {
+		t.Errorf("Incorrect number of IFD1 entries wanted %d got %d", 1, enc.Len())
+	}
+
+	// The allowlist applies to IFD1
+	policy := StripPolicy{Exif: true, Tags: []StripTag{{ifds.IFD0, ifds.Make}}, Thumbnail: true}
+	if buf, err = policy.stripExif(exif); err != nil {
+		t.Fatal(err)
+	}
+	if enc, err = exif2.NewEncoderTiff(buf); err != nil {
+		t.Fatal(err)
+	}
+	if enc.Len() != 1 {
+		t.Errorf("Incorrect number of entries wanted %d got %d", 1, enc.Len())
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	_, exif := testStripExif(t)
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	img.Set(2, 3, color.RGBA{R: 0xff, A: 0xff})
+	var buf bytes.Buffer
+	if err := goimagepng.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	var rw png.Rewriter
+	_ = rw.Set(png.ChunkExif, exif[6:])
+	src := new(bytes.Buffer)
+	if err := rw.Rewrite(src, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err := Strip(bytes.NewReader(src.Bytes()), &buf, StripPrivate); err != nil {
+		t.Fatal(err)
+	}
+	m, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Exif.Make != "Canon" || m.Exif.GPS.Latitude() != 0 || m.Exif.CameraSerial != "" {
+		t.Errorf("Incorrect Exif got %q %f %q", m.Exif.Make, m.Exif.GPS.Latitude(), m.Exif.CameraSerial)
+	}
+	decoded, err := goimagepng.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r1, g1, b1, _ := decoded.At(2, 3).RGBA(); r1 != 0xffff || g1 != 0 || b1 != 0 {
+		t.Errorf("Incorrect pixel wanted %v got %v", img.At(2, 3), decoded.At(2, 3))
+	}
+
+	if err = Strip(bytes.NewReader([]byte("GIF89a\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")), &buf, StripAll); err == nil {
+		t.Error("Incorrect error wanted an error for a GIF image")
+	}
+}
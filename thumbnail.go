@@ -0,0 +1,87 @@
+package imagemeta
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/tdelov/imagemeta/exif2"
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/jpeg"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/tiff"
+)
+
+// Thumbnail returns the JPEG thumbnail image of IFD1 from a JPEG, TIFF, CR2, NEF or DNG
+// image. The ThumbnailOffset of the Exif is resolved against the offset of the Tiff header.
+//
+// Returns ErrNoThumbnail when the image does not have a JPEG thumbnail image.
+func Thumbnail(r io.ReadSeeker) (buf []byte, err error) {
+	rr := readerPool.Get().(*bufio.Reader)
+	rr.Reset(r)
+	defer readerPool.Put(rr)
+
+	ir := exif2.NewIfdReader(exif2.Logger)
+	defer ir.Close()
+
+	it, err := imagetype.ScanBuf(rr)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = meta.ImageTypeError(err, it) }()
+	switch decodeType(it) {
+	case imagetype.ImageJPEG:
+		// The thumbnail image is in the Exif segment, IFD1 is read with random access
+		// as it may be before IFD0.
+		exifReader := func(er io.Reader, h meta.ExifHeader) (err error) {
+			if buf, err = io.ReadAll(io.LimitReader(er, int64(h.ExifLength))); err != nil {
+				return err
+			}
+			h.TiffHeaderOffset = 0
+			return ir.DecodeReaderAt(bytes.NewReader(buf), h)
+		}
+		s := jpeg.Scanner{ExifReader: exifReader}
+		if _, err = s.Scan(rr); err != nil {
+			return nil, err
+		}
+		return thumbnail(bytes.NewReader(buf), 0, ir.Exif)
+	case imagetype.ImageCR2, imagetype.ImageTiff, imagetype.ImagePanaRAW, imagetype.ImageRW2, imagetype.ImageDNG, imagetype.ImagePEF, imagetype.ImageSRW, imagetype.Image3FR:
+		header, err := tiff.ScanTiffHeader(rr, it)
+		if err != nil {
+			return nil, err
+		}
+		if err = ir.DecodeTiff(rr, header); err != nil {
+			return nil, err
+		}
+		return thumbnail(r, int64(header.TiffHeaderOffset), ir.Exif)
+	}
+	return nil, ErrMetadataNotSupported
+}
+
+// thumbnail reads the thumbnail image of e from r. The thumbnail image is at the
+// ThumbnailOffset from the Tiff header at tiffHeaderOffset.
+func thumbnail(r io.ReadSeeker, tiffHeaderOffset int64, e exif2.Exif) ([]byte, error) {
+	if e.ThumbnailOffset == 0 || e.ThumbnailLength < 2 {
+		return nil, ErrNoThumbnail
+	}
+	offset := tiffHeaderOffset + int64(e.ThumbnailOffset)
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if offset+int64(e.ThumbnailLength) > size {
+		return nil, ErrNoThumbnail
+	}
+	if _, err = r.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, e.ThumbnailLength)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	// The thumbnail image starts with the JPEG SOI marker
+	if buf[0] != 0xFF || buf[1] != 0xD8 {
+		return nil, ErrNoThumbnail
+	}
+	return buf, nil
+}
@@ -0,0 +1,25 @@
+package tiff
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tdelov/imagemeta/imagetype"
+)
+
+func FuzzScanTiffHeader(f *testing.F) {
+	files, _ := filepath.Glob("../testImages/*.exif")
+	for _, file := range files {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(buf)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ScanTiffHeader(bytes.NewReader(data), imagetype.ImageUnknown)
+		_, _ = ScanTiffHeader(bytes.NewReader(data), imagetype.ImageCR2)
+	})
+}
@@ -57,3 +57,31 @@ func ScanTiffHeader(r io.Reader, it imagetype.ImageType) (header meta.ExifHeader
 		return header, nil
 	}
 }
+
+// ScanTiffHeaderBytes searches buf for the beginning of the EXIF information like
+// ScanTiffHeader without copying buf. The TiffHeaderOffset is the offset in buf.
+func ScanTiffHeaderBytes(buf []byte, it imagetype.ImageType) (header meta.ExifHeader, err error) {
+	for discarded := 0; discarded+TiffHeaderLength <= len(buf); {
+		b := buf[discarded : discarded+TiffHeaderLength]
+		if discarded == 0 {
+			it, _ = imagetype.Buf(b)
+		}
+
+		byteOrder := utils.BinaryOrder(b)
+		if byteOrder == utils.UnknownEndian {
+			// Exif not identified. Move forward by one byte.
+			if b[1] == 0x49 || b[1] == 0x4d {
+				discarded++
+				continue
+			}
+			discarded += 2
+			continue
+		}
+
+		// Found Tiff Header
+		header = meta.NewExifHeader(byteOrder, byteOrder.Uint32(b[4:8]), uint32(discarded), 0, it)
+		header.FirstIfd = ifds.IFD0
+		return header, nil
+	}
+	return header, meta.ErrNoExif
+}
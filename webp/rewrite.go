@@ -0,0 +1,247 @@
+package webp
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+// Errors
+var (
+	ErrChunk = errors.New("error unsupported chunk")
+)
+
+// Chunk is a metadata chunk of a WebP image that can be rewritten.
+type Chunk uint8
+
+// Chunks
+const (
+	// ChunkExif is the EXIF chunk
+	ChunkExif Chunk = iota
+	// ChunkXMP is the "XMP " chunk
+	ChunkXMP
+	// ChunkICC is the ICCP chunk
+	ChunkICC
+
+	chunkCount = 3
+)
+
+// chunkTypes are the FourCCs of the Chunks
+var chunkTypes = [chunkCount]string{"EXIF", "XMP ", "ICCP"}
+
+// chunkFlags are the VP8X flags of the Chunks
+var chunkFlags = [chunkCount]byte{flagExif, flagXMP, flagICC}
+
+// rewrite is the change of a Chunk.
+type rewrite struct {
+	payload []byte
+	set     bool
+	remove  bool
+}
+
+// Rewriter rewrites the metadata chunks of a WebP image without recompressing it.
+//
+// Chunks that are set replace all chunks of the same kind in the image. The ICCP chunk is written
+// after the VP8X chunk and the EXIF and XMP chunks at the end of the image. A VP8X chunk is
+// added to images in the simple format when a chunk is set. Chunks that are removed are dropped
+// from the image and the flags of the VP8X chunk are updated. All other chunks are copied unchanged.
+type Rewriter struct {
+	chunks [chunkCount]rewrite
+}
+
+// Set replaces or inserts the Chunk c with payload. ChunkExif is the Tiff header and IFDs.
+//
+// Returns ErrChunk if c is not a supported Chunk.
+func (rw *Rewriter) Set(c Chunk, payload []byte) error {
+	if c >= chunkCount {
+		return ErrChunk
+	}
+	rw.chunks[c] = rewrite{payload: payload, set: true}
+	return nil
+}
+
+// Remove removes all chunks of kind c from the image.
+func (rw *Rewriter) Remove(c Chunk) error {
+	if c >= chunkCount {
+		return ErrChunk
+	}
+	rw.chunks[c] = rewrite{remove: true}
+	return nil
+}
+
+// chunk is a chunk of the rewritten image. The data of chunks that are
+// copied from the image is read at offset.
+type chunk struct {
+	chunkType string
+	length    uint32
+	offset    int64
+	data      []byte
+}
+
+// size returns the size of the chunk with its header and padding.
+func (c chunk) size() int64 {
+	return chunkHeaderSize + int64(c.length) + int64(c.length&1)
+}
+
+// Rewrite reads a WebP image from r and writes it with the rewritten chunks to w.
+// The chunk headers are read first to calculate the size of the rewritten image.
+func (rw Rewriter) Rewrite(w io.Writer, r io.ReadSeeker) error {
+	chunks, err := rw.layout(r)
+	if err != nil {
+		return err
+	}
+	size := int64(4)
+	for _, c := range chunks {
+		size += c.size()
+	}
+	if size > math.MaxUint32 {
+		return ErrChunkLength
+	}
+	buf := make([]byte, riffHeaderSize, riffHeaderSize+vp8xLength)
+	copy(buf, "RIFF")
+	riffEndian.PutUint32(buf[4:8], uint32(size))
+	copy(buf[8:], "WEBP")
+	if _, err = w.Write(buf); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		buf = append(buf[:0], c.chunkType...)
+		buf = riffEndian.AppendUint32(buf, c.length)
+		if c.data != nil {
+			buf = append(buf, c.data...)
+		}
+		if c.length&1 == 1 && c.data != nil {
+			buf = append(buf, 0)
+		}
+		if _, err = w.Write(buf); err != nil {
+			return err
+		}
+		if c.data != nil {
+			continue
+		}
+		if _, err = r.Seek(c.offset, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err = io.CopyN(w, r, int64(c.length)+int64(c.length&1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// layout reads the chunk headers of the image and returns the chunks of the rewritten image.
+func (rw Rewriter) layout(r io.ReadSeeker) (chunks []chunk, err error) {
+	buf := make([]byte, riffHeaderSize)
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err = readRIFFHeader(r, buf); err != nil {
+		return nil, err
+	}
+	vp8x := -1
+	var flags byte
+	var bitstream []byte
+	offset := int64(riffHeaderSize)
+	for {
+		if _, err = io.ReadFull(r, buf[:chunkHeaderSize]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		c := chunk{chunkType: string(buf[:4]), length: riffEndian.Uint32(buf[4:8]), offset: offset + chunkHeaderSize}
+		offset += c.size()
+
+		keep := true
+		switch c.chunkType {
+		case "VP8X", "VP8 ", "VP8L":
+			if c.length < vp8xLength {
+				return nil, ErrChunkLength
+			}
+			data := make([]byte, vp8xLength)
+			if _, err = io.ReadFull(r, data); err != nil {
+				return nil, err
+			}
+			if c.chunkType == "VP8X" {
+				c.data, c.length = data, vp8xLength
+				vp8x = len(chunks)
+			} else if bitstream == nil {
+				bitstream = append([]byte(c.chunkType), data...)
+			}
+		default:
+			if i := chunkIndex(c.chunkType); i >= 0 {
+				keep = !(rw.chunks[i].set || rw.chunks[i].remove)
+				if keep {
+					flags |= chunkFlags[i]
+				}
+			}
+		}
+		if keep {
+			chunks = append(chunks, c)
+		}
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	// Chunks that are set
+	for i := range rw.chunks {
+		if rw.chunks[i].set {
+			flags |= chunkFlags[i]
+		}
+	}
+	if vp8x < 0 && flags == 0 {
+		return chunks, nil
+	}
+	if vp8x < 0 {
+		if bitstream == nil {
+			return nil, ErrChunkLength
+		}
+		width, height, alpha := bitstreamDimensions(string(bitstream[:4]), bitstream[4:])
+		data := make([]byte, vp8xLength)
+		if alpha {
+			data[0] = flagAlpha
+		}
+		putUint24(data[4:7], width-1)
+		putUint24(data[7:10], height-1)
+		chunks = append([]chunk{{chunkType: "VP8X", length: vp8xLength, data: data}}, chunks...)
+		vp8x = 0
+	}
+	chunks[vp8x].data[0] = chunks[vp8x].data[0]&^(flagExif|flagXMP|flagICC) | flags
+
+	// The ICCP chunk follows the VP8X chunk, EXIF and XMP chunks are at the end
+	if c := rw.chunks[ChunkICC]; c.set {
+		chunks = append(chunks[:vp8x+1], append([]chunk{newChunk(ChunkICC, c.payload)}, chunks[vp8x+1:]...)...)
+	}
+	for _, i := range []Chunk{ChunkExif, ChunkXMP} {
+		if c := rw.chunks[i]; c.set {
+			chunks = append(chunks, newChunk(i, c.payload))
+		}
+	}
+	return chunks, nil
+}
+
+// chunkIndex returns the Chunk of the chunk type or -1.
+func chunkIndex(chunkType string) int {
+	for i, t := range chunkTypes {
+		if t == chunkType {
+			return i
+		}
+	}
+	return -1
+}
+
+// newChunk returns a new chunk of c with payload.
+func newChunk(c Chunk, payload []byte) chunk {
+	if payload == nil {
+		payload = []byte{}
+	}
+	return chunk{chunkType: chunkTypes[c], length: uint32(len(payload)), data: payload}
+}
+
+// putUint24 puts the 24 bit value v into buf in little endian byte order.
+func putUint24(buf []byte, v uint32) {
+	buf[0] = byte(v)
+	buf[1] = byte(v >> 8)
+	buf[2] = byte(v >> 16)
+}
@@ -0,0 +1,184 @@
+// Package webp reads and rewrites the metadata (Exif, XMP and ICC Profile) of a WebP image.
+package webp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+	"github.com/tdelov/imagemeta/meta/utils"
+)
+
+// Errors
+var (
+	ErrNotWebP     = errors.New("error not a WebP image")
+	ErrChunkLength = errors.New("error WebP chunk length insufficient")
+)
+
+const (
+	// RIFF header "RIFF" + file size + "WEBP"
+	riffHeaderSize  = 12
+	chunkHeaderSize = 8
+
+	// VP8X chunk flags
+	flagAnimation = 0x02
+	flagXMP       = 0x04
+	flagExif      = 0x08
+	flagAlpha     = 0x10
+	flagICC       = 0x20
+
+	vp8xLength = 10
+
+	// exifPrefix is written before the Tiff header by some encoders
+	exifPrefix = "Exif\x00\x00"
+)
+
+var riffEndian = binary.LittleEndian
+
+// Scanner scans a WebP Image for its metadata. ExifReader, XMPReader and ICCReader
+// are run at their respective chunks during the scan when they are not nil.
+type Scanner struct {
+	ExifReader func(r io.Reader, header meta.ExifHeader) error
+	XMPReader  func(r io.Reader) error
+	ICCReader  func(r io.Reader) error
+}
+
+// Scan scans a reader for WebP chunks. Returns the dimensions of the image
+// from the VP8X, VP8 or VP8L chunk or an error.
+func (s Scanner) Scan(r io.ReadSeeker) (meta.Dimensions, error) {
+	return s.ScanContext(context.Background(), r)
+}
+
+// ScanContext is Scan with a context. The context is checked before each WebP chunk
+// and ctx.Err() is returned when the context is done.
+func (s Scanner) ScanContext(ctx context.Context, r io.ReadSeeker) (dim meta.Dimensions, err error) {
+	buf := make([]byte, riffHeaderSize)
+	if err = readRIFFHeader(r, buf); err != nil {
+		return dim, err
+	}
+	offset := int64(riffHeaderSize)
+	for {
+		if err = ctx.Err(); err != nil {
+			return dim, err
+		}
+		if _, err = io.ReadFull(r, buf[:chunkHeaderSize]); err != nil {
+			if err == io.EOF {
+				return dim, nil
+			}
+			return dim, err
+		}
+		chunkType := string(buf[:4])
+		length := riffEndian.Uint32(buf[4:8])
+		offset += chunkHeaderSize
+
+		switch chunkType {
+		case "VP8X", "VP8 ", "VP8L":
+			if dim.Width == 0 {
+				dim, err = readDimensions(r, chunkType, length, buf)
+			}
+		case "EXIF":
+			if s.ExifReader != nil {
+				err = s.readExif(r, offset, length)
+			}
+		case "XMP ":
+			if s.XMPReader != nil {
+				err = s.XMPReader(io.LimitReader(r, int64(length)))
+			}
+		case "ICCP":
+			if s.ICCReader != nil {
+				err = s.ICCReader(io.LimitReader(r, int64(length)))
+			}
+		}
+		if err != nil {
+			err = meta.ContainerError(err, chunkType, offset-chunkHeaderSize)
+			return dim, meta.ImageTypeError(err, imagetype.ImageWebP)
+		}
+
+		// Seek to the next chunk, chunks are padded to an even length
+		offset += int64(length) + int64(length&1)
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return dim, err
+		}
+	}
+}
+
+// readRIFFHeader reads the RIFF header of a WebP image into buf.
+func readRIFFHeader(r io.Reader, buf []byte) error {
+	if _, err := io.ReadFull(r, buf[:riffHeaderSize]); err != nil {
+		return err
+	}
+	if string(buf[:4]) != "RIFF" || string(buf[8:12]) != "WEBP" {
+		return ErrNotWebP
+	}
+	return nil
+}
+
+// readExif reads the Tiff header of the EXIF chunk and runs the ExifReader.
+// An "Exif\x00\x00" prefix is skipped.
+func (s Scanner) readExif(r io.Reader, offset int64, length uint32) error {
+	buf := make([]byte, len(exifPrefix)+8)
+	if length < 8 {
+		return ErrChunkLength
+	}
+	if _, err := io.ReadFull(r, buf[:8]); err != nil {
+		return err
+	}
+	if string(buf[:len(exifPrefix)]) == exifPrefix {
+		if length < uint32(len(buf)) {
+			return ErrChunkLength
+		}
+		if _, err := io.ReadFull(r, buf[8:]); err != nil {
+			return err
+		}
+		buf = buf[len(exifPrefix):]
+		offset += int64(len(exifPrefix))
+		length -= uint32(len(exifPrefix))
+	} else {
+		buf = buf[:8]
+	}
+	byteOrder := utils.BinaryOrder(buf)
+	if byteOrder == utils.UnknownEndian {
+		return meta.ErrNoExif
+	}
+	header := meta.NewExifHeader(byteOrder, byteOrder.Uint32(buf[4:8]), uint32(offset), length, imagetype.ImageWebP)
+
+	// Exif Readers expect to read from the beginning of the Tiff header.
+	return s.ExifReader(io.MultiReader(bytes.NewReader(buf), io.LimitReader(r, int64(length)-8)), header)
+}
+
+// readDimensions reads the canvas size of a VP8X chunk or the frame size of
+// a VP8 or VP8L bitstream.
+func readDimensions(r io.Reader, chunkType string, length uint32, buf []byte) (meta.Dimensions, error) {
+	if length < vp8xLength {
+		return meta.Dimensions{}, ErrChunkLength
+	}
+	if _, err := io.ReadFull(r, buf[:vp8xLength]); err != nil {
+		return meta.Dimensions{}, err
+	}
+	width, height, _ := bitstreamDimensions(chunkType, buf)
+	return meta.NewDimensions(width, height), nil
+}
+
+// bitstreamDimensions returns the width, height and if the image has an alpha channel
+// from the first 10 bytes of a VP8X, VP8 or VP8L chunk.
+func bitstreamDimensions(chunkType string, buf []byte) (width, height uint32, alpha bool) {
+	switch chunkType {
+	case "VP8X":
+		// Canvas width and height minus one as 24 bit values
+		width = 1 + (uint32(buf[4]) | uint32(buf[5])<<8 | uint32(buf[6])<<16)
+		height = 1 + (uint32(buf[7]) | uint32(buf[8])<<8 | uint32(buf[9])<<16)
+		return width, height, buf[0]&flagAlpha != 0
+	case "VP8 ":
+		// Frame tag (3 bytes), start code (3 bytes) and 14 bit width and height
+		return uint32(riffEndian.Uint16(buf[6:8]) & 0x3fff), uint32(riffEndian.Uint16(buf[8:10]) & 0x3fff), false
+	case "VP8L":
+		// Signature (1 byte), 14 bit width and height minus one and the alpha bit
+		bits := riffEndian.Uint32(buf[1:5])
+		return 1 + bits&0x3fff, 1 + (bits>>14)&0x3fff, (bits>>28)&1 == 1
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,106 @@
+package webp
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/tdelov/imagemeta/imagetype"
+	"github.com/tdelov/imagemeta/meta"
+)
+
+// testWebP returns a lossless WebP image in the simple format of 100x50 pixels with an alpha channel.
+func testWebP() []byte {
+	var bits uint32 = 99 | 49<<14 | 1<<28
+	vp8l := []byte{0x2f, 0, 0, 0, 0, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	binary.LittleEndian.PutUint32(vp8l[1:5], bits)
+
+	buf := []byte("RIFF\x00\x00\x00\x00WEBPVP8L")
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(vp8l)))
+	buf = append(buf, vp8l...)
+	buf = append(buf, 0) // padding
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(buf)-8))
+	return buf
+}
+
+func TestRewriter(t *testing.T) {
+	src := testWebP()
+	exif := []byte("II\x2a\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00")
+	xmp := []byte("<x:xmpmeta/>")
+	icc := []byte("profile")
+
+	var rw Rewriter
+	_ = rw.Set(ChunkExif, exif)
+	_ = rw.Set(ChunkXMP, xmp)
+	_ = rw.Set(ChunkICC, icc)
+	if err := rw.Set(Chunk(10), nil); err != ErrChunk {
+		t.Errorf("Incorrect error wanted %s got %v", ErrChunk, err)
+	}
+	var buf bytes.Buffer
+	if err := rw.Rewrite(&buf, bytes.NewReader(src)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+	if string(out[12:16]) != "VP8X" || out[20] != flagAlpha|flagExif|flagXMP|flagICC {
+		t.Errorf("Incorrect VP8X chunk got %q with flags %x", out[12:16], out[20])
+	}
+	if size := binary.LittleEndian.Uint32(out[4:8]); int(size) != len(out)-8 {
+		t.Errorf("Incorrect RIFF size wanted %d got %d", len(out)-8, size)
+	}
+
+	var gotExif, gotXMP, gotICC []byte
+	var header meta.ExifHeader
+	s := Scanner{
+		ExifReader: func(r io.Reader, h meta.ExifHeader) (err error) {
+			header = h
+			gotExif, err = io.ReadAll(r)
+			return err
+		},
+		XMPReader: func(r io.Reader) (err error) {
+			gotXMP, err = io.ReadAll(r)
+			return err
+		},
+		ICCReader: func(r io.Reader) (err error) {
+			gotICC, err = io.ReadAll(r)
+			return err
+		},
+	}
+	dim, err := s.Scan(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dim != meta.NewDimensions(100, 50) {
+		t.Errorf("Incorrect Dimensions wanted %s got %s", meta.NewDimensions(100, 50), dim)
+	}
+	if !bytes.Equal(gotExif, exif) || header.ImageType != imagetype.ImageWebP || header.FirstIfdOffset != 8 {
+		t.Errorf("Incorrect Exif got %x with header %s", gotExif, header)
+	}
+	if !bytes.Equal(gotXMP, xmp) || !bytes.Equal(gotICC, icc) {
+		t.Errorf("Incorrect XMP %q or ICC Profile %q", gotXMP, gotICC)
+	}
+
+	// Remove the metadata again
+	rw = Rewriter{}
+	_ = rw.Remove(ChunkExif)
+	_ = rw.Remove(ChunkXMP)
+	_ = rw.Remove(ChunkICC)
+	buf.Reset()
+	if err = rw.Rewrite(&buf, bytes.NewReader(out)); err != nil {
+		t.Fatal(err)
+	}
+	out = buf.Bytes()
+	if out[20] != flagAlpha || !bytes.HasSuffix(out, src[12:]) || len(out) != len(src)+chunkHeaderSize+vp8xLength {
+		t.Errorf("Incorrect WebP with metadata removed got %x", out)
+	}
+
+	if _, err = (Scanner{}).Scan(bytes.NewReader([]byte("GIF89a\x00\x00\x00\x00\x00\x00"))); err != ErrNotWebP {
+		t.Errorf("Incorrect error wanted %s got %v", ErrNotWebP, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = s.ScanContext(ctx, bytes.NewReader(src)); err != context.Canceled {
+		t.Errorf("Incorrect error wanted %s got %v", context.Canceled, err)
+	}
+}
@@ -0,0 +1,35 @@
+package xmp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func FuzzParseXmp(f *testing.F) {
+	for _, pattern := range []string{"test/*.xmp", "../testImages/*.xmp"} {
+		files, _ := filepath.Glob(pattern)
+		for _, file := range files {
+			buf, err := os.ReadFile(file)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(buf)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = ParseXmp(bytes.NewReader(data))
+	})
+}
+
+// panicReader panics with a value that is not an error.
+type panicReader struct{}
+
+func (panicReader) Read(p []byte) (int, error) { panic("read") }
+
+func TestParseXmpPanic(t *testing.T) {
+	if _, err := ParseXmp(panicReader{}); err == nil || err.Error() != "xmp: panic: read" {
+		t.Errorf("Incorrect error wanted %q got %v", "xmp: panic: read", err)
+	}
+}
@@ -0,0 +1,89 @@
+package xmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// RemoveProperties returns the XMP packet buf without the properties for which remove
+// returns true. remove is called with the namespace URI and the local name of every
+// element and attribute of the packet.
+//
+// The properties are cut from buf, the remainder of the packet is unchanged.
+func RemoveProperties(buf []byte, remove func(namespace, name string) bool) ([]byte, error) {
+	// The padding after the packet trailer is not parsed
+	end := bytes.LastIndexByte(buf, '>') + 1
+	d := xml.NewDecoder(bytes.NewReader(buf[:end]))
+	var cuts [][2]int64
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if remove(se.Name.Space, se.Name.Local) {
+			if err = d.Skip(); err != nil {
+				return nil, err
+			}
+			cuts = append(cuts, [2]int64{start, d.InputOffset()})
+			continue
+		}
+		cuts = appendAttrCuts(cuts, buf[start:d.InputOffset()], start, se.Attr, remove)
+	}
+	if len(cuts) == 0 {
+		return buf, nil
+	}
+	out := make([]byte, 0, len(buf))
+	var pos int64
+	for _, c := range cuts {
+		out = append(out, buf[pos:c[0]]...)
+		pos = c[1]
+	}
+	return append(out, buf[pos:]...), nil
+}
+
+// appendAttrCuts appends the byte ranges of the attributes of the start tag raw that are
+// removed to cuts. Attributes are matched by their order to attrs, raw starts at offset.
+func appendAttrCuts(cuts [][2]int64, raw []byte, offset int64, attrs []xml.Attr, remove func(namespace, name string) bool) [][2]int64 {
+	var i, n int
+	// Skip the '<' and the element name
+	for i = 1; i < len(raw) && !isSpace(raw[i]) && raw[i] != '>' && raw[i] != '/'; i++ {
+	}
+	for ; n < len(attrs); n++ {
+		attrStart := i
+		for i < len(raw) && isSpace(raw[i]) {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' || raw[i] == '/' {
+			// The attributes do not match the start tag
+			return cuts
+		}
+		// The quoted value of the attribute
+		for i < len(raw) && raw[i] != '"' && raw[i] != '\'' {
+			i++
+		}
+		if i >= len(raw) {
+			return cuts
+		}
+		quote := raw[i]
+		for i++; i < len(raw) && raw[i] != quote; i++ {
+		}
+		i++
+		if remove(attrs[n].Name.Space, attrs[n].Name.Local) {
+			cuts = append(cuts, [2]int64{offset + int64(attrStart), offset + int64(i)})
+		}
+	}
+	return cuts
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
@@ -0,0 +1,54 @@
+package xmp
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemoveProperties(t *testing.T) {
+	buf, err := os.ReadFile("test/1.xmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeGPS := func(namespace, name string) bool {
+		return namespace == "http://ns.adobe.com/exif/1.0/" && strings.HasPrefix(name, "GPS")
+	}
+	out, err := RemoveProperties(buf, removeGPS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("exif:GPS")) {
+		t.Error("Incorrect GPS attributes were not removed")
+	}
+	x1, err := ParseXmp(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x2, err := ParseXmp(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x2.Exif.GPSLatitude != "" || x2.Exif.GPSAltitude != 0 {
+		t.Errorf("Incorrect GPS wanted empty got %q %f", x2.Exif.GPSLatitude, x2.Exif.GPSAltitude)
+	}
+	if !reflect.DeepEqual(x1.Tiff, x2.Tiff) || x1.Exif.ExposureTime != x2.Exif.ExposureTime {
+		t.Errorf("Incorrect properties were removed wanted %v got %v", x1.Tiff, x2.Tiff)
+	}
+
+	// Properties as elements
+	packet := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:ExifVersion="0232">` +
+		`<exif:GPSLatitude>11,57.1312N</exif:GPSLatitude><exif:GPSAltitude/></rdf:Description></rdf:RDF></x:xmpmeta>`
+	want := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:ExifVersion="0232">` +
+		`</rdf:Description></rdf:RDF></x:xmpmeta>`
+	if out, err = RemoveProperties([]byte(packet), removeGPS); err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("Incorrect XMP wanted %s got %s", want, out)
+	}
+}
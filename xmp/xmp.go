@@ -4,6 +4,7 @@ package xmp
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -33,7 +34,11 @@ type XMP struct {
 func ParseXmp(r io.Reader) (xmp XMP, err error) {
 	defer func() {
 		if state := recover(); state != nil {
-			err = state.(error)
+			// Panics that are not errors are wrapped
+			var ok bool
+			if err, ok = state.(error); !ok {
+				err = fmt.Errorf("xmp: panic: %v", state)
+			}
 		}
 	}()
 	xr := newXMPReader(r)
@@ -0,0 +1,11 @@
+module github.com/tdelov/imagemeta/zerologhandler
+
+go 1.21
+
+require github.com/rs/zerolog v1.29.0
+
+require (
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.17 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+)
@@ -0,0 +1,152 @@
+// Package zerologhandler provides a slog.Handler that writes log records to a zerolog.Logger.
+//
+// The imagemeta packages log with log/slog, this package is only needed to keep
+// using a zerolog.Logger. It is a separate module so that zerolog is not a dependency
+// of imagemeta:
+//
+//	imagemeta.SetLogger(zerologhandler.New(zerolog.New(os.Stdout).Level(zerolog.InfoLevel)))
+package zerologhandler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+)
+
+// Handler is a slog.Handler that writes log records to a zerolog.Logger.
+type Handler struct {
+	l zerolog.Logger
+
+	// goas are the groups and attributes added with WithGroup and WithAttrs in order
+	goas []groupOrAttrs
+}
+
+// groupOrAttrs is either a group name or a list of attributes
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// New returns a new Handler that writes to l.
+func New(l zerolog.Logger) *Handler {
+	return &Handler{l: l}
+}
+
+// Level returns the zerolog.Level of a slog.Level.
+func Level(level slog.Level) zerolog.Level {
+	switch {
+	case level < slog.LevelDebug:
+		return zerolog.TraceLevel
+	case level < slog.LevelInfo:
+		return zerolog.DebugLevel
+	case level < slog.LevelWarn:
+		return zerolog.InfoLevel
+	case level < slog.LevelError:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
+
+// Enabled reports whether the zerolog.Logger logs records of the given level.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	lvl := Level(level)
+	return lvl >= h.l.GetLevel() && lvl >= zerolog.GlobalLevel()
+}
+
+// Handle writes the log record to the zerolog.Logger.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	ev := h.l.WithLevel(Level(r.Level))
+	if ev == nil {
+		return nil
+	}
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	// Nest the attributes in the groups from the innermost group outwards
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		if g := h.goas[i]; g.group != "" {
+			attrs = []slog.Attr{{Key: g.group, Value: slog.GroupValue(attrs...)}}
+		} else {
+			attrs = append(g.attrs[:len(g.attrs):len(g.attrs)], attrs...)
+		}
+	}
+	for _, a := range attrs {
+		addAttr(ev, a)
+	}
+	ev.Msg(r.Message)
+	return nil
+}
+
+// WithAttrs returns a new Handler with the attributes added to every log record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns a new Handler that nests the attributes of log records in the group name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(g groupOrAttrs) *Handler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas), len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas = append(h2.goas, g)
+	return &h2
+}
+
+// addAttr adds the attribute to the zerolog.Event
+func addAttr(ev *zerolog.Event, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		ev.Str(a.Key, a.Value.String())
+	case slog.KindInt64:
+		ev.Int64(a.Key, a.Value.Int64())
+	case slog.KindUint64:
+		ev.Uint64(a.Key, a.Value.Uint64())
+	case slog.KindFloat64:
+		ev.Float64(a.Key, a.Value.Float64())
+	case slog.KindBool:
+		ev.Bool(a.Key, a.Value.Bool())
+	case slog.KindDuration:
+		ev.Dur(a.Key, a.Value.Duration())
+	case slog.KindTime:
+		ev.Time(a.Key, a.Value.Time())
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				addAttr(ev, ga)
+			}
+			return
+		}
+		dict := zerolog.Dict()
+		for _, ga := range attrs {
+			addAttr(dict, ga)
+		}
+		ev.Dict(a.Key, dict)
+	default:
+		if err, ok := a.Value.Any().(error); ok {
+			ev.AnErr(a.Key, err)
+			return
+		}
+		ev.Interface(a.Key, a.Value.Any())
+	}
+}
@@ -0,0 +1,47 @@
+package zerologhandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type point struct{ x, y int }
+
+func (p point) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("x", p.x), slog.Int("y", p.y))
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := New(zerolog.New(&buf).Level(zerolog.InfoLevel))
+	l := slog.New(h).With("package", "exif2").WithGroup("tag")
+
+	l.Debug("not logged")
+	if buf.Len() != 0 {
+		t.Fatalf("Incorrect Debug record wanted none got %s", buf.String())
+	}
+
+	l.Error("decode", "offset", uint32(1234), "point", point{1, 2}, "error", errors.New("EOF"))
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["level"] != "error" || rec["message"] != "decode" || rec["package"] != "exif2" {
+		t.Errorf("Incorrect record got %v", rec)
+	}
+	tag, ok := rec["tag"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Incorrect group tag got %v", rec["tag"])
+	}
+	if tag["offset"] != 1234.0 || tag["error"] != "EOF" {
+		t.Errorf("Incorrect group tag got %v", tag)
+	}
+	if p, ok := tag["point"].(map[string]interface{}); !ok || p["x"] != 1.0 || p["y"] != 2.0 {
+		t.Errorf("Incorrect LogValuer got %v", tag["point"])
+	}
+}